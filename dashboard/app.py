@@ -11,6 +11,7 @@ import os
 # Read from env var if available (Docker), else default to localhost for local dev
 API_URL = os.getenv("API_URL", "http://localhost:8081")
 USER_ADDRESS = os.getenv("USER_ADDRESS", "")
+SESSION_TOKEN = os.getenv("SESSION_TOKEN", "")
 
 st.set_page_config(
     page_title="TrustFlow Transparency Dashboard",
@@ -23,6 +24,7 @@ st.title("🛡️ TrustFlow Transparency Dashboard")
 # --- Sidebar: Refresh & Stats ---
 st.sidebar.header("Control Panel")
 wallet = st.sidebar.text_input("Wallet Address", value=USER_ADDRESS)
+session_token = st.sidebar.text_input("Session Token", value=SESSION_TOKEN, type="password")
 if st.sidebar.button("Refresh Data"):
     st.rerun()
 
@@ -32,10 +34,17 @@ if auto_refresh:
     st.rerun()
 
 # --- Fetch Data ---
+def api_headers(wallet_addr, token):
+    # A session token (from POST /auth/verify) authenticates; the bare address only works with ALLOW_HEADER_AUTH
+    headers = {"X-User-Address": wallet_addr} if wallet_addr else {}
+    if token:
+        headers["Authorization"] = f"Bearer {token}"
+    return headers
+
 @st.cache_data(ttl=2)
-def fetch_intents(wallet_addr):
+def fetch_intents(wallet_addr, token):
     try:
-        headers = {"X-User-Address": wallet_addr} if wallet_addr else {}
+        headers = api_headers(wallet_addr, token)
         response = requests.get(f"{API_URL}/intents", headers=headers)
         if response.status_code == 200:
             return response.json()
@@ -46,7 +55,7 @@ def fetch_intents(wallet_addr):
         st.error(f"Connection error: {e}")
         return []
 
-intents_data = fetch_intents(wallet)
+intents_data = fetch_intents(wallet, session_token)
 
 # --- Overview Stats ---
 if intents_data:
@@ -103,7 +112,7 @@ else:
     
     if selected_id:
         try:
-            headers = {"X-User-Address": wallet} if wallet else {}
+            headers = api_headers(wallet, session_token)
             details_resp = requests.get(f"{API_URL}/status/{selected_id}", headers=headers)
             if details_resp.status_code == 200:
                 details = details_resp.json()
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"trustflow/src/internal/audit"
+	"trustflow/src/internal/storage"
+)
+
+// Usage: audit verify [-db trustflow.db]
+// Re-walks the hash-chained audit log and exits 1 at the first broken link.
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: audit verify [-db trustflow.db]")
+		os.Exit(2)
+	}
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	dbPath := flags.String("db", "trustflow.db", "SQLite database to verify")
+	flags.Parse(os.Args[2:])
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		log.Fatalf("❌ %v", err) // Opening a missing path would create an empty database
+	}
+	store, err := storage.NewStorage(*dbPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to open %s: %v", *dbPath, err)
+	}
+
+	report, err := audit.NewLog(store).Verify()
+	if err != nil {
+		log.Fatalf("❌ Verification failed: %v", err)
+	}
+	if !report.Valid {
+		fmt.Printf("🚨 Audit chain broken at event %d: %s\n", report.BrokenSeq, report.Reason)
+		fmt.Printf("   %d events verified; last intact event %d (%s)\n", report.Checked, report.HeadSeq, report.HeadHash)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Audit chain intact: %d events, head %d (%s)\n", report.Checked, report.HeadSeq, report.HeadHash)
+}
@@ -1,22 +1,37 @@
 package main
 
 import (
+	"context"
 	"log"
+	"math/big"
 	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
 	"trustflow/src/internal/api"
+	"trustflow/src/internal/approval"
+	"trustflow/src/internal/audit"
+	"trustflow/src/internal/auth"
+	"trustflow/src/internal/budget"
 	"trustflow/src/internal/chain"
 	"trustflow/src/internal/config"
-	"trustflow/src/internal/executor"
 	"trustflow/src/internal/orchestrator"
-	"trustflow/src/internal/simulator"
+	"trustflow/src/internal/policy"
 	"trustflow/src/internal/storage"
+	"trustflow/src/internal/stream"
+	"trustflow/src/internal/wallet"
+	"trustflow/src/internal/webhook"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// Background loops stop on SIGINT/SIGTERM; see the shutdown at the end
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// 1. Load Config
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -31,42 +46,104 @@ func main() {
 	defer client.Close()
 	log.Printf("✅ Connected to Chain ID: %s", client.GetAddress().Hex())
 
-	// 3. Initialize Simulator
-	sim := simulator.NewSimulator(client)
-
-	// 4. Initialize Executor
-	exec := executor.NewExecutor(client)
-
-	// 5. Initialize Storage
+	// 3. Initialize Storage
 	store, err := storage.NewStorage("trustflow.db")
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 	log.Println("✅ Connected to SQLite Storage")
+	client.SetNonceStore(store)    // Nonces are allocated locally and persisted across restarts
+	events := stream.NewHub(store) // Streams every status transition the store persists
 
-	// 6. Initialize Orchestrator
-	orch := orchestrator.NewOrchestrator(sim, exec, store)
+	// 4. Initialize Agent Wallets (per-user HD wallets, or the server wallet for everyone)
+	var hd *wallet.HDWallet
+	if cfg.HDMnemonicFile != "" {
+		hd, err = wallet.LoadHDWallet(cfg.HDMnemonicFile, cfg.HDPassphraseFile)
+		if err != nil {
+			log.Fatalf("Failed to load HD wallet: %v", err)
+		}
+		log.Printf("✅ Deriving per-user agent wallets from %s", wallet.DefaultBasePath)
+	}
+	wallets := wallet.NewManager(client, hd, store)
 
-	// 7. Initialize API Handler
-	handler := api.NewHandler(orch, sim)
+	// 5. Load Policy (optional)
+	var rules *policy.Engine
+	if cfg.PolicyFile != "" {
+		rules, err = policy.LoadFile(cfg.PolicyFile)
+		if err != nil {
+			log.Fatalf("Failed to load policy: %v", err)
+		}
+		log.Printf("✅ Loaded Policy from %s", cfg.PolicyFile)
+	}
 
-	// Initialize Gin router
-	router := gin.Default()
+	// 6. Initialize Budget Tracker
+	var budgetLimit *big.Int
+	if cfg.BudgetLimit != "" {
+		limit, ok := new(big.Int).SetString(cfg.BudgetLimit, 10)
+		if !ok || limit.Sign() < 0 {
+			log.Fatalf("Invalid BUDGET_LIMIT_WEI: %s", cfg.BudgetLimit)
+		}
+		budgetLimit = limit
+		log.Printf("✅ Budget Limit: %s wei per %s", budgetLimit, cfg.BudgetWindow)
+	}
+	tracker := budget.NewTracker(store, budgetLimit, cfg.BudgetWindow)
 
-	// Middleware enforcing X-User-Address header
-	requireUserHeader := func(c *gin.Context) {
-		user := c.GetHeader("X-User-Address")
-		if user == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing X-User-Address header"})
-			c.Abort()
-			return
+	// 7. Load Approval Gate (optional)
+	var approvals *approval.Gate
+	if cfg.ApprovalFile != "" {
+		approvals, err = approval.LoadFile(cfg.ApprovalFile)
+		if err != nil {
+			log.Fatalf("Failed to load approval rules: %v", err)
 		}
-		if !strings.HasPrefix(user, "0x") || len(user) != 42 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address"})
-			c.Abort()
-			return
+		log.Printf("✅ Loaded Approval Rules from %s", cfg.ApprovalFile)
+	}
+
+	// 8. Initialize Webhook Dispatcher (deliveries queued before a restart are picked up again)
+	hooks := webhook.NewDispatcher(store, cfg.WebhookMaxAttempts, cfg.WebhookRetryBase, cfg.WebhookAllowPrivate)
+	hooks.Start(ctx)
+
+	// 9. Initialize Orchestrator
+	orch := orchestrator.NewOrchestrator(wallets, store, rules, tracker, approvals, hooks)
+	if err := orch.Recover(ctx); err != nil {
+		log.Fatalf("Failed to recover interrupted intents: %v", err)
+	}
+	orch.Start(ctx, cfg.Workers)
+	orch.WatchStuckTransactions(ctx, cfg.StuckTxAfter, cfg.MaxSpeedups)
+	orch.WatchApprovals(ctx)
+
+	// 10. Initialize Sign-In with Ethereum
+	authn := auth.NewAuthenticator(store, cfg.AuthDomain, client.ChainID(), cfg.SessionTTL, cfg.AdminAddresses)
+	if cfg.AuthDomain == "" {
+		log.Println("⚠️ AUTH_DOMAIN not set: sign-in is disabled, only X-User-Address is accepted")
+	}
+	if cfg.AllowHeaderAuth {
+		log.Println("⚠️ ALLOW_HEADER_AUTH is on: requests without a session may claim any X-User-Address")
+	}
+	if len(cfg.AdminAddresses) == 0 {
+		log.Println("ℹ️ ADMIN_ADDRESSES not set: API keys cannot be managed")
+	}
+
+	// 11. Initialize Audit Anchoring (optional)
+	if cfg.AnchorInterval > 0 {
+		var contract *common.Address
+		if cfg.AnchorContract != "" {
+			address := common.HexToAddress(cfg.AnchorContract)
+			contract = &address
 		}
-		c.Next()
+		audit.NewAnchorer(store, client, contract).Start(ctx, cfg.AnchorInterval)
+		log.Printf("✅ Anchoring the audit log on-chain every %s", cfg.AnchorInterval)
+	} else {
+		log.Println("ℹ️ ANCHOR_INTERVAL not set: the audit log is not anchored on-chain")
+	}
+
+	// 12. Initialize API Handler
+	handler := api.NewHandler(orch, hooks, events, authn, audit.NewLog(store), cfg.RequireSignedIntents)
+
+	// Initialize Gin router
+	router := gin.Default()
+	// Client IPs key the sign-in rate limit: only listed proxies may set them via X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
 	}
 
 	openAPISpec := `{
@@ -79,11 +156,50 @@ func main() {
 	  "servers": [
 	    { "url": "/" }
 	  ],
+	  "security": [
+	    { "SessionToken": [] }
+	  ],
 	  "paths": {
+	    "/auth/nonce": {
+	      "get": {
+	        "summary": "Get a sign-in nonce",
+	        "description": "Issues a single-use nonce, valid for 10 minutes, to embed in an EIP-4361 (Sign-In with Ethereum) message, along with the domain and chain ID the message must name.",
+	        "security": [],
+	        "responses": {
+	          "200": {
+	            "description": "Nonce",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/AuthNonce" } } }
+	          },
+	          "429": { "description": "More than 10 nonces a minute from this client, or too many unused nonces outstanding; see Retry-After" },
+	          "503": { "description": "Sign-in is disabled (no AUTH_DOMAIN, development only)" }
+	        }
+	      }
+	    },
+	    "/auth/verify": {
+	      "post": {
+	        "summary": "Sign in with Ethereum",
+	        "description": "Verifies an EIP-4361 message signed with personal_sign and returns a session token for its address. Send the token as Authorization: Bearer <token> on every other request.",
+	        "security": [],
+	        "requestBody": {
+	          "required": true,
+	          "content": { "application/json": { "schema": { "$ref": "#/components/schemas/SignInRequest" } } }
+	        },
+	        "responses": {
+	          "200": {
+	            "description": "Signed in",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/Session" } } }
+	          },
+	          "400": { "description": "Malformed message, or wrong domain, chain ID, version or validity window" },
+	          "401": { "description": "Signature does not match the message address, or nonce unknown, expired or already used" },
+	          "503": { "description": "Sign-in is disabled (no AUTH_DOMAIN, development only)" }
+	        }
+	      }
+	    },
 	    "/health": {
 	      "get": {
 	        "summary": "Health check",
 	        "description": "Service liveness check",
+	        "security": [],
 	        "responses": {
 	          "200": {
 	            "description": "OK",
@@ -100,8 +216,11 @@ func main() {
 	    "/intent": {
       "post": {
         "summary": "Submit intent",
-        "description": "Submit a single action or multi-step workflow for execution",
-        "parameters": [ { "$ref": "#/components/parameters/UserAddressHeader" } ],
+        "description": "Queue a single action or multi-step workflow for asynchronous execution. Retrying with the same Idempotency-Key, or the same client-supplied id, returns the intent already accepted (replayed: true, Idempotent-Replayed header) instead of executing it again.",
+        "parameters": [
+          { "$ref": "#/components/parameters/UserAddressHeader" },
+          { "name": "Idempotency-Key", "in": "header", "required": false, "schema": { "type": "string", "maxLength": 255 }, "description": "Client-chosen key, unique per user, that makes retries safe" }
+        ],
 	        "requestBody": {
 	          "required": true,
 	          "content": {
@@ -118,6 +237,30 @@ func main() {
 	                    }
 	                  }
 	                },
+	                "erc20_transfer": {
+	                  "summary": "ERC-20 token transfer",
+	                  "value": {
+	                    "action": "erc20_transfer",
+	                    "params": {
+	                      "token": "0x5FbDB2315678afecb367f032d93F642f64180aa3",
+	                      "recipient": "0x71C7656EC7ab88b098defB751B7401B5f6d8976F",
+	                      "amount": "1.5"
+	                    }
+	                  }
+	                },
+	                "contract_call": {
+	                  "summary": "Arbitrary contract call",
+	                  "value": {
+	                    "action": "contract_call",
+	                    "params": {
+	                      "contract": "0x5FbDB2315678afecb367f032d93F642f64180aa3",
+	                      "function": "swap(address,uint256,uint256)"
+	                    },
+	                    "typed_params": {
+	                      "args": ["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "1000000", "990000"]
+	                    }
+	                  }
+	                },
 	                "multi_step": {
 	                  "summary": "Multi-step workflow",
 	                  "value": {
@@ -144,32 +287,15 @@ func main() {
 	          }
 	        },
 	        "responses": {
-	          "200": {
-	            "description": "Intent processed",
-	            "content": {
-	              "application/json": {
-	                "schema": { "$ref": "#/components/schemas/IntentResponse" },
-	                "example": {
-	                  "status": "success",
-	                  "intent_id": "a55470d4-784f-485b-b36f-ce70e540da3b",
-	                  "message": "Successfully executed 2 steps",
-	                  "tx_hash": "0xabc...",
-	                  "tx_hashes": ["0xabc...", "0xdef..."]
-	                }
-	              }
-	            }
-	          },
-	          "422": {
-	            "description": "Intent failed",
+	          "202": {
+	            "description": "Intent accepted for background processing; poll /status/{id} for the outcome (success, failed, blocked)",
 	            "content": {
 	              "application/json": {
 	                "schema": { "$ref": "#/components/schemas/IntentResponse" },
 	                "example": {
-	                  "status": "failed",
+	                  "status": "pending",
 	                  "intent_id": "a55470d4-784f-485b-b36f-ce70e540da3b",
-	                  "message": "Execution halted at step 1: insufficient funds",
-	                  "failed_step_index": 0,
-	                  "error": "insufficient funds: have 0 wei, want 21000000000000 wei"
+	                  "message": "Intent accepted with 2 steps; poll /status/a55470d4-784f-485b-b36f-ce70e540da3b for progress"
 	                }
 	              }
 	            }
@@ -183,6 +309,18 @@ func main() {
 	              }
 	            }
 	          },
+	          "401": {
+	            "description": "The intent's EIP-712 signature is invalid or not by the authenticated address, or it is unsigned while REQUIRE_SIGNED_INTENTS is on",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/ErrorResponse" } } }
+	          },
+	          "409": {
+	            "description": "The client-supplied intent id belongs to another user",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/ErrorResponse" } } }
+	          },
+	          "422": {
+	            "description": "The Idempotency-Key or intent id was already used for a request with different steps",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/ErrorResponse" } } }
+	          },
 	          "500": {
 	            "description": "Internal server error",
 	            "content": {
@@ -198,7 +336,7 @@ func main() {
 	    "/simulate": {
       "post": {
         "summary": "Dry-run simulation",
-        "description": "Pre-flight checks including gas estimate and price",
+        "description": "Pre-flight checks including gas estimate and price. Multi-step workflows are simulated in order against cumulative state",
         "parameters": [ { "$ref": "#/components/parameters/UserAddressHeader" } ],
 	        "requestBody": {
 	          "required": true,
@@ -238,7 +376,12 @@ func main() {
 	                  "revert": {
 	                    "value": {
 	                      "valid": false,
-	                      "error": "Simulation Reverted: execution reverted"
+	                      "error": "Simulation Reverted: simulation failed (transaction would revert): error: ERC20: transfer amount exceeds balance",
+	                      "revert": {
+	                        "code": "error",
+	                        "reason": "ERC20: transfer amount exceeds balance",
+	                        "data": "0x08c379a0..."
+	                      }
 	                    }
 	                  }
 	                }
@@ -320,6 +463,320 @@ func main() {
 	          }
 	        }
 	      }
+	    },
+	    "/intents/{id}/events": {
+	      "get": {
+	        "summary": "Stream an intent's status events",
+	        "description": "Server-Sent Events stream of the intent's status transitions (event: intent or step, id: sequence number, data: StatusEvent). Starts with the latest 100 events unless resumed; a reconnecting EventSource sends Last-Event-ID and receives everything it missed, in sequence order (Last-Event-ID: 0 replays the full history).",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "name": "id", "in": "path", "required": true, "schema": { "type": "string" } },
+	          { "$ref": "#/components/parameters/LastEventIDHeader" },
+	          { "$ref": "#/components/parameters/LastEventIDQuery" }
+	        ],
+	        "responses": {
+	          "200": {
+	            "description": "Event stream",
+	            "content": { "text/event-stream": { "schema": { "$ref": "#/components/schemas/StatusEvent" } } }
+	          },
+	          "400": { "description": "Invalid Last-Event-ID" },
+	          "404": { "description": "Intent not found" }
+	        }
+	      }
+	    },
+	    "/events": {
+	      "get": {
+	        "summary": "Stream all status events",
+	        "description": "Server-Sent Events stream of status transitions across all of the caller's intents. Starts with the latest 100 events, resumable with Last-Event-ID.",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "$ref": "#/components/parameters/LastEventIDHeader" },
+	          { "$ref": "#/components/parameters/LastEventIDQuery" }
+	        ],
+	        "responses": {
+	          "200": {
+	            "description": "Event stream",
+	            "content": { "text/event-stream": { "schema": { "$ref": "#/components/schemas/StatusEvent" } } }
+	          },
+	          "400": { "description": "Invalid Last-Event-ID" }
+	        }
+	      }
+	    },
+	    "/budget": {
+	      "get": {
+	        "summary": "Rolling spend budget",
+	        "description": "Value executed within the budget window and what remains of the cap",
+	        "parameters": [ { "$ref": "#/components/parameters/UserAddressHeader" } ],
+	        "responses": {
+	          "200": {
+	            "description": "Budget status",
+	            "content": {
+	              "application/json": {
+	                "schema": { "$ref": "#/components/schemas/BudgetStatus" },
+	                "example": {
+	                  "user_address": "0x71C7656EC7ab88b098defB751B7401B5f6d8976F",
+	                  "limit": "1000000000000000000",
+	                  "spent": "300000000000000000",
+	                  "remaining": "700000000000000000",
+	                  "window_seconds": 86400,
+	                  "unlimited": false
+	                }
+	              }
+	            }
+	          }
+	        }
+	      }
+	    },
+	    "/wallet": {
+	      "get": {
+	        "summary": "Agent wallet",
+	        "description": "Address and balance of the wallet the user's intents are sent from. With HD_MNEMONIC_FILE set each user has their own derived wallet; otherwise all users share the server wallet.",
+	        "parameters": [ { "$ref": "#/components/parameters/UserAddressHeader" } ],
+	        "responses": {
+	          "200": {
+	            "description": "Wallet info",
+	            "content": {
+	              "application/json": {
+	                "schema": { "$ref": "#/components/schemas/WalletInfo" },
+	                "example": {
+	                  "user_address": "0x71C7656EC7ab88b098defB751B7401B5f6d8976F",
+	                  "wallet_address": "0x9858EfFD232B4033E47d90003D41EC34EcaEda94",
+	                  "derivation_path": "m/44'/60'/0'/0/0",
+	                  "balance": "250000000000000000",
+	                  "shared": false
+	                }
+	              }
+	            }
+	          },
+	          "500": {
+	            "description": "Wallet could not be derived or its balance fetched",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/ErrorResponse" } } }
+	          }
+	        }
+	      }
+	    },
+	    "/intent/{id}/approve": {
+	      "post": {
+	        "summary": "Approve an intent",
+	        "description": "Records the caller's approval of an intent in awaiting_approval. Once the required number of approvers agree, the intent is queued again and executed.",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "name": "id", "in": "path", "required": true, "schema": { "type": "string" } }
+	        ],
+	        "requestBody": {
+	          "required": false,
+	          "content": { "application/json": { "schema": { "$ref": "#/components/schemas/ApprovalDecisionRequest" } } }
+	        },
+	        "responses": {
+	          "200": {
+	            "description": "Approval recorded; status is pending once the quorum is reached, awaiting_approval otherwise",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/IntentResponse" } } }
+	          },
+	          "403": { "description": "Caller (X-User-Address) is not an approver of the intent" },
+	          "404": { "description": "Intent not found or not subject to approval" },
+	          "409": { "description": "Intent no longer awaiting approval (approved, rejected or expired), or the caller already decided" }
+	        }
+	      }
+	    },
+	    "/intent/{id}/reject": {
+	      "post": {
+	        "summary": "Reject an intent",
+	        "description": "Records the caller's rejection of an intent in awaiting_approval; a single rejection ends the intent as rejected.",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "name": "id", "in": "path", "required": true, "schema": { "type": "string" } }
+	        ],
+	        "requestBody": {
+	          "required": false,
+	          "content": { "application/json": { "schema": { "$ref": "#/components/schemas/ApprovalDecisionRequest" } } }
+	        },
+	        "responses": {
+	          "200": {
+	            "description": "Rejection recorded; status is rejected",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/IntentResponse" } } }
+	          },
+	          "403": { "description": "Caller (X-User-Address) is not an approver of the intent" },
+	          "404": { "description": "Intent not found or not subject to approval" },
+	          "409": { "description": "Intent no longer awaiting approval (approved, rejected or expired), or the caller already decided" }
+	        }
+	      }
+	    },
+	    "/intent/{id}/steps/{index}/speedup": {
+	      "post": {
+	        "summary": "Speed up a stuck step",
+	        "description": "Rebroadcasts the step's pending transaction at the same nonce with bumped fees",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "name": "id", "in": "path", "required": true, "schema": { "type": "string" } },
+	          { "name": "index", "in": "path", "required": true, "schema": { "type": "integer" } }
+	        ],
+	        "responses": {
+	          "200": {
+	            "description": "Replacement broadcast",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/TxReplacement" } } }
+	          },
+	          "404": { "description": "Step not found or never broadcast" },
+	          "409": { "description": "Step transaction already mined or settled" }
+	        }
+	      }
+	    },
+	    "/intent/{id}/steps/{index}/cancel": {
+	      "post": {
+	        "summary": "Cancel a stuck step",
+	        "description": "Replaces the step's pending transaction with a zero-value self-transfer at the same nonce; the workflow halts once it is mined",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "name": "id", "in": "path", "required": true, "schema": { "type": "string" } },
+	          { "name": "index", "in": "path", "required": true, "schema": { "type": "integer" } }
+	        ],
+	        "responses": {
+	          "200": {
+	            "description": "Replacement broadcast",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/TxReplacement" } } }
+	          },
+	          "404": { "description": "Step not found or never broadcast" },
+	          "409": { "description": "Step transaction already mined or settled" }
+	        }
+	      }
+	    },
+	    "/webhooks": {
+	      "post": {
+	        "summary": "Register a webhook",
+	        "description": "Registers a URL that receives a JSON POST for each lifecycle event of the caller's intents. Every request carries X-TrustFlow-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of \"<t>.<raw body>\"> keyed with the webhook's secret. Failed deliveries are retried with exponential backoff and redirects are not followed. URLs that are or resolve to loopback, private or link-local addresses are rejected.",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" }
+	        ],
+	        "requestBody": {
+	          "required": true,
+	          "content": { "application/json": { "schema": { "$ref": "#/components/schemas/WebhookRequest" } } }
+	        },
+	        "responses": {
+	          "201": {
+	            "description": "Webhook registered; the secret is only returned here",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/Webhook" } } }
+	          },
+	          "400": { "description": "Invalid URL or unknown event type" }
+	        }
+	      },
+	      "get": {
+	        "summary": "List webhooks",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" }
+	        ],
+	        "responses": {
+	          "200": {
+	            "description": "The caller's webhooks, without secrets",
+	            "content": { "application/json": { "schema": { "type": "array", "items": { "$ref": "#/components/schemas/Webhook" } } } }
+	          }
+	        }
+	      }
+	    },
+	    "/webhooks/{id}": {
+	      "delete": {
+	        "summary": "Delete a webhook",
+	        "description": "Removes the webhook; its pending deliveries are abandoned.",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "name": "id", "in": "path", "required": true, "schema": { "type": "string" } }
+	        ],
+	        "responses": {
+	          "204": { "description": "Deleted" },
+	          "404": { "description": "Webhook not found" }
+	        }
+	      }
+	    },
+	    "/webhooks/{id}/deliveries": {
+	      "get": {
+	        "summary": "Webhook delivery log",
+	        "description": "The 100 most recent deliveries to the webhook, newest first, with the outcome of each one's latest attempt.",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "name": "id", "in": "path", "required": true, "schema": { "type": "string" } }
+	        ],
+	        "responses": {
+	          "200": {
+	            "description": "Deliveries",
+	            "content": { "application/json": { "schema": { "type": "array", "items": { "$ref": "#/components/schemas/WebhookDelivery" } } } }
+	          },
+	          "404": { "description": "Webhook not found" }
+	        }
+	      }
+	    },
+	    "/audit/proof/{intent_id}": {
+	      "get": {
+	        "summary": "Audit inclusion proofs for an intent",
+	        "description": "Every audit event of the caller's intent, each with the on-chain anchor covering it and the Merkle path from its leaf, sha256(0x00 || event hash), to the anchor's root. Interior nodes are sha256(0x01 || left || right). Events not anchored yet carry no anchor.",
+	        "parameters": [
+	          { "$ref": "#/components/parameters/UserAddressHeader" },
+	          { "name": "intent_id", "in": "path", "required": true, "schema": { "type": "string" } }
+	        ],
+	        "responses": {
+	          "200": {
+	            "description": "Inclusion proofs",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/IntentAuditProof" } } }
+	          },
+	          "404": { "description": "Intent not found" }
+	        }
+	      }
+	    },
+	    "/admin/api-keys": {
+	      "post": {
+	        "summary": "Create an API key",
+	        "description": "Admin only (a signed-in session for an address in ADMIN_ADDRESSES). Issues a long-lived key acting as the given address with the given scopes. The key is only returned here; just its hash is stored.",
+	        "requestBody": {
+	          "required": true,
+	          "content": { "application/json": { "schema": { "$ref": "#/components/schemas/APIKeyRequest" } } }
+	        },
+	        "responses": {
+	          "201": {
+	            "description": "Key created",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/APIKey" } } }
+	          },
+	          "400": { "description": "Invalid address, scopes or expires_in" },
+	          "403": { "description": "Not an admin session" }
+	        }
+	      },
+	      "get": {
+	        "summary": "List API keys",
+	        "description": "Admin only. Every key, or the keys bound to ?address=, newest first and including revoked ones.",
+	        "parameters": [
+	          { "name": "address", "in": "query", "required": false, "schema": { "type": "string" } }
+	        ],
+	        "responses": {
+	          "200": {
+	            "description": "Keys, without the keys themselves",
+	            "content": { "application/json": { "schema": { "type": "array", "items": { "$ref": "#/components/schemas/APIKey" } } } }
+	          },
+	          "403": { "description": "Not an admin session" }
+	        }
+	      }
+	    },
+	    "/admin/api-keys/{id}": {
+	      "delete": {
+	        "summary": "Revoke an API key",
+	        "description": "Admin only. Requests with the key are rejected from then on.",
+	        "parameters": [
+	          { "name": "id", "in": "path", "required": true, "schema": { "type": "string" } }
+	        ],
+	        "responses": {
+	          "204": { "description": "Revoked" },
+	          "403": { "description": "Not an admin session" },
+	          "404": { "description": "No live key with this ID" }
+	        }
+	      }
+	    },
+	    "/admin/audit/verify": {
+	      "get": {
+	        "summary": "Verify the audit chain",
+	        "description": "Admin only: the chain spans every user's events. Re-walks the hash-chained audit_events log from its first event, recomputing every hash, and reports the first event that is missing, edited or does not link to its predecessor. A broken chain is still a 200 with valid: false.",
+	        "responses": {
+	          "200": {
+	            "description": "Verification report",
+	            "content": { "application/json": { "schema": { "$ref": "#/components/schemas/AuditReport" } } }
+	          },
+	          "403": { "description": "Not an admin session" }
+	        }
+	      }
 	    }
 	  },
   "components": {
@@ -327,23 +784,98 @@ func main() {
       "UserAddressHeader": {
         "name": "X-User-Address",
         "in": "header",
-        "required": true,
+        "required": false,
         "schema": { "type": "string" },
-        "description": "Wallet address used for data scoping"
+        "description": "Must match the signed-in address if sent. Without a session token it is only trusted when the server runs with ALLOW_HEADER_AUTH (development)."
+      },
+      "LastEventIDHeader": {
+        "name": "Last-Event-ID",
+        "in": "header",
+        "required": false,
+        "schema": { "type": "integer" },
+        "description": "Resume after this event sequence number"
+      },
+      "LastEventIDQuery": {
+        "name": "last_event_id",
+        "in": "query",
+        "required": false,
+        "schema": { "type": "integer" },
+        "description": "Same as Last-Event-ID, for clients that cannot set headers"
+      }
+    },
+    "securitySchemes": {
+      "SessionToken": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "Session token from POST /auth/verify, or an API key (tfk_...) from POST /admin/api-keys. API keys only reach the endpoints their scopes allow: intent:submit (submit, speed up, cancel), intent:read (status, intents, events, budget, wallet, audit), intent:approve (approve, reject), simulate, webhooks."
       }
     },
     "schemas": {
+	      "AuthNonce": {
+	        "type": "object",
+	        "properties": {
+	          "nonce": { "type": "string" },
+	          "domain": { "type": "string", "description": "Domain the message must name" },
+	          "chain_id": { "type": "integer", "description": "Chain ID the message must name" },
+	          "intent_salt": { "type": "string", "description": "EIP-712 domain salt for signing intents: keccak256 of the domain" },
+	          "expires_at": { "type": "integer" }
+	        }
+	      },
+	      "SignInRequest": {
+	        "type": "object",
+	        "required": ["message", "signature"],
+	        "properties": {
+	          "message": { "type": "string", "description": "EIP-4361 message, exactly as signed" },
+	          "signature": { "type": "string", "description": "65-byte personal_sign signature, hex" }
+	        }
+	      },
+	      "Session": {
+	        "type": "object",
+	        "properties": {
+	          "token": { "type": "string" },
+	          "address": { "type": "string", "description": "EIP-55 checksummed signed-in address" },
+	          "expires_at": { "type": "integer" }
+	        }
+	      },
+	      "APIKeyRequest": {
+	        "type": "object",
+	        "required": ["address", "scopes"],
+	        "properties": {
+	          "address": { "type": "string", "description": "Address the key acts as" },
+	          "name": { "type": "string" },
+	          "scopes": { "type": "array", "items": { "type": "string", "enum": ["intent:submit", "intent:read", "intent:approve", "simulate", "webhooks"] } },
+	          "expires_in": { "type": "string", "description": "Go duration such as \"720h\"; omit for a key that never expires" }
+	        }
+	      },
+	      "APIKey": {
+	        "type": "object",
+	        "properties": {
+	          "id": { "type": "string" },
+	          "key": { "type": "string", "description": "Sent as Authorization: Bearer <key>; only returned on creation" },
+	          "prefix": { "type": "string", "description": "Start of the key, to tell keys apart" },
+	          "name": { "type": "string" },
+	          "address": { "type": "string" },
+	          "scopes": { "type": "array", "items": { "type": "string" } },
+	          "created_by": { "type": "string" },
+	          "created_at": { "type": "integer" },
+	          "expires_at": { "type": "integer" },
+	          "last_used_at": { "type": "integer" },
+	          "revoked_at": { "type": "integer" }
+	        }
+	      },
 	      "Intent": {
 	        "type": "object",
 	        "properties": {
 	          "id": { "type": "string" },
 	          "action": { "type": "string" },
 	          "params": { "type": "object", "additionalProperties": { "type": "string" } },
+	          "typed_params": { "type": "object", "additionalProperties": {} },
 	          "steps": {
 	            "type": "array",
 	            "items": { "$ref": "#/components/schemas/IntentStep" }
 	          },
-	          "created_at": { "type": "integer", "format": "int64" }
+	          "created_at": { "type": "integer", "format": "int64" },
+	          "signature": { "type": "string", "description": "EIP-712 signature (eth_signTypedData_v4) by the authenticated address over domain {name: TrustFlow, version: 1, chainId, salt: keccak256(AUTH_DOMAIN), as intent_salt from /auth/nonce} and Intent(string id,Step[] steps), Step(string action,Param[] params,Param[] typedParams), Param(string key,string value). Params are sorted by key; typed_params values are their compact JSON. A signed intent must carry its id." }
 	        }
 	      },
 	      "IntentStep": {
@@ -351,7 +883,8 @@ func main() {
 	        "properties": {
 	          "id": { "type": "string" },
 	          "action": { "type": "string" },
-	          "params": { "type": "object", "additionalProperties": { "type": "string" } }
+	          "params": { "type": "object", "additionalProperties": { "type": "string" } },
+	          "typed_params": { "type": "object", "additionalProperties": {} }
 	        },
 	        "required": ["action", "params"]
 	      },
@@ -364,7 +897,11 @@ func main() {
 	          "tx_hash": { "type": "string" },
 	          "tx_hashes": { "type": "array", "items": { "type": "string" } },
 	          "failed_step_index": { "type": "integer" },
-	          "error": { "type": "string" }
+	          "error": { "type": "string" },
+	          "blocked_rule": { "type": "string" },
+	          "solvency": { "$ref": "#/components/schemas/SolvencyReport" },
+	          "approval": { "$ref": "#/components/schemas/ApprovalState" },
+	          "replayed": { "type": "boolean", "description": "True when a retried submission returned the intent already accepted" }
 	        }
 	      },
 	      "StepState": {
@@ -372,31 +909,282 @@ func main() {
 	        "properties": {
 	          "step_index": { "type": "integer" },
 	          "action": { "type": "string" },
-	          "status": { "type": "string" },
+	          "status": { "type": "string", "enum": ["pending", "submitted", "success", "failed", "blocked", "skipped", "unconfirmed", "cancelled"] },
 	          "tx_hash": { "type": "string" },
-	          "error": { "type": "string" }
+	          "error": { "type": "string" },
+	          "block_number": { "type": "integer", "format": "int64" },
+	          "gas_used": { "type": "integer", "format": "int64" },
+	          "receipt_status": { "type": "integer", "description": "1 = success, 0 = reverted on-chain" },
+	          "fees": { "$ref": "#/components/schemas/FeeParams" },
+	          "replacements": { "type": "array", "items": { "$ref": "#/components/schemas/TxReplacement" } }
+	        }
+	      },
+	      "TxReplacement": {
+	        "type": "object",
+	        "properties": {
+	          "kind": { "type": "string", "enum": ["speedup", "cancel"] },
+	          "tx_hash": { "type": "string" },
+	          "fees": { "$ref": "#/components/schemas/FeeParams" },
+	          "created_at": { "type": "integer", "format": "int64" }
+	        }
+	      },
+	      "FeeParams": {
+	        "type": "object",
+	        "properties": {
+	          "type": { "type": "string", "enum": ["legacy", "dynamic_fee"] },
+	          "gas_price": { "type": "string" },
+	          "max_fee_per_gas": { "type": "string" },
+	          "max_priority_fee_per_gas": { "type": "string" },
+	          "base_fee": { "type": "string" }
 	        }
 	      },
 	      "IntentState": {
 	        "type": "object",
 	        "properties": {
 	          "intent_id": { "type": "string" },
-	          "status": { "type": "string" },
+	          "status": { "type": "string", "enum": ["pending", "processing", "awaiting_approval", "success", "failed", "blocked", "rejected", "expired"] },
 	          "created_at": { "type": "integer", "format": "int64" },
 	          "message": { "type": "string" },
 	          "raw_intent": { "type": "string" },
-	          "steps": { "type": "array", "items": { "$ref": "#/components/schemas/StepState" } }
+	          "signature": { "type": "string", "description": "EIP-712 signature authorizing raw_intent, if it was signed" },
+	          "steps": { "type": "array", "items": { "$ref": "#/components/schemas/StepState" } },
+	          "approval": { "$ref": "#/components/schemas/ApprovalState" }
+	        }
+	      },
+	      "ApprovalState": {
+	        "type": "object",
+	        "properties": {
+	          "reason": { "type": "string", "description": "Threshold the workflow exceeded" },
+	          "risk_score": { "type": "integer", "description": "0-100" },
+	          "total_value": { "type": "string", "description": "Wei" },
+	          "required": { "type": "integer" },
+	          "approvers": { "type": "array", "items": { "type": "string" } },
+	          "requested_at": { "type": "integer", "format": "int64" },
+	          "expires_at": { "type": "integer", "format": "int64" },
+	          "approved_at": { "type": "integer", "format": "int64" },
+	          "decisions": { "type": "array", "items": { "$ref": "#/components/schemas/ApprovalDecision" } }
 	        }
 	      },
+	      "ApprovalDecision": {
+	        "type": "object",
+	        "properties": {
+	          "approver": { "type": "string" },
+	          "decision": { "type": "string", "enum": ["approve", "reject"] },
+	          "comment": { "type": "string" },
+	          "created_at": { "type": "integer", "format": "int64" }
+	        }
+	      },
+	      "ApprovalDecisionRequest": {
+	        "type": "object",
+	        "properties": { "comment": { "type": "string" } }
+	      },
 	      "SimulationResponse": {
 	        "type": "object",
 	        "properties": {
 	          "valid": { "type": "boolean" },
 	          "gas_limit": { "type": "integer", "format": "int64" },
-	          "gas_price": { "type": "string" },
+	          "gas_price": { "type": "string", "description": "Max Wei per gas (EIP-1559 fee cap on London chains)" },
 	          "total_cost": { "type": "string" },
+	          "fees": { "$ref": "#/components/schemas/FeeParams" },
 	          "message": { "type": "string" },
-	          "error": { "type": "string" }
+	          "error": { "type": "string" },
+	          "warnings": { "type": "array", "items": { "type": "string" } },
+	          "revert": { "$ref": "#/components/schemas/RevertDetails" },
+	          "failed_step_index": { "type": "integer" },
+	          "steps": { "type": "array", "items": { "$ref": "#/components/schemas/StepSimulation" } },
+	          "solvency": { "$ref": "#/components/schemas/SolvencyReport" },
+	          "simulated_independently": { "type": "boolean", "description": "The node lacks eth_simulateV1, so each step was simulated against the current state only; workflows whose steps depend on each other are refused" }
+	        }
+	      },
+	      "SolvencyReport": {
+	        "type": "object",
+	        "properties": {
+	          "solvent": { "type": "boolean" },
+	          "balance": { "type": "string" },
+	          "gas_price": { "type": "string" },
+	          "total_required": { "type": "string" },
+	          "shortfall": { "type": "string" },
+	          "first_shortfall_at": { "type": "integer" },
+	          "steps": { "type": "array", "items": { "$ref": "#/components/schemas/StepCost" } }
+	        }
+	      },
+	      "StepCost": {
+	        "type": "object",
+	        "properties": {
+	          "step_index": { "type": "integer" },
+	          "value": { "type": "string" },
+	          "gas_limit": { "type": "integer", "format": "int64" },
+	          "gas_cost": { "type": "string" },
+	          "cumulative": { "type": "string" },
+	          "shortfall": { "type": "string" }
+	        }
+	      },
+	      "StepSimulation": {
+	        "type": "object",
+	        "properties": {
+	          "step_index": { "type": "integer" },
+	          "action": { "type": "string" },
+	          "gas_limit": { "type": "integer", "format": "int64" },
+	          "warnings": { "type": "array", "items": { "type": "string" } }
+	        }
+	      },
+	      "RevertDetails": {
+	        "type": "object",
+	        "properties": {
+	          "code": { "type": "string", "enum": ["error", "panic", "custom_error", "unknown"] },
+	          "reason": { "type": "string" },
+	          "data": { "type": "string" }
+	        }
+	      },
+	      "BudgetStatus": {
+	        "type": "object",
+	        "properties": {
+	          "user_address": { "type": "string" },
+	          "limit": { "type": "string" },
+	          "spent": { "type": "string" },
+	          "remaining": { "type": "string" },
+	          "window_seconds": { "type": "integer", "format": "int64" },
+	          "unlimited": { "type": "boolean" }
+	        }
+	      },
+	      "WalletInfo": {
+	        "type": "object",
+	        "properties": {
+	          "user_address": { "type": "string" },
+	          "wallet_address": { "type": "string" },
+	          "derivation_path": { "type": "string", "description": "BIP-44 path; omitted for the shared server wallet" },
+	          "balance": { "type": "string", "description": "Wei" },
+	          "shared": { "type": "boolean" }
+	        }
+	      },
+	      "StatusEvent": {
+	        "type": "object",
+	        "properties": {
+	          "seq": { "type": "integer", "description": "Sequence number; also the SSE event id" },
+	          "intent_id": { "type": "string" },
+	          "kind": { "type": "string", "enum": ["intent", "step"] },
+	          "step_index": { "type": "integer" },
+	          "status": { "type": "string" },
+	          "tx_hash": { "type": "string" },
+	          "message": { "type": "string" },
+	          "created_at": { "type": "integer" }
+	        }
+	      },
+	      "WebhookRequest": {
+	        "type": "object",
+	        "required": ["url"],
+	        "properties": {
+	          "url": { "type": "string", "description": "Absolute http(s) URL" },
+	          "events": {
+	            "type": "array",
+	            "description": "Event types to receive; omit for all",
+	            "items": { "$ref": "#/components/schemas/WebhookEventType" }
+	          }
+	        }
+	      },
+	      "WebhookEventType": {
+	        "type": "string",
+	        "enum": ["intent.created", "step.broadcast", "step.confirmed", "intent.succeeded", "intent.failed", "intent.blocked"]
+	      },
+	      "Webhook": {
+	        "type": "object",
+	        "properties": {
+	          "id": { "type": "string" },
+	          "url": { "type": "string" },
+	          "events": { "type": "array", "items": { "$ref": "#/components/schemas/WebhookEventType" } },
+	          "secret": { "type": "string", "description": "HMAC-SHA256 signing key; only returned on creation" },
+	          "created_at": { "type": "integer" }
+	        }
+	      },
+	      "WebhookEvent": {
+	        "type": "object",
+	        "description": "Body POSTed to a webhook",
+	        "properties": {
+	          "id": { "type": "string", "description": "Also sent as X-TrustFlow-Delivery; the same on every retry" },
+	          "type": { "$ref": "#/components/schemas/WebhookEventType" },
+	          "intent_id": { "type": "string" },
+	          "status": { "type": "string", "description": "Intent status, or step status for step events" },
+	          "step_index": { "type": "integer" },
+	          "tx_hash": { "type": "string" },
+	          "message": { "type": "string" },
+	          "created_at": { "type": "integer" }
+	        }
+	      },
+	      "WebhookDelivery": {
+	        "type": "object",
+	        "properties": {
+	          "id": { "type": "integer" },
+	          "webhook_id": { "type": "string" },
+	          "event_id": { "type": "string" },
+	          "event_type": { "$ref": "#/components/schemas/WebhookEventType" },
+	          "intent_id": { "type": "string" },
+	          "status": { "type": "string", "enum": ["pending", "delivered", "failed"] },
+	          "attempts": { "type": "integer" },
+	          "next_attempt_at": { "type": "integer" },
+	          "response_code": { "type": "integer", "description": "HTTP status of the latest attempt" },
+	          "error": { "type": "string" },
+	          "created_at": { "type": "integer" },
+	          "delivered_at": { "type": "integer" }
+	        }
+	      },
+	      "AuditReport": {
+	        "type": "object",
+	        "properties": {
+	          "valid": { "type": "boolean" },
+	          "checked": { "type": "integer", "description": "Events verified before stopping" },
+	          "head_seq": { "type": "integer", "description": "Last intact event" },
+	          "head_hash": { "type": "string", "description": "Its hash, committing to the whole intact chain" },
+	          "broken_seq": { "type": "integer", "description": "First event that does not link to its predecessor" },
+	          "reason": { "type": "string" }
+	        }
+	      },
+	      "AuditEvent": {
+	        "type": "object",
+	        "properties": {
+	          "seq": { "type": "integer" },
+	          "intent_id": { "type": "string" },
+	          "payload": { "type": "string", "description": "JSON record of the transition, hashed exactly as stored" },
+	          "prev_hash": { "type": "string" },
+	          "hash": { "type": "string", "description": "SHA-256 of \"<prev_hash>\\n<seq>\\n<payload>\", hex" },
+	          "created_at": { "type": "integer" }
+	        }
+	      },
+	      "AuditAnchor": {
+	        "type": "object",
+	        "properties": {
+	          "id": { "type": "integer" },
+	          "from_seq": { "type": "integer", "description": "Exclusive: the first leaf is event from_seq+1" },
+	          "to_seq": { "type": "integer", "description": "Inclusive" },
+	          "root": { "type": "string", "description": "Merkle root, also in the transaction's calldata" },
+	          "tx_hash": { "type": "string" },
+	          "status": { "type": "string", "enum": ["submitted", "confirmed", "failed"] },
+	          "block_number": { "type": "integer" },
+	          "error": { "type": "string" },
+	          "created_at": { "type": "integer" },
+	          "confirmed_at": { "type": "integer" }
+	        }
+	      },
+	      "ProofNode": {
+	        "type": "object",
+	        "properties": {
+	          "hash": { "type": "string" },
+	          "position": { "type": "string", "enum": ["left", "right"], "description": "Side of the running hash the sibling goes on" }
+	        }
+	      },
+	      "AuditProof": {
+	        "type": "object",
+	        "properties": {
+	          "event": { "$ref": "#/components/schemas/AuditEvent" },
+	          "anchor": { "$ref": "#/components/schemas/AuditAnchor" },
+	          "leaf_index": { "type": "integer" },
+	          "path": { "type": "array", "items": { "$ref": "#/components/schemas/ProofNode" } }
+	        }
+	      },
+	      "IntentAuditProof": {
+	        "type": "object",
+	        "properties": {
+	          "intent_id": { "type": "string" },
+	          "proofs": { "type": "array", "items": { "$ref": "#/components/schemas/AuditProof" } }
 	        }
 	      },
 	      "ErrorResponse": {
@@ -415,11 +1203,32 @@ func main() {
 
 	// Define Routes
 	apiGroup := router.Group("/")
-	apiGroup.Use(requireUserHeader)
-	apiGroup.POST("/intent", handler.SubmitIntent)
-	apiGroup.POST("/simulate", handler.SimulateIntent)
-	apiGroup.GET("/status/:id", handler.GetStatus)
-	apiGroup.GET("/intents", handler.ListIntents)
+	apiGroup.Use(authn.Middleware(cfg.AllowHeaderAuth)) // Every route below acts as the signed-in address
+	apiGroup.POST("/intent", auth.Require(auth.ScopeIntentSubmit), handler.SubmitIntent)
+	apiGroup.POST("/simulate", auth.Require(auth.ScopeSimulate), handler.SimulateIntent)
+	apiGroup.GET("/status/:id", auth.Require(auth.ScopeIntentRead), handler.GetStatus)
+	apiGroup.GET("/intents", auth.Require(auth.ScopeIntentRead), handler.ListIntents)
+	apiGroup.GET("/intents/:id/events", auth.Require(auth.ScopeIntentRead), handler.StreamIntentEvents)
+	apiGroup.GET("/events", auth.Require(auth.ScopeIntentRead), handler.StreamEvents)
+	apiGroup.GET("/budget", auth.Require(auth.ScopeIntentRead), handler.GetBudget)
+	apiGroup.GET("/wallet", auth.Require(auth.ScopeIntentRead), handler.GetWallet)
+	apiGroup.POST("/intent/:id/approve", auth.Require(auth.ScopeIntentApprove), handler.ApproveIntent)
+	apiGroup.POST("/intent/:id/reject", auth.Require(auth.ScopeIntentApprove), handler.RejectIntent)
+	apiGroup.POST("/intent/:id/steps/:index/speedup", auth.Require(auth.ScopeIntentSubmit), handler.SpeedUpStep)
+	apiGroup.POST("/intent/:id/steps/:index/cancel", auth.Require(auth.ScopeIntentSubmit), handler.CancelStep)
+	apiGroup.POST("/webhooks", auth.Require(auth.ScopeWebhooks), handler.CreateWebhook)
+	apiGroup.GET("/webhooks", auth.Require(auth.ScopeWebhooks), handler.ListWebhooks)
+	apiGroup.DELETE("/webhooks/:id", auth.Require(auth.ScopeWebhooks), handler.DeleteWebhook)
+	apiGroup.GET("/webhooks/:id/deliveries", auth.Require(auth.ScopeWebhooks), handler.ListWebhookDeliveries)
+	apiGroup.GET("/audit/proof/:intent_id", auth.Require(auth.ScopeIntentRead), handler.GetAuditProof)
+	adminGroup := router.Group("/admin")
+	adminGroup.Use(authn.Middleware(false), authn.RequireAdmin()) // Never header auth, even in development
+	adminGroup.POST("/api-keys", handler.CreateAPIKey)
+	adminGroup.GET("/api-keys", handler.ListAPIKeys)
+	adminGroup.DELETE("/api-keys/:id", handler.RevokeAPIKey)
+	adminGroup.GET("/audit/verify", handler.VerifyAudit)
+	router.GET("/auth/nonce", handler.GetAuthNonce)
+	router.POST("/auth/verify", handler.VerifySignIn)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status": "ok",
@@ -438,8 +1247,24 @@ func main() {
 	if port == "" {
 		port = "8081"
 	}
-	log.Println("Starting TrustFlow Orchestrator on :" + port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		log.Println("Starting TrustFlow Orchestrator on :" + port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to run server: %v", err)
+		}
+	}()
+
+	// Graceful shutdown: stop taking requests and claiming intents, then let workers finish
+	// the intents they hold. Any still running past the drain timeout resume on restart.
+	<-ctx.Done()
+	log.Println("🛑 Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️ HTTP shutdown: %v", err)
+	}
+	if !orch.Wait(cfg.ShutdownTimeout) {
+		log.Printf("⚠️ Workers still busy after %s; their intents resume on restart", cfg.ShutdownTimeout)
 	}
 }
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"trustflow/src/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKey handles the POST /admin/api-keys request. The response carries the key,
+// which is not returned again.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var body struct {
+		Address   string   `json:"address" binding:"required"`
+		Name      string   `json:"name"`
+		Scopes    []string `json:"scopes" binding:"required"`
+		ExpiresIn string   `json:"expires_in"` // Go duration, e.g. "720h"; empty never expires
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if body.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(body.ExpiresIn)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expires_in"})
+			return
+		}
+		ttl = parsed
+	}
+
+	admin := auth.UserAddress(c)
+	key, err := h.auth.CreateAPIKey(admin, body.Address, body.Name, body.Scopes, ttl)
+	if errors.Is(err, auth.ErrInvalidAPIKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create API key for %s: %v", body.Address, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	log.Printf("🔑 %s issued API key %s for %s with scopes %v", admin, key.Prefix, key.Address, key.Scopes)
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys handles the GET /admin/api-keys request, optionally filtered by ?address=
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.auth.ListAPIKeys(c.Query("address"))
+	if errors.Is(err, auth.ErrInvalidAPIKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles the DELETE /admin/api-keys/:id request
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	err := h.auth.RevokeAPIKey(c.Param("id"))
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to revoke API key %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	log.Printf("🔑 %s revoked API key %s", auth.UserAddress(c), c.Param("id"))
+	c.Status(http.StatusNoContent)
+}
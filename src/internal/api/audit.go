@@ -0,0 +1,42 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"trustflow/src/internal/audit"
+	"trustflow/src/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyAudit handles the GET /admin/audit/verify request. A broken chain is still a 200: the
+// report says where it breaks.
+func (h *Handler) VerifyAudit(c *gin.Context) {
+	report, err := h.audit.Verify()
+	if err != nil {
+		log.Printf("Audit verification failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit log"})
+		return
+	}
+	if !report.Valid {
+		log.Printf("🚨 Audit chain broken at event %d: %s", report.BrokenSeq, report.Reason)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAuditProof handles the GET /audit/proof/:intent_id request
+func (h *Handler) GetAuditProof(c *gin.Context) {
+	proof, err := h.audit.Proof(auth.UserAddress(c), c.Param("intent_id"))
+	if errors.Is(err, audit.ErrIntentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Intent not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to build audit proof for %s: %v", c.Param("intent_id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build audit proof"})
+		return
+	}
+	c.JSON(http.StatusOK, proof)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"trustflow/src/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuthNonce handles the GET /auth/nonce request
+func (h *Handler) GetAuthNonce(c *gin.Context) {
+	nonce, err := h.auth.NewNonce(c.ClientIP())
+	switch {
+	case errors.Is(err, auth.ErrTooManyNonces):
+		c.Header("Retry-After", "60")
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrSignInDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Failed to issue nonce: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue nonce"})
+		return
+	}
+	c.JSON(http.StatusOK, nonce)
+}
+
+// VerifySignIn handles the POST /auth/verify request: a signed EIP-4361 message is
+// exchanged for a session token
+func (h *Handler) VerifySignIn(c *gin.Context) {
+	var body struct {
+		Message   string `json:"message" binding:"required"`
+		Signature string `json:"signature" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.auth.Verify(body.Message, body.Signature)
+	switch {
+	case errors.Is(err, auth.ErrSignInDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrInvalidSignature), errors.Is(err, auth.ErrInvalidNonce):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Sign-in failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	log.Printf("🔑 Signed in %s until %d", session.Address, session.ExpiresAt)
+	c.JSON(http.StatusOK, session)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"trustflow/src/internal/auth"
+	"trustflow/src/internal/stream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keepAliveInterval is how often an idle stream sends a comment so proxies keep it open
+const keepAliveInterval = 15 * time.Second
+
+// StreamIntentEvents handles the GET /intents/:id/events request
+func (h *Handler) StreamIntentEvents(c *gin.Context) {
+	userID := auth.UserAddress(c)
+	state, err := h.orch.GetIntentStatus(userID, c.Param("id"))
+	if err != nil {
+		log.Printf("Failed to get status for %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if state == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Intent not found"})
+		return
+	}
+	h.streamEvents(c, userID, state.IntentID)
+}
+
+// StreamEvents handles the GET /events request
+func (h *Handler) StreamEvents(c *gin.Context) {
+	h.streamEvents(c, auth.UserAddress(c), "")
+}
+
+// streamEvents sends the user's status events as Server-Sent Events until the client goes
+// away. It resumes after the Last-Event-ID header (sent by EventSource on reconnect) or the
+// last_event_id query parameter, and otherwise starts with the latest events.
+func (h *Handler) streamEvents(c *gin.Context, userID string, intentID string) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	afterSeq := stream.FromRecent
+	if lastEventID != "" {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		afterSeq = seq
+	}
+
+	events, cancel, err := h.events.Subscribe(userID, intentID, afterSeq)
+	if err != nil {
+		log.Printf("Failed to open event stream for %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open event stream"})
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return // Storage failed; the client reconnects with Last-Event-ID
+			}
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Kind, data)
+		}
+		c.Writer.Flush()
+	}
+}
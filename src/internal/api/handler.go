@@ -1,27 +1,48 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"io"
 	"log"
 	"math/big"
 	"net/http"
+	"strconv"
 	"time"
+	"trustflow/src/internal/audit"
+	"trustflow/src/internal/auth"
 	"trustflow/src/internal/orchestrator"
 	"trustflow/src/internal/simulator"
+	"trustflow/src/internal/stream"
+	"trustflow/src/internal/webhook"
 	"trustflow/src/pkg/types"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// maxIdempotencyKeyLen bounds the Idempotency-Key header
+const maxIdempotencyKeyLen = 255
+
 type Handler struct {
-	orch *orchestrator.Orchestrator
-	sim  *simulator.Simulator // Kept for SimulateIntent (dry-run)
+	orch   *orchestrator.Orchestrator
+	hooks  *webhook.Dispatcher
+	events *stream.Hub
+	auth   *auth.Authenticator
+	audit  *audit.Log
+
+	requireSignedIntents bool // Reject intents without an EIP-712 signature
 }
 
-func NewHandler(orch *orchestrator.Orchestrator, sim *simulator.Simulator) *Handler {
+func NewHandler(orch *orchestrator.Orchestrator, hooks *webhook.Dispatcher, events *stream.Hub, authn *auth.Authenticator, auditLog *audit.Log, requireSignedIntents bool) *Handler {
 	return &Handler{
-		orch: orch,
-		sim:  sim,
+		orch:   orch,
+		hooks:  hooks,
+		events: events,
+		auth:   authn,
+		audit:  auditLog,
+
+		requireSignedIntents: requireSignedIntents,
 	}
 }
 
@@ -36,6 +57,13 @@ func (h *Handler) SubmitIntent(c *gin.Context) {
 		return
 	}
 
+	// A signature proves the submitting address's own key authorized exactly these steps
+	userID := auth.UserAddress(c)
+	if err := h.auth.VerifyIntent(intent, userID, h.requireSignedIntents); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Assign ID and Timestamp if missing
 	if intent.ID == "" {
 		intent.ID = uuid.New().String()
@@ -44,24 +72,37 @@ func (h *Handler) SubmitIntent(c *gin.Context) {
 		intent.CreatedAt = time.Now().Unix()
 	}
 
-	// Delegate to Orchestrator
-	userID := c.GetHeader("X-User-Address")
-	response, err := h.orch.ProcessIntent(c.Request.Context(), userID, intent)
+	// Retries with the same key (or client-supplied ID) return the intent already accepted
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+		return
+	}
+
+	// Queue for background processing; progress is reported by GET /status/:id
+	response, err := h.orch.SubmitIntent(userID, idempotencyKey, intent)
+	if errors.Is(err, orchestrator.ErrNoActions) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, orchestrator.ErrIntentIDTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, orchestrator.ErrIdempotencyMismatch) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		log.Printf("Orchestration failed: %v", err)
+		log.Printf("Submission failed: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Determine status code based on response status
-	statusCode := http.StatusOK
-	if response.Status == "failed" {
-		// If some steps succeeded but later ones failed, it's a partial failure (often 206 or 422, or 502)
-		// 422 Unprocessable Entity seems appropriate if the intent couldn't be fully processed.
-		statusCode = http.StatusUnprocessableEntity
+	if response.Replayed {
+		c.Header("Idempotent-Replayed", "true")
 	}
-
-	c.JSON(statusCode, response)
+	c.JSON(http.StatusAccepted, response)
 }
 
 // GetStatus handles the GET /status/:id request
@@ -72,7 +113,7 @@ func (h *Handler) GetStatus(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetHeader("X-User-Address")
+	userID := auth.UserAddress(c)
 	state, err := h.orch.GetIntentStatus(userID, id)
 	if err != nil {
 		log.Printf("Failed to get status for %s: %v", id, err)
@@ -90,7 +131,7 @@ func (h *Handler) GetStatus(c *gin.Context) {
 
 // ListIntents handles the GET /intents request
 func (h *Handler) ListIntents(c *gin.Context) {
-	userID := c.GetHeader("X-User-Address")
+	userID := auth.UserAddress(c)
 	intents, err := h.orch.ListIntents(userID, 50)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch intents"})
@@ -99,6 +140,173 @@ func (h *Handler) ListIntents(c *gin.Context) {
 	c.JSON(http.StatusOK, intents)
 }
 
+// GetBudget handles the GET /budget request
+func (h *Handler) GetBudget(c *gin.Context) {
+	userID := auth.UserAddress(c)
+	status, err := h.orch.GetBudget(userID)
+	if err != nil {
+		log.Printf("Failed to get budget for %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budget"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// GetWallet handles the GET /wallet request
+func (h *Handler) GetWallet(c *gin.Context) {
+	userID := auth.UserAddress(c)
+	info, err := h.orch.GetWallet(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to get wallet for %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// ApproveIntent handles the POST /intent/:id/approve request
+func (h *Handler) ApproveIntent(c *gin.Context) {
+	h.decide(c, h.orch.ApproveIntent)
+}
+
+// RejectIntent handles the POST /intent/:id/reject request
+func (h *Handler) RejectIntent(c *gin.Context) {
+	h.decide(c, h.orch.RejectIntent)
+}
+
+// decide records the caller's decision on an intent awaiting their approval
+func (h *Handler) decide(c *gin.Context, decide func(approver, intentID, comment string) (*types.IntentResponse, error)) {
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	// The body is optional
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	approver := auth.UserAddress(c)
+	response, err := decide(approver, c.Param("id"), body.Comment)
+	switch {
+	case errors.Is(err, orchestrator.ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, orchestrator.ErrNotApprover):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, orchestrator.ErrApprovalClosed), errors.Is(err, orchestrator.ErrAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Decision on %s by %s failed: %v", c.Param("id"), approver, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SpeedUpStep handles the POST /intent/:id/steps/:index/speedup request
+func (h *Handler) SpeedUpStep(c *gin.Context) {
+	h.replaceStep(c, h.orch.SpeedUpStep)
+}
+
+// CancelStep handles the POST /intent/:id/steps/:index/cancel request
+func (h *Handler) CancelStep(c *gin.Context) {
+	h.replaceStep(c, h.orch.CancelStep)
+}
+
+func (h *Handler) replaceStep(c *gin.Context, replace func(ctx context.Context, userID, intentID string, stepIndex int) (*types.TxReplacement, error)) {
+	stepIndex, err := strconv.Atoi(c.Param("index"))
+	if err != nil || stepIndex < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid step index"})
+		return
+	}
+
+	userID := auth.UserAddress(c)
+	replacement, err := replace(c.Request.Context(), userID, c.Param("id"), stepIndex)
+	if errors.Is(err, orchestrator.ErrStepNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, orchestrator.ErrStepNotPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Replacement failed for %s step %d: %v", c.Param("id"), stepIndex, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, replacement)
+}
+
+// CreateWebhook handles the POST /webhooks request. The response carries the signing
+// secret, which is not returned again.
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var body struct {
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := auth.UserAddress(c)
+	hook, err := h.hooks.Register(userID, body.URL, body.Events)
+	if errors.Is(err, webhook.ErrInvalidWebhook) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to register webhook for %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register webhook"})
+		return
+	}
+	c.JSON(http.StatusCreated, hook)
+}
+
+// ListWebhooks handles the GET /webhooks request
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.hooks.List(auth.UserAddress(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// DeleteWebhook handles the DELETE /webhooks/:id request
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	err := h.hooks.Delete(auth.UserAddress(c), c.Param("id"))
+	if errors.Is(err, webhook.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete webhook %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries handles the GET /webhooks/:id/deliveries request
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	deliveries, err := h.hooks.Deliveries(auth.UserAddress(c), c.Param("id"))
+	if errors.Is(err, webhook.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
 // SimulateIntent handles the POST /simulate request
 func (h *Handler) SimulateIntent(c *gin.Context) {
 	var intent types.Intent
@@ -112,28 +320,63 @@ func (h *Handler) SimulateIntent(c *gin.Context) {
 		return
 	}
 
-	// 1. Parse Intent
-	candidate, err := simulator.ParseIntent(intent)
-	if err != nil {
+	steps := intent.WorkflowSteps()
+	if len(steps) == 0 {
 		c.JSON(http.StatusOK, types.SimulationResponse{
 			Valid: false,
-			Error: "Parsing Failed: " + err.Error(),
+			Error: "Parsing Failed: no actions found in intent",
 		})
 		return
 	}
 
-	// 2. Simulate (Get Gas Limit)
-	gasLimit, err := h.sim.Simulate(c.Request.Context(), candidate)
+	// Dry-run from the wallet that would send the intent
+	sim, err := h.orch.Simulator(auth.UserAddress(c))
 	if err != nil {
-		c.JSON(http.StatusOK, types.SimulationResponse{
-			Valid: false,
-			Error: "Simulation Reverted: " + err.Error(),
-		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 1. Parse every step
+	candidates := make([]*simulator.TxCandidate, len(steps))
+	for i, step := range steps {
+		candidate, err := sim.Parse(c.Request.Context(), step.AsIntent())
+		if err != nil {
+			failedIdx := i
+			c.JSON(http.StatusOK, types.SimulationResponse{
+				Valid:           false,
+				Error:           "Parsing Failed: " + err.Error(),
+				FailedStepIndex: &failedIdx,
+			})
+			return
+		}
+		candidates[i] = candidate
+	}
+
+	// 2. Simulate the whole workflow against cumulative state (Get Gas Limits)
+	gasLimits, independent, err := sim.SimulateWorkflow(c.Request.Context(), candidates)
+	if err != nil {
+		response := types.SimulationResponse{
+			Valid:                  false,
+			Error:                  "Simulation Reverted: " + err.Error(),
+			SimulatedIndependently: independent,
+		}
+		var wfErr *simulator.WorkflowError
+		if errors.As(err, &wfErr) {
+			response.FailedStepIndex = &wfErr.StepIndex
+		}
+		if errors.Is(err, simulator.ErrUnlimitedApproval) || errors.Is(err, simulator.ErrDependentSteps) {
+			response.Error = "Simulation Refused: " + err.Error()
+		}
+		var simErr *simulator.SimulationError
+		if errors.As(err, &simErr) {
+			response.Revert = simErr.Details()
+		}
+		c.JSON(http.StatusOK, response)
 		return
 	}
 
-	// 3. Get Cost Details
-	gasPrice, err := h.sim.GetGasPrice(c.Request.Context())
+	// 3. Get Cost Details (EIP-1559 fee cap on London chains, legacy gas price otherwise)
+	fees, err := sim.SuggestFees(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusOK, types.SimulationResponse{
 			Valid: false,
@@ -141,16 +384,54 @@ func (h *Handler) SimulateIntent(c *gin.Context) {
 		})
 		return
 	}
+	gasPrice := fees.MaxPricePerGas()
+
+	// 4. Check Solvency for the aggregate of all steps
+	report, err := sim.CheckWorkflowSolvency(c.Request.Context(), candidates, gasLimits)
+	if err != nil {
+		response := types.SimulationResponse{
+			Valid:    false,
+			GasPrice: gasPrice.String(),
+			Error:    "Solvency Check Failed: " + err.Error(),
+			Fees:     fees.Details(),
+			Solvency: report,
+		}
+		if report != nil {
+			response.FailedStepIndex = report.FirstShortfallAt
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	var totalGas uint64
+	var warnings []string
+	stepResults := make([]types.StepSimulation, len(steps))
+	for i, step := range steps {
+		totalGas += gasLimits[i]
+		warnings = append(warnings, candidates[i].Warnings()...)
+		stepResults[i] = types.StepSimulation{
+			StepIndex: i,
+			Action:    step.Action,
+			GasLimit:  gasLimits[i],
+			Warnings:  candidates[i].Warnings(),
+		}
+	}
 
 	// Calculate Total Cost (Gas * Price)
-	totalCost := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasPrice)
+	totalCost := new(big.Int).Mul(new(big.Int).SetUint64(totalGas), gasPrice)
 
 	response := types.SimulationResponse{
 		Valid:     true,
-		GasLimit:  gasLimit,
+		GasLimit:  totalGas,
 		GasPrice:  gasPrice.String(),
 		TotalCost: totalCost.String(),
 		Message:   "Simulation Successful",
+		Warnings:  warnings,
+		Fees:      fees.Details(),
+		Steps:     stepResults,
+		Solvency:  report,
+
+		SimulatedIndependently: independent,
 	}
 
 	c.JSON(http.StatusOK, response)
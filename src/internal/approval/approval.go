@@ -0,0 +1,278 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"trustflow/src/internal/policy"
+	"trustflow/src/internal/simulator"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// RuleApprovalRequired is reported when an intent needs human approval but nobody can give it
+const RuleApprovalRequired = "approval_required"
+
+// DefaultUser keys the approver set used for users without their own entry
+const DefaultUser = "*"
+
+// DefaultToken keys the token threshold used for tokens without their own entry
+const DefaultToken = "*"
+
+// Risk points per workflow property; the score is capped at 100
+const (
+	riskUnlimitedApproval = 50 // type(uint256).max allowance the intent opted in to
+	riskContractCall      = 25 // Arbitrary calldata to an arbitrary contract
+	riskTokenApproval     = 10 // Finite allowance to a spender
+)
+
+// Rules is the approval configuration loaded from the approval file.
+// An intent needs approval when it exceeds any threshold; empty thresholds are not enforced.
+type Rules struct {
+	ValueThreshold  string                 `json:"value_threshold" yaml:"value_threshold"`   // Wei, total value of the workflow
+	TokenThresholds map[string]string      `json:"token_thresholds" yaml:"token_thresholds"` // Base units per ERC-20 token address, or "*" for every other token
+	RiskThreshold   int                    `json:"risk_threshold" yaml:"risk_threshold"`     // Risk score (0-100) from RiskScore
+	Expiry          string                 `json:"expiry" yaml:"expiry"`                     // How long approvers have to decide (default 24h)
+	Approvers       map[string]ApproverSet `json:"approvers" yaml:"approvers"`               // Keyed by user address, or "*" for everyone else
+}
+
+// ApproverSet is an M-of-N group: Required of the Addresses must approve
+type ApproverSet struct {
+	Required  int      `json:"required" yaml:"required"`
+	Addresses []string `json:"addresses" yaml:"addresses"`
+}
+
+// Requirement is the approval a workflow must collect before it is executed
+type Requirement struct {
+	Reason     string
+	RiskScore  int
+	TotalValue *big.Int
+	Required   int
+	Approvers  []string
+	Expiry     time.Duration
+}
+
+// State converts the requirement to the approval state persisted with the intent
+func (r *Requirement) State(now time.Time) *types.ApprovalState {
+	return &types.ApprovalState{
+		Reason:      r.Reason,
+		RiskScore:   r.RiskScore,
+		TotalValue:  r.TotalValue.String(),
+		Required:    r.Required,
+		Approvers:   r.Approvers,
+		RequestedAt: now.Unix(),
+		ExpiresAt:   now.Add(r.Expiry).Unix(),
+	}
+}
+
+// Gate decides which workflows must wait for human approval, and who may give it
+type Gate struct {
+	valueThreshold  *big.Int            // nil: value never requires approval
+	tokenThresholds map[string]*big.Int // Keyed by checksummed token address or "*"; empty: token amounts never require approval
+	riskThreshold   int                 // 0: risk never requires approval
+	expiry          time.Duration
+	approvers       map[string]ApproverSet // Keyed by lowercase user address
+}
+
+// LoadFile reads approval rules from a YAML or JSON file
+func LoadFile(path string) (*Gate, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approval file: %w", err)
+	}
+
+	var rules Rules
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(raw, &rules)
+	default:
+		err = yaml.Unmarshal(raw, &rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse approval file: %w", err)
+	}
+
+	return NewGate(rules)
+}
+
+// NewGate validates the rules and prepares them for evaluation
+func NewGate(rules Rules) (*Gate, error) {
+	g := &Gate{
+		tokenThresholds: make(map[string]*big.Int),
+		riskThreshold:   rules.RiskThreshold,
+		expiry:          24 * time.Hour,
+		approvers:       make(map[string]ApproverSet),
+	}
+
+	if rules.ValueThreshold != "" {
+		v, ok := new(big.Int).SetString(rules.ValueThreshold, 10)
+		if !ok || v.Sign() < 0 {
+			return nil, fmt.Errorf("value_threshold: invalid wei amount %q", rules.ValueThreshold)
+		}
+		g.valueThreshold = v
+	}
+	for token, threshold := range rules.TokenThresholds {
+		if token != DefaultToken && !common.IsHexAddress(token) {
+			return nil, fmt.Errorf("token_thresholds: invalid token address %q", token)
+		}
+		v, ok := new(big.Int).SetString(threshold, 10)
+		if !ok || v.Sign() < 0 {
+			return nil, fmt.Errorf("token_thresholds[%s]: invalid amount %q", token, threshold)
+		}
+		if token != DefaultToken {
+			token = common.HexToAddress(token).Hex()
+		}
+		g.tokenThresholds[token] = v
+	}
+	if rules.RiskThreshold < 0 || rules.RiskThreshold > 100 {
+		return nil, fmt.Errorf("risk_threshold: must be between 0 and 100, got %d", rules.RiskThreshold)
+	}
+	if rules.Expiry != "" {
+		expiry, err := time.ParseDuration(rules.Expiry)
+		if err != nil || expiry <= 0 {
+			return nil, fmt.Errorf("expiry: invalid duration %q", rules.Expiry)
+		}
+		g.expiry = expiry
+	}
+
+	for user, set := range rules.Approvers {
+		if user != DefaultUser && !common.IsHexAddress(user) {
+			return nil, fmt.Errorf("approvers: invalid user address %q", user)
+		}
+		addresses := make([]string, len(set.Addresses))
+		seen := make(map[common.Address]bool)
+		for i, addr := range set.Addresses {
+			if !common.IsHexAddress(addr) {
+				return nil, fmt.Errorf("approvers[%s]: invalid approver address %q", user, addr)
+			}
+			if seen[common.HexToAddress(addr)] {
+				return nil, fmt.Errorf("approvers[%s]: duplicate approver %s", user, addr)
+			}
+			seen[common.HexToAddress(addr)] = true
+			addresses[i] = common.HexToAddress(addr).Hex()
+		}
+		if set.Required < 1 || set.Required > len(addresses) {
+			return nil, fmt.Errorf("approvers[%s]: required must be between 1 and %d, got %d", user, len(addresses), set.Required)
+		}
+		g.approvers[strings.ToLower(user)] = ApproverSet{Required: set.Required, Addresses: addresses}
+	}
+
+	return g, nil
+}
+
+// Evaluate returns the approval a workflow needs, or nil if it may run straight away.
+// It returns a violation when approval is needed but no approvers are configured for the user.
+func (g *Gate) Evaluate(userID string, steps []types.IntentStep, candidates []*simulator.TxCandidate) (*Requirement, *policy.Violation) {
+	if g == nil {
+		return nil, nil // No approval gate configured
+	}
+
+	totalValue := new(big.Int)
+	for _, candidate := range candidates {
+		if candidate.Value != nil {
+			totalValue.Add(totalValue, candidate.Value)
+		}
+	}
+	score := RiskScore(steps, candidates)
+
+	var reasons []string
+	if g.valueThreshold != nil && totalValue.Cmp(g.valueThreshold) > 0 {
+		reasons = append(reasons, fmt.Sprintf("total value %s wei exceeds %s wei", totalValue, g.valueThreshold))
+	}
+	reasons = append(reasons, g.tokenReasons(candidates)...)
+	if g.riskThreshold > 0 && score > g.riskThreshold {
+		reasons = append(reasons, fmt.Sprintf("risk score %d exceeds %d", score, g.riskThreshold))
+	}
+	if len(reasons) == 0 {
+		return nil, nil
+	}
+	reason := strings.Join(reasons, "; ")
+
+	set, ok := g.approvers[strings.ToLower(userID)]
+	if !ok {
+		set, ok = g.approvers[DefaultUser]
+	}
+	if !ok {
+		return nil, &policy.Violation{
+			Rule:    RuleApprovalRequired,
+			Message: reason + ", but no approvers are configured for this user",
+		}
+	}
+
+	return &Requirement{
+		Reason:     reason,
+		RiskScore:  score,
+		TotalValue: totalValue,
+		Required:   set.Required,
+		Approvers:  set.Addresses,
+		Expiry:     g.expiry,
+	}, nil
+}
+
+// tokenReasons totals the ERC-20 amounts a workflow transfers or approves per token and
+// reports each token over its threshold, in the order the workflow first touches them
+func (g *Gate) tokenReasons(candidates []*simulator.TxCandidate) []string {
+	if len(g.tokenThresholds) == 0 {
+		return nil
+	}
+	var tokens []string
+	totals := make(map[string]*big.Int)
+	for _, candidate := range candidates {
+		if candidate.TokenAmount == nil || candidate.ToAddress == nil {
+			continue
+		}
+		token := candidate.ToAddress.Hex()
+		if totals[token] == nil {
+			tokens = append(tokens, token)
+			totals[token] = new(big.Int)
+		}
+		totals[token].Add(totals[token], candidate.TokenAmount)
+	}
+
+	var reasons []string
+	for _, token := range tokens {
+		threshold, ok := g.tokenThresholds[token]
+		if !ok {
+			threshold, ok = g.tokenThresholds[DefaultToken]
+		}
+		if ok && totals[token].Cmp(threshold) > 0 {
+			reasons = append(reasons, fmt.Sprintf("token %s amount %s exceeds %s", token, totals[token], threshold))
+		}
+	}
+	return reasons
+}
+
+// RiskScore rates a workflow from 0 to 100 by the kind of access it hands out:
+// unlimited allowances (including contract_call approvals decoded by the simulator),
+// arbitrary contract calls and token approvals
+func RiskScore(steps []types.IntentStep, candidates []*simulator.TxCandidate) int {
+	score := 0
+	for i, step := range steps {
+		switch {
+		case i < len(candidates) && candidates[i].UnlimitedApproval:
+			score += riskUnlimitedApproval
+		case step.Action == "contract_call":
+			score += riskContractCall
+		case step.Action == "erc20_approve":
+			score += riskTokenApproval
+		}
+	}
+	return min(score, 100)
+}
+
+// IsApprover reports whether address is one of the approvers of state
+func IsApprover(state *types.ApprovalState, address string) bool {
+	for _, approver := range state.Approvers {
+		if strings.EqualFold(approver, address) {
+			return true
+		}
+	}
+	return false
+}
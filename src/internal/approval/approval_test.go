@@ -0,0 +1,186 @@
+package approval_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+	"trustflow/src/internal/approval"
+	"trustflow/src/internal/simulator"
+	"trustflow/src/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	user      = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+	approverA = "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"
+	approverB = "0x4B20993Bc481177ec7E8f571ceCaE8A9e22C02db"
+	token     = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+)
+
+func payment(wei int64) ([]types.IntentStep, []*simulator.TxCandidate) {
+	return []types.IntentStep{{Action: "payment"}}, []*simulator.TxCandidate{{Value: big.NewInt(wei)}}
+}
+
+func TestGate_Evaluate(t *testing.T) {
+	gate, err := approval.NewGate(approval.Rules{
+		ValueThreshold: "1000",
+		RiskThreshold:  40,
+		Expiry:         "1h",
+		Approvers: map[string]approval.ApproverSet{
+			user: {Required: 2, Addresses: []string{approverA, approverB}},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("Below Thresholds", func(t *testing.T) {
+		steps, candidates := payment(1000)
+		req, violation := gate.Evaluate(user, steps, candidates)
+		assert.Nil(t, req)
+		assert.Nil(t, violation)
+	})
+
+	t.Run("Value Over Threshold", func(t *testing.T) {
+		steps, candidates := payment(600)
+		steps = append(steps, steps[0])
+		candidates = append(candidates, candidates[0])
+
+		req, violation := gate.Evaluate(user, steps, candidates)
+		assert.Nil(t, violation)
+		require.NotNil(t, req)
+		assert.Equal(t, "1200", req.TotalValue.String())
+		assert.Equal(t, 2, req.Required)
+		assert.Equal(t, []string{approverA, approverB}, req.Approvers)
+		assert.Contains(t, req.Reason, "total value 1200 wei")
+	})
+
+	t.Run("Risk Over Threshold", func(t *testing.T) {
+		steps := []types.IntentStep{{Action: "erc20_approve"}}
+		candidates := []*simulator.TxCandidate{{Value: big.NewInt(0), UnlimitedApproval: true, AllowUnlimited: true}}
+
+		req, violation := gate.Evaluate(user, steps, candidates)
+		assert.Nil(t, violation)
+		require.NotNil(t, req)
+		assert.Equal(t, 50, req.RiskScore)
+		assert.Contains(t, req.Reason, "risk score 50")
+	})
+
+	t.Run("Unlimited contract_call Approve Scores As Unlimited", func(t *testing.T) {
+		approve, err := simulator.ParseIntent(types.Intent{Action: "contract_call", Params: map[string]string{
+			"contract":        token,
+			"function":        "approve(address,uint256)",
+			"args":            `["` + approverA + `", "` + simulator.MaxUint256.String() + `"]`,
+			"allow_unlimited": "true",
+		}})
+		require.NoError(t, err)
+
+		req, violation := gate.Evaluate(user, []types.IntentStep{{Action: "contract_call"}}, []*simulator.TxCandidate{approve})
+		assert.Nil(t, violation)
+		require.NotNil(t, req)
+		assert.Equal(t, 50, req.RiskScore)
+	})
+
+	t.Run("No Approvers Configured", func(t *testing.T) {
+		steps, candidates := payment(5000)
+		req, violation := gate.Evaluate("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", steps, candidates)
+		assert.Nil(t, req)
+		require.NotNil(t, violation)
+		assert.Equal(t, approval.RuleApprovalRequired, violation.Rule)
+	})
+
+	t.Run("Nil Gate", func(t *testing.T) {
+		var none *approval.Gate
+		steps, candidates := payment(5000)
+		req, violation := none.Evaluate(user, steps, candidates)
+		assert.Nil(t, req)
+		assert.Nil(t, violation)
+	})
+}
+
+func TestGate_DefaultApprovers(t *testing.T) {
+	gate, err := approval.NewGate(approval.Rules{
+		ValueThreshold: "0",
+		Approvers: map[string]approval.ApproverSet{
+			approval.DefaultUser: {Required: 1, Addresses: []string{approverA}},
+		},
+	})
+	require.NoError(t, err)
+
+	steps, candidates := payment(1)
+	req, violation := gate.Evaluate(user, steps, candidates)
+	assert.Nil(t, violation)
+	require.NotNil(t, req)
+	assert.Equal(t, []string{approverA}, req.Approvers)
+	assert.Equal(t, int64(24*60*60), req.State(time.Unix(0, 0)).ExpiresAt, "expiry defaults to 24h")
+}
+
+func TestGate_TokenThresholds(t *testing.T) {
+	const other = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+	gate, err := approval.NewGate(approval.Rules{
+		TokenThresholds: map[string]string{token: "1000", approval.DefaultToken: "50"},
+		Approvers: map[string]approval.ApproverSet{
+			user: {Required: 1, Addresses: []string{approverA}},
+		},
+	})
+	require.NoError(t, err)
+
+	transfer := func(token string, amount int64) *simulator.TxCandidate {
+		candidate, err := simulator.ParseIntent(types.Intent{Action: "erc20_transfer", Params: map[string]string{
+			"token": token, "recipient": approverB, "amount": big.NewInt(amount).String(),
+		}})
+		require.NoError(t, err)
+		return candidate
+	}
+	steps := []types.IntentStep{{Action: "erc20_transfer"}, {Action: "erc20_transfer"}}
+
+	t.Run("Below Threshold", func(t *testing.T) {
+		req, violation := gate.Evaluate(user, steps, []*simulator.TxCandidate{transfer(token, 400), transfer(token, 600)})
+		assert.Nil(t, req)
+		assert.Nil(t, violation)
+	})
+
+	t.Run("Summed Per Token", func(t *testing.T) {
+		req, violation := gate.Evaluate(user, steps, []*simulator.TxCandidate{transfer(token, 600), transfer(token, 600)})
+		assert.Nil(t, violation)
+		require.NotNil(t, req)
+		assert.Contains(t, req.Reason, "amount 1200 exceeds 1000")
+	})
+
+	t.Run("Default Threshold For Other Tokens", func(t *testing.T) {
+		req, _ := gate.Evaluate(user, steps[:1], []*simulator.TxCandidate{transfer(other, 51)})
+		require.NotNil(t, req)
+		assert.Contains(t, req.Reason, other)
+	})
+
+	t.Run("contract_call Transfer Counted", func(t *testing.T) {
+		call, err := simulator.ParseIntent(types.Intent{Action: "contract_call", Params: map[string]string{
+			"contract": token, "function": "transfer(address,uint256)", "args": `["` + approverB + `", "5000"]`,
+		}})
+		require.NoError(t, err)
+		req, _ := gate.Evaluate(user, []types.IntentStep{{Action: "contract_call"}}, []*simulator.TxCandidate{call})
+		require.NotNil(t, req)
+		assert.Contains(t, req.Reason, "amount 5000 exceeds 1000")
+	})
+}
+
+func TestNewGate_Invalid(t *testing.T) {
+	tests := map[string]approval.Rules{
+		"quorum too large": {Approvers: map[string]approval.ApproverSet{user: {Required: 2, Addresses: []string{approverA}}}},
+		"zero quorum":      {Approvers: map[string]approval.ApproverSet{user: {Required: 0, Addresses: []string{approverA}}}},
+		"duplicate":        {Approvers: map[string]approval.ApproverSet{user: {Required: 2, Addresses: []string{approverA, approverA}}}},
+		"bad approver":     {Approvers: map[string]approval.ApproverSet{user: {Required: 1, Addresses: []string{"alice"}}}},
+		"bad user":         {Approvers: map[string]approval.ApproverSet{"bob": {Required: 1, Addresses: []string{approverA}}}},
+		"bad value":        {ValueThreshold: "-1"},
+		"bad risk":         {RiskThreshold: 101},
+		"bad expiry":       {Expiry: "soon"},
+		"bad token":        {TokenThresholds: map[string]string{"usdc": "1"}},
+		"bad token amount": {TokenThresholds: map[string]string{approverA: "-1"}},
+	}
+	for name, rules := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := approval.NewGate(rules)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package audit
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"trustflow/src/internal/chain"
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	maxAnchorLeaves   = 4096             // Audit events per anchor; a backlog takes several
+	anchorStaleAfter  = 30 * time.Minute // An anchor still unmined after this is sped up
+	maxAnchorSpeedups = 3                // Speed-ups per anchor; after that it waits to be mined
+)
+
+// anchorSelector is the 4-byte selector of anchor(bytes32 root, uint256 fromSeq, uint256 toSeq)
+var anchorSelector = crypto.Keccak256([]byte("anchor(bytes32,uint256,uint256)"))[:4]
+
+// Chain sends anchoring transactions; *chain.ChainClient satisfies it
+type Chain interface {
+	GetAddress() common.Address
+	EstimateGas(ctx context.Context, callMsg ethereum.CallMsg) (uint64, error)
+	SendJournaledTransaction(ctx context.Context, to *common.Address, value *big.Int, data []byte, gasLimit uint64, journal func(*chain.SentTx) error) (*chain.SentTx, error)
+	WaitForReceipt(ctx context.Context, txHash string) (*ethtypes.Receipt, error)
+	FindReceipt(ctx context.Context, hashes []string) (*ethtypes.Receipt, error)
+	ReplaceTransaction(ctx context.Context, nonce uint64, to *common.Address, value *big.Int, data []byte, gasLimit uint64, previous *chain.FeeParams) (*chain.SentTx, error)
+}
+
+// Anchorer periodically publishes a Merkle root over the audit events not yet anchored, so
+// the log can be checked against the chain rather than only against itself
+type Anchorer struct {
+	store    *storage.Storage
+	chain    Chain
+	contract *common.Address // Anchoring contract; nil sends the calldata to the server wallet itself
+}
+
+func NewAnchorer(store *storage.Storage, chain Chain, contract *common.Address) *Anchorer {
+	return &Anchorer{store: store, chain: chain, contract: contract}
+}
+
+// AnchorCalldata is the transaction data publishing root for audit events (fromSeq, toSeq]:
+// a call to anchor(bytes32,uint256,uint256)
+func AnchorCalldata(root []byte, fromSeq, toSeq int64) []byte {
+	data := append([]byte{}, anchorSelector...)
+	data = append(data, common.LeftPadBytes(root, 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(fromSeq).Bytes(), 32)...)
+	return append(data, common.LeftPadBytes(big.NewInt(toSeq).Bytes(), 32)...)
+}
+
+// Start anchors new audit events every interval until ctx is cancelled
+func (a *Anchorer) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			a.Settle(ctx)
+			for {
+				anchor, err := a.Anchor(ctx)
+				if err != nil {
+					log.Printf("❌ Audit anchoring failed: %v", err)
+				}
+				if err != nil || anchor == nil || anchor.ToSeq-anchor.FromSeq < maxAnchorLeaves || ctx.Err() != nil {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Anchor publishes the root over the next batch of audit events not yet anchored and waits
+// for it to be mined. It returns nil if there is nothing new to anchor.
+func (a *Anchorer) Anchor(ctx context.Context) (*types.AuditAnchor, error) {
+	from, err := a.store.AnchoredThrough()
+	if err != nil {
+		return nil, err
+	}
+	events, err := a.store.ListAuditEvents(from, maxAnchorLeaves)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	if err := a.checkChain(from, events); err != nil {
+		return nil, err
+	}
+	leaves, err := eventLeaves(events)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor := &types.AuditAnchor{
+		FromSeq: from,
+		ToSeq:   events[len(events)-1].Seq,
+		Root:    hexutil.Encode(MerkleRoot(leaves)),
+	}
+	data := AnchorCalldata(MerkleRoot(leaves), anchor.FromSeq, anchor.ToSeq)
+	to := a.chain.GetAddress()
+	if a.contract != nil {
+		to = *a.contract
+	}
+	gas, err := a.chain.EstimateGas(ctx, ethereum.CallMsg{From: a.chain.GetAddress(), To: &to, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate anchoring gas: %w", err)
+	}
+
+	// Journal the anchor before broadcasting, so a crash never leaves an anchor unrecorded
+	gasLimit := gas * 13 / 10
+	sent, err := a.chain.SendJournaledTransaction(ctx, &to, big.NewInt(0), data, gasLimit, func(sent *chain.SentTx) error {
+		anchor.TxHash = sent.Hash
+		anchor.CreatedAt = time.Now().Unix()
+		return a.store.SaveAnchor(anchor, storage.AnchorTx{Nonce: sent.Nonce, To: to.Hex(), GasLimit: gasLimit, Fees: sent.Fees.Details()})
+	})
+	if errors.Is(err, chain.ErrBroadcastUncertain) {
+		// It may still be mined: left submitted for the next round to settle
+		log.Printf("⚠️ Audit anchor %d broadcast unanswered: %v", anchor.ID, err)
+		return anchor, nil
+	}
+	if err != nil {
+		if anchor.ID != 0 {
+			a.fail(anchor, err.Error())
+		}
+		return nil, err
+	}
+	log.Printf("⚓ Anchoring audit events %d-%d (root %s) in %s", anchor.FromSeq+1, anchor.ToSeq, anchor.Root, sent.Hash)
+
+	receipt, err := a.chain.WaitForReceipt(ctx, sent.Hash)
+	if err != nil {
+		// Left submitted: a later round picks up the receipt, or speeds it up
+		log.Printf("⚠️ Audit anchor %d not confirmed yet: %v", anchor.ID, err)
+		return anchor, nil
+	}
+	a.record(anchor, receipt)
+	return anchor, nil
+}
+
+// checkChain refuses to publish a root over events that do not link up from the last anchored
+// one: an anchor over a tampered log would vouch for it
+func (a *Anchorer) checkChain(from int64, events []types.AuditEvent) error {
+	head := &types.AuditReport{HeadSeq: from, HeadHash: storage.AuditGenesisHash}
+	if from > 0 {
+		previous, err := a.store.ListAuditEvents(from-1, 1)
+		if err != nil {
+			return err
+		}
+		if len(previous) == 0 || previous[0].Seq != from {
+			return fmt.Errorf("audit chain broken: anchored event %d is missing", from)
+		}
+		head.HeadHash = previous[0].Hash
+	}
+	for _, event := range events {
+		if reason := checkLink(head, event); reason != "" {
+			return fmt.Errorf("audit chain broken at event %d: %s", event.Seq, reason)
+		}
+		head.HeadSeq, head.HeadHash = event.Seq, event.Hash
+	}
+	return nil
+}
+
+// Settle resolves anchors left submitted by an earlier round or a restart, and speeds up those
+// left unmined. Failing a stuck anchor instead would strand its nonce: every later anchor would
+// queue behind it, and it could still be mined after its events were anchored again.
+func (a *Anchorer) Settle(ctx context.Context) {
+	pending, err := a.store.ListPendingAnchors()
+	if err != nil {
+		log.Printf("❌ Failed to list audit anchors: %v", err)
+		return
+	}
+	for i := range pending {
+		anchor := &pending[i]
+		receipt, err := a.chain.FindReceipt(ctx, anchor.Hashes())
+		switch {
+		case err != nil:
+			log.Printf("❌ Failed to check audit anchor %d: %v", anchor.ID, err)
+		case receipt != nil:
+			a.record(&anchor.AuditAnchor, receipt)
+		case time.Since(time.Unix(anchor.BroadcastAt, 0)) <= anchorStaleAfter:
+			// Not stuck yet
+		case anchor.Tx == nil:
+			// Recorded before its transaction was kept, so it cannot be replaced
+			a.fail(&anchor.AuditAnchor, "not mined")
+		case len(anchor.Replacements) >= maxAnchorSpeedups:
+			log.Printf("🐢 Audit anchor %d still unmined after %d speed-ups", anchor.ID, len(anchor.Replacements))
+		default:
+			if err := a.speedUp(ctx, anchor); err != nil {
+				log.Printf("❌ Speed-up of audit anchor %d failed: %v", anchor.ID, err)
+			}
+		}
+	}
+}
+
+// speedUp rebroadcasts a stuck anchor's transaction at its nonce with bumped fees
+func (a *Anchorer) speedUp(ctx context.Context, anchor *storage.PendingAnchor) error {
+	previous, err := chain.FeeParamsFromDetails(anchor.Tx.Fees)
+	if err != nil {
+		return err
+	}
+	root, err := hexutil.Decode(anchor.Root)
+	if err != nil {
+		return fmt.Errorf("corrupt anchor root: %w", err)
+	}
+	to := common.HexToAddress(anchor.Tx.To)
+	data := AnchorCalldata(root, anchor.FromSeq, anchor.ToSeq)
+	sent, err := a.chain.ReplaceTransaction(ctx, anchor.Tx.Nonce, &to, big.NewInt(0), data, anchor.Tx.GasLimit, previous)
+	if err != nil {
+		return err
+	}
+	log.Printf("🔁 Audit anchor %d sped up at nonce %d. Hash: %s", anchor.ID, sent.Nonce, sent.Hash)
+	return a.store.AddAnchorReplacement(anchor.ID, sent.Hash, sent.Fees.Details())
+}
+
+func (a *Anchorer) record(anchor *types.AuditAnchor, receipt *ethtypes.Receipt) {
+	anchor.TxHash = receipt.TxHash.Hex() // The original or a replacement
+	if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		a.fail(anchor, "reverted")
+		return
+	}
+	anchor.Status = storage.AnchorConfirmed
+	anchor.BlockNumber = receipt.BlockNumber.Uint64()
+	if err := a.store.UpdateAnchor(anchor.ID, anchor.Status, anchor.TxHash, anchor.BlockNumber, "", time.Now().Unix()); err != nil {
+		log.Printf("❌ Failed to record audit anchor %d: %v", anchor.ID, err)
+		return
+	}
+	log.Printf("⚓ Audit anchor %d confirmed in block %d", anchor.ID, anchor.BlockNumber)
+}
+
+// fail gives up on an anchor; its events, and any anchored after them, are anchored again
+// in the next round
+func (a *Anchorer) fail(anchor *types.AuditAnchor, reason string) {
+	anchor.Status = storage.AnchorFailed
+	anchor.Error = reason
+	if err := a.store.UpdateAnchor(anchor.ID, anchor.Status, anchor.TxHash, 0, reason, time.Now().Unix()); err != nil {
+		log.Printf("❌ Failed to record audit anchor %d: %v", anchor.ID, err)
+		return
+	}
+	log.Printf("⚠️ Audit anchor %d failed (%s); events from %d on will be anchored again", anchor.ID, reason, anchor.FromSeq+1)
+}
+
+// ErrIntentNotFound is returned for proofs of an intent the user does not own
+var ErrIntentNotFound = errors.New("intent not found")
+
+// Proof returns an inclusion proof for each of a user's intent's audit events in the anchor
+// covering it. Events not anchored yet are listed without one.
+func (l *Log) Proof(userID string, intentID string) (*types.IntentAuditProof, error) {
+	owner, _, found, err := l.store.FindIntentByID(intentID)
+	if err != nil {
+		return nil, err
+	}
+	if !found || owner != userID {
+		return nil, ErrIntentNotFound
+	}
+
+	events, err := l.store.ListIntentAuditEvents(intentID)
+	if err != nil {
+		return nil, err
+	}
+	result := &types.IntentAuditProof{IntentID: intentID, Proofs: []types.AuditProof{}}
+	leaves := map[int64][][]byte{} // Per anchor, as an intent's events usually share one
+	for _, event := range events {
+		proof := types.AuditProof{Event: event}
+		anchor, err := l.store.FindAnchor(event.Seq)
+		if err != nil {
+			return nil, err
+		}
+		if anchor != nil {
+			if leaves[anchor.ID] == nil {
+				anchored, err := l.store.ListAuditEvents(anchor.FromSeq, int(anchor.ToSeq-anchor.FromSeq))
+				if err != nil {
+					return nil, err
+				}
+				if leaves[anchor.ID], err = eventLeaves(anchored); err != nil {
+					return nil, err
+				}
+			}
+			proof.Anchor = anchor
+			proof.LeafIndex = int(event.Seq - anchor.FromSeq - 1)
+			proof.Path = MerkleProof(leaves[anchor.ID], proof.LeafIndex)
+		}
+		result.Proofs = append(result.Proofs, proof)
+	}
+	return result, nil
+}
+
+func eventLeaves(events []types.AuditEvent) ([][]byte, error) {
+	leaves := make([][]byte, len(events))
+	for i, event := range events {
+		hash, err := hex.DecodeString(event.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt hash of audit event %d: %w", event.Seq, err)
+		}
+		leaves[i] = LeafHash(hash)
+	}
+	return leaves, nil
+}
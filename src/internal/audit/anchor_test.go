@@ -0,0 +1,340 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"trustflow/src/internal/audit"
+	"trustflow/src/internal/chain"
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain records anchoring transactions and mines them with the configured receipt status
+type fakeChain struct {
+	sent     [][]byte
+	to       []common.Address
+	replaced []*chain.SentTx // Replacements, with the nonce and fees they were sent at
+	status   uint64
+	unmined  bool // Sent transactions stay in the mempool, so WaitForReceipt gives up
+	receipts map[string]*ethtypes.Receipt
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{status: ethtypes.ReceiptStatusSuccessful, receipts: map[string]*ethtypes.Receipt{}}
+}
+
+func (f *fakeChain) GetAddress() common.Address {
+	return common.HexToAddress(user)
+}
+
+func (f *fakeChain) EstimateGas(ctx context.Context, callMsg ethereum.CallMsg) (uint64, error) {
+	return 30000, nil
+}
+
+func (f *fakeChain) SendJournaledTransaction(ctx context.Context, to *common.Address, value *big.Int, data []byte, gasLimit uint64, journal func(*chain.SentTx) error) (*chain.SentTx, error) {
+	sent := &chain.SentTx{
+		Hash:  fmt.Sprintf("0x%064x", len(f.sent)+1),
+		Nonce: uint64(len(f.sent)),
+		Fees:  &chain.FeeParams{Type: chain.TxTypeDynamic, GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(250), BaseFee: big.NewInt(124)},
+	}
+	if err := journal(sent); err != nil {
+		return nil, err
+	}
+	f.sent = append(f.sent, data)
+	f.to = append(f.to, *to)
+	if !f.unmined {
+		f.mine(sent.Hash)
+	}
+	return sent, nil
+}
+
+func (f *fakeChain) ReplaceTransaction(ctx context.Context, nonce uint64, to *common.Address, value *big.Int, data []byte, gasLimit uint64, previous *chain.FeeParams) (*chain.SentTx, error) {
+	sent := &chain.SentTx{
+		Hash:  fmt.Sprintf("0x%064x", 1000+len(f.replaced)),
+		Nonce: nonce,
+		Fees:  chain.BumpFees(previous, previous),
+	}
+	f.replaced = append(f.replaced, sent)
+	f.sent = append(f.sent, data)
+	f.to = append(f.to, *to)
+	return sent, nil
+}
+
+// mine includes a sent transaction in the next block
+func (f *fakeChain) mine(hash string) {
+	f.receipts[hash] = &ethtypes.Receipt{TxHash: common.HexToHash(hash), Status: f.status, BlockNumber: big.NewInt(int64(100 + len(f.sent)))}
+}
+
+func (f *fakeChain) WaitForReceipt(ctx context.Context, txHash string) (*ethtypes.Receipt, error) {
+	if receipt := f.receipts[txHash]; receipt != nil {
+		return receipt, nil
+	}
+	return nil, errors.New("timed out")
+}
+
+func (f *fakeChain) FindReceipt(ctx context.Context, hashes []string) (*ethtypes.Receipt, error) {
+	for _, hash := range hashes {
+		if receipt := f.receipts[hash]; receipt != nil {
+			return receipt, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestMerkleProof(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		leaves := make([][]byte, size)
+		for i := range leaves {
+			leaves[i] = audit.LeafHash([]byte{byte(i)})
+		}
+		root := audit.MerkleRoot(leaves)
+		for i := range leaves {
+			path := audit.MerkleProof(leaves, i)
+			assert.True(t, audit.VerifyProof(leaves[i], path, root), "leaf %d of %d", i, size)
+			assert.False(t, audit.VerifyProof(audit.LeafHash([]byte{0xff}), path, root), "forged leaf %d of %d", i, size)
+		}
+	}
+	assert.Nil(t, audit.MerkleRoot(nil))
+
+	// A single leaf is its own root; leaves and nodes never collide
+	leaf := audit.LeafHash([]byte("event"))
+	assert.Equal(t, leaf, audit.MerkleRoot([][]byte{leaf}))
+	assert.NotEqual(t, audit.LeafHash(append(leaf, leaf...)), audit.MerkleRoot([][]byte{leaf, leaf}))
+}
+
+func TestAnchor(t *testing.T) {
+	store, _ := newAuditedStore(t)
+	fake := newFakeChain()
+	anchorer := audit.NewAnchorer(store, fake, nil)
+
+	anchor, err := anchorer.Anchor(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, anchor)
+	assert.Equal(t, int64(0), anchor.FromSeq)
+	assert.Equal(t, int64(5), anchor.ToSeq)
+	assert.Equal(t, storage.AnchorConfirmed, anchor.Status)
+	assert.Equal(t, uint64(101), anchor.BlockNumber)
+
+	// Without a contract the root goes to the server wallet itself, as anchor(root, from, to) calldata
+	root, err := hexutil.Decode(anchor.Root)
+	require.NoError(t, err)
+	require.Len(t, fake.sent, 1)
+	assert.Equal(t, fake.GetAddress(), fake.to[0])
+	assert.Equal(t, audit.AnchorCalldata(root, 0, 5), fake.sent[0])
+	assert.Equal(t, root, fake.sent[0][4:36])
+
+	// Nothing new to anchor
+	anchor, err = anchorer.Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, anchor)
+
+	// The next anchor covers only the events recorded since
+	contract := common.HexToAddress("0x00000000000000000000000000000000000a0c40")
+	require.NoError(t, store.UpdateIntentStatus("intent-1", user, "failed", "late"))
+	anchor, err = audit.NewAnchorer(store, fake, &contract).Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), anchor.FromSeq)
+	assert.Equal(t, int64(6), anchor.ToSeq)
+	assert.Equal(t, contract, fake.to[1])
+}
+
+func TestAnchor_Reverted(t *testing.T) {
+	store, _ := newAuditedStore(t)
+	fake := newFakeChain()
+	fake.status = ethtypes.ReceiptStatusFailed
+
+	anchor, err := audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, storage.AnchorFailed, anchor.Status)
+
+	// A failed anchor's events are anchored again
+	fake.status = ethtypes.ReceiptStatusSuccessful
+	anchor, err = audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), anchor.FromSeq)
+	assert.Equal(t, storage.AnchorConfirmed, anchor.Status)
+}
+
+func TestAnchor_Unconfirmed(t *testing.T) {
+	store, _ := newAuditedStore(t)
+	fake := newFakeChain()
+	fake.unmined = true
+
+	anchor, err := audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, storage.AnchorSubmitted, anchor.Status)
+
+	// A submitted anchor still covers its events: the next round does not send them again
+	anchor, err = audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, anchor)
+	pending, err := store.ListPendingAnchors()
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
+
+func TestAnchor_Stuck(t *testing.T) {
+	store, path := newAuditedStore(t)
+	fake := newFakeChain()
+	fake.unmined = true
+	anchorer := audit.NewAnchorer(store, fake, nil)
+
+	anchor, err := anchorer.Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, storage.AnchorSubmitted, anchor.Status)
+
+	// Not stale yet: left alone
+	anchorer.Settle(context.Background())
+	assert.Empty(t, fake.replaced)
+
+	// Stale: sped up at the same nonce with bumped fees, instead of failed and sent again
+	stale := func() {
+		t.Helper()
+		tamper(t, path, "UPDATE audit_anchors SET broadcast_at = 0")
+		anchorer.Settle(context.Background())
+	}
+	stale()
+	require.Len(t, fake.replaced, 1)
+	assert.Equal(t, uint64(0), fake.replaced[0].Nonce)
+	assert.Equal(t, big.NewInt(3), fake.replaced[0].Fees.GasTipCap)
+	assert.Equal(t, fake.sent[0], fake.sent[1], "the replacement publishes the same root")
+	pending, err := store.ListPendingAnchors()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, []string{anchor.TxHash, fake.replaced[0].Hash}, pending[0].Hashes())
+
+	// Each speed-up builds on the last, up to the limit
+	anchorer.Settle(context.Background())
+	assert.Len(t, fake.replaced, 1, "a fresh replacement is not stale")
+	for range 3 {
+		stale()
+	}
+	require.Len(t, fake.replaced, 3)
+	assert.Equal(t, big.NewInt(4), fake.replaced[1].Fees.GasTipCap)
+
+	// Whichever transaction is mined confirms the anchor
+	fake.mine(fake.replaced[1].Hash)
+	anchorer.Settle(context.Background())
+	confirmed, err := store.FindAnchor(1)
+	require.NoError(t, err)
+	require.NotNil(t, confirmed)
+	assert.Equal(t, storage.AnchorConfirmed, confirmed.Status)
+	assert.Equal(t, fake.replaced[1].Hash, confirmed.TxHash)
+	pending, err = store.ListPendingAnchors()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestAnchor_BrokenChain(t *testing.T) {
+	t.Run("Edited", func(t *testing.T) {
+		store, path := newAuditedStore(t)
+		tamper(t, path, "DROP TRIGGER audit_events_no_update",
+			"UPDATE audit_events SET payload = replace(payload, 'success', 'failed') WHERE seq = 4")
+		fake := newFakeChain()
+
+		_, err := audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+		assert.ErrorContains(t, err, "audit chain broken at event 4")
+		assert.Empty(t, fake.sent, "no root is published over a tampered log")
+	})
+
+	t.Run("Anchored Event Deleted", func(t *testing.T) {
+		store, path := newAuditedStore(t)
+		fake := newFakeChain()
+		_, err := audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, store.UpdateIntentStatus("intent-1", user, "failed", "late"))
+		tamper(t, path, "DROP TRIGGER audit_events_no_delete", "DELETE FROM audit_events WHERE seq = 5")
+
+		_, err = audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+		assert.ErrorContains(t, err, "anchored event 5 is missing")
+		assert.Len(t, fake.sent, 1)
+	})
+}
+
+func TestAnchor_FailedBeforeLater(t *testing.T) {
+	store, _ := newAuditedStore(t)
+	fake := newFakeChain()
+	fake.unmined = true
+	first, err := audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, storage.AnchorSubmitted, first.Status)
+
+	// A later anchor is confirmed while the first is still unmined
+	fake.unmined = false
+	require.NoError(t, store.UpdateIntentStatus("intent-1", user, "failed", "late"))
+	later, err := audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), later.FromSeq)
+	assert.Equal(t, storage.AnchorConfirmed, later.Status)
+
+	// The first then fails: its events are not left out, they are anchored again along with the rest
+	require.NoError(t, store.UpdateAnchor(first.ID, storage.AnchorFailed, first.TxHash, 0, "reverted", 0))
+	anchor, err := audit.NewAnchorer(store, fake, nil).Anchor(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, anchor)
+	assert.Equal(t, int64(0), anchor.FromSeq)
+	assert.Equal(t, int64(6), anchor.ToSeq)
+
+	through, err := store.AnchoredThrough()
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), through)
+	proof, err := audit.NewLog(store).Proof(user, "intent-1")
+	require.NoError(t, err)
+	for _, p := range proof.Proofs {
+		require.NotNil(t, p.Anchor, "event %d", p.Event.Seq)
+	}
+}
+
+func TestProof(t *testing.T) {
+	store, _ := newAuditedStore(t)
+	_, err := store.CreateIntent(types.Intent{ID: "intent-2", Steps: []types.IntentStep{{Action: "payment"}}}, user, "", "hash-2")
+	require.NoError(t, err)
+	log := audit.NewLog(store)
+
+	// Before anchoring, events are listed without proofs
+	proof, err := log.Proof(user, "intent-1")
+	require.NoError(t, err)
+	require.Len(t, proof.Proofs, 5)
+	assert.Nil(t, proof.Proofs[0].Anchor)
+
+	anchor, err := audit.NewAnchorer(store, newFakeChain(), nil).Anchor(context.Background())
+	require.NoError(t, err)
+	root, err := hexutil.Decode(anchor.Root)
+	require.NoError(t, err)
+
+	proof, err = log.Proof(user, "intent-1")
+	require.NoError(t, err)
+	assert.Equal(t, "intent-1", proof.IntentID)
+	require.Len(t, proof.Proofs, 5)
+	for i, p := range proof.Proofs {
+		require.NotNil(t, p.Anchor)
+		assert.Equal(t, anchor.ID, p.Anchor.ID)
+		assert.Equal(t, i, p.LeafIndex)
+		hash, err := hex.DecodeString(p.Event.Hash)
+		require.NoError(t, err)
+		assert.True(t, audit.VerifyProof(audit.LeafHash(hash), p.Path, root), "event %d", p.Event.Seq)
+	}
+
+	// The other intent's single event is the sixth and last leaf
+	proof, err = log.Proof(user, "intent-2")
+	require.NoError(t, err)
+	require.Len(t, proof.Proofs, 1)
+	assert.Equal(t, 5, proof.Proofs[0].LeafIndex)
+
+	// Another user's intent is not found
+	_, err = log.Proof("0x0000000000000000000000000000000000000001", "intent-1")
+	assert.ErrorIs(t, err, audit.ErrIntentNotFound)
+	_, err = log.Proof(user, "missing")
+	assert.ErrorIs(t, err, audit.ErrIntentNotFound)
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"fmt"
+
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+)
+
+// pageSize is how many audit events are read at a time while walking the chain
+const pageSize = 1000
+
+// Log checks the hash-chained audit log the store appends to on every status transition
+type Log struct {
+	store *storage.Storage
+}
+
+func NewLog(store *storage.Storage) *Log {
+	return &Log{store: store}
+}
+
+// Verify re-walks the audit chain from its first event, recomputing every hash, and reports
+// the first event that is missing, reordered, edited or does not link to its predecessor.
+// Valid reports cover the whole chain up to HeadHash.
+func (l *Log) Verify() (*types.AuditReport, error) {
+	report := &types.AuditReport{Valid: true, HeadHash: storage.AuditGenesisHash}
+	for {
+		events, err := l.store.ListAuditEvents(report.HeadSeq, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if reason := checkLink(report, event); reason != "" {
+				report.Valid = false
+				report.BrokenSeq = event.Seq
+				report.Reason = reason
+				return report, nil
+			}
+			report.Checked++
+			report.HeadSeq = event.Seq
+			report.HeadHash = event.Hash
+		}
+		if len(events) < pageSize {
+			return report, nil
+		}
+	}
+}
+
+// checkLink explains why event does not follow the intact chain ending at report's head,
+// or returns "" if it does
+func checkLink(report *types.AuditReport, event types.AuditEvent) string {
+	switch {
+	case event.Seq != report.HeadSeq+1:
+		return fmt.Sprintf("expected event %d after %d, found %d", report.HeadSeq+1, report.HeadSeq, event.Seq)
+	case event.PrevHash != report.HeadHash:
+		return fmt.Sprintf("prev_hash %s does not match the hash of event %d", event.PrevHash, report.HeadSeq)
+	case event.Hash != storage.AuditHash(event.PrevHash, event.Seq, event.Payload):
+		return "hash does not match the event's contents"
+	}
+	return ""
+}
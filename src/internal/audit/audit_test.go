@@ -0,0 +1,132 @@
+package audit_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"trustflow/src/internal/audit"
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const user = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+
+// newAuditedStore records a short intent lifecycle and returns the store with its database path
+func newAuditedStore(t *testing.T) (*storage.Storage, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trustflow.db")
+	store, err := storage.NewStorage(path)
+	require.NoError(t, err)
+
+	intent := types.Intent{ID: "intent-1", Signature: "0xsig", Steps: []types.IntentStep{
+		{Action: "payment", Params: map[string]string{"amount": "1"}},
+	}}
+	_, err = store.CreateIntent(intent, user, "", "hash-1")
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateIntentStatus("intent-1", user, "processing", ""))
+	require.NoError(t, store.UpdateStepStatus("intent-1", user, 0, "submitted", "0xabc", ""))
+	require.NoError(t, store.UpdateStepStatus("intent-1", user, 0, "success", "0xabc", ""))
+	require.NoError(t, store.UpdateIntentStatus("intent-1", user, "success", "done"))
+	return store, path
+}
+
+// tamper edits the database behind the store's back, as an attacker with file access could
+func tamper(t *testing.T, path string, statements ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, statement := range statements {
+		_, err := db.Exec(statement)
+		require.NoError(t, err, statement)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	store, _ := newAuditedStore(t)
+	report, err := audit.NewLog(store).Verify()
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, int64(5), report.Checked)
+	assert.Equal(t, int64(5), report.HeadSeq)
+
+	events, err := store.ListAuditEvents(0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, storage.AuditGenesisHash, events[0].PrevHash)
+	assert.Equal(t, report.HeadHash, events[4].Hash)
+
+	// Queueing the intent fixes its content and signature in the chain
+	var created map[string]any
+	require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &created))
+	assert.Equal(t, "pending", created["status"])
+	assert.Equal(t, "0xsig", created["signature"])
+	assert.Len(t, created["intent_hash"], 64)
+}
+
+func TestVerify_Empty(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	report, err := audit.NewLog(store).Verify()
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Zero(t, report.Checked)
+	assert.Equal(t, storage.AuditGenesisHash, report.HeadHash)
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	t.Run("Append Only", func(t *testing.T) {
+		_, path := newAuditedStore(t)
+		db, err := sql.Open("sqlite", path)
+		require.NoError(t, err)
+		defer db.Close()
+		_, err = db.Exec("UPDATE audit_events SET payload = '{}' WHERE seq = 2")
+		assert.ErrorContains(t, err, "append-only")
+		_, err = db.Exec("DELETE FROM audit_events WHERE seq = 2")
+		assert.ErrorContains(t, err, "append-only")
+	})
+
+	for name, tc := range map[string]struct {
+		statement string
+		brokenSeq int64
+		checked   int64
+		reason    string
+	}{
+		"Edited":    {"UPDATE audit_events SET payload = replace(payload, 'success', 'failed') WHERE seq = 4", 4, 3, "hash does not match"},
+		"Deleted":   {"DELETE FROM audit_events WHERE seq = 2", 3, 1, "expected event 2"},
+		"Truncated": {"DELETE FROM audit_events WHERE seq = 1", 2, 0, "expected event 1"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store, path := newAuditedStore(t)
+			tamper(t, path, "DROP TRIGGER audit_events_no_update", "DROP TRIGGER audit_events_no_delete", tc.statement)
+
+			report, err := audit.NewLog(store).Verify()
+			require.NoError(t, err)
+			assert.False(t, report.Valid)
+			assert.Equal(t, tc.brokenSeq, report.BrokenSeq)
+			assert.Equal(t, tc.checked, report.Checked)
+			assert.Contains(t, report.Reason, tc.reason)
+		})
+	}
+
+	t.Run("Rehashed", func(t *testing.T) {
+		// Recomputing an edited event's own hash still breaks the next event's link
+		store, path := newAuditedStore(t)
+		events, err := store.ListAuditEvents(2, 1)
+		require.NoError(t, err)
+		payload := strings.Replace(events[0].Payload, "0xabc", "0xdef", 1)
+		tamper(t, path, "DROP TRIGGER audit_events_no_update", fmt.Sprintf("UPDATE audit_events SET payload = '%s', hash = '%s' WHERE seq = 3",
+			payload, storage.AuditHash(events[0].PrevHash, 3, payload)))
+
+		report, err := audit.NewLog(store).Verify()
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.Equal(t, int64(4), report.BrokenSeq)
+		assert.Contains(t, report.Reason, "prev_hash")
+	})
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Merkle trees are built over audit event hashes. Leaves and interior nodes are hashed with
+// distinct prefixes (as in RFC 6962) so a leaf can never pass for a node, and the odd node
+// out of a level moves up unchanged rather than being paired with itself.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash is the Merkle leaf for an audit event's hash
+func LeafHash(eventHash []byte) []byte {
+	sum := sha256.Sum256(append([]byte{leafPrefix}, eventHash...))
+	return sum[:]
+}
+
+func nodeHash(left, right []byte) []byte {
+	sum := sha256.Sum256(append(append([]byte{nodePrefix}, left...), right...))
+	return sum[:]
+}
+
+// MerkleRoot returns the root of the tree over leaves; nil for no leaves
+func MerkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return nil
+	}
+	level := leaves
+	for len(level) > 1 {
+		level = nextLevel(level)
+	}
+	return level[0]
+}
+
+// MerkleProof returns the siblings on the path from leaves[index] up to the root
+func MerkleProof(leaves [][]byte, index int) []types.ProofNode {
+	var path []types.ProofNode
+	level := leaves
+	for len(level) > 1 {
+		switch {
+		case index%2 == 1:
+			path = append(path, types.ProofNode{Hash: hexutil.Encode(level[index-1]), Position: "left"})
+		case index+1 < len(level):
+			path = append(path, types.ProofNode{Hash: hexutil.Encode(level[index+1]), Position: "right"})
+		}
+		level = nextLevel(level)
+		index /= 2
+	}
+	return path
+}
+
+// VerifyProof reports whether leaf, hashed with each sibling of the path in turn, gives root
+func VerifyProof(leaf []byte, path []types.ProofNode, root []byte) bool {
+	hash := leaf
+	for _, node := range path {
+		sibling, err := hexutil.Decode(node.Hash)
+		if err != nil {
+			return false
+		}
+		if node.Position == "left" {
+			hash = nodeHash(sibling, hash)
+		} else {
+			hash = nodeHash(hash, sibling)
+		}
+	}
+	return bytes.Equal(hash, root)
+}
+
+func nextLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+		} else {
+			next = append(next, nodeHash(level[i], level[i+1]))
+		}
+	}
+	return next
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+// API key scopes, each granting a group of endpoints
+const (
+	ScopeIntentSubmit  = "intent:submit"  // Submit intents and speed up or cancel their steps
+	ScopeIntentRead    = "intent:read"    // Read intents, their events, the budget, the agent wallet and the audit log
+	ScopeIntentApprove = "intent:approve" // Approve or reject intents awaiting approval
+	ScopeSimulate      = "simulate"       // Dry-run intents
+	ScopeWebhooks      = "webhooks"       // Manage webhooks
+)
+
+// Scopes lists every API key scope
+var Scopes = []string{ScopeIntentSubmit, ScopeIntentRead, ScopeIntentApprove, ScopeSimulate, ScopeWebhooks}
+
+// keyPrefixLen is how much of a key is kept in the clear to tell keys apart
+const keyPrefixLen = 12
+
+var (
+	// ErrInvalidAPIKey is returned when a key's address, scopes or lifetime are not acceptable
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyNotFound is returned when there is no live API key with the given ID
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrUnknownAPIKey is returned when authenticating with an unknown, expired or revoked API key
+	ErrUnknownAPIKey = errors.New("invalid, expired or revoked API key")
+)
+
+// CreateAPIKey issues a key acting as address with the given scopes, expiring after ttl
+// (zero never expires). The key itself is returned only here; just its hash is stored.
+func (a *Authenticator) CreateAPIKey(admin string, address string, name string, scopes []string, ttl time.Duration) (*types.APIKey, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("%w: address must be a hex address", ErrInvalidAPIKey)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKey)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKey, scope)
+		}
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("%w: expiry must not be negative", ErrInvalidAPIKey)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	now := time.Now()
+	key := types.APIKey{
+		ID:        uuid.New().String(),
+		Key:       "tfk_" + hex.EncodeToString(raw),
+		Name:      name,
+		Address:   common.HexToAddress(address).Hex(), // Checksummed, like session addresses
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedBy: admin,
+		CreatedAt: now.Unix(),
+	}
+	key.Prefix = key.Key[:keyPrefixLen]
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl).Unix()
+	}
+	if err := a.store.SaveAPIKey(key, hashToken(key.Key)); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns the keys bound to address, or every key if address is empty
+func (a *Authenticator) ListAPIKeys(address string) ([]types.APIKey, error) {
+	if address != "" {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("%w: address must be a hex address", ErrInvalidAPIKey)
+		}
+		address = common.HexToAddress(address).Hex()
+	}
+	return a.store.ListAPIKeys(address)
+}
+
+// RevokeAPIKey revokes a key; requests using it are rejected from then on
+func (a *Authenticator) RevokeAPIKey(id string) error {
+	revoked, err := a.store.RevokeAPIKey(id, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// authenticateKey returns the live API key for a "tfk_" token
+func (a *Authenticator) authenticateKey(token string) (*types.APIKey, error) {
+	key, err := a.store.UseAPIKey(hashToken(token), time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrUnknownAPIKey
+	}
+	return key, nil
+}
+
+// IsAdmin reports whether address may manage API keys
+func (a *Authenticator) IsAdmin(address string) bool {
+	return common.IsHexAddress(address) && slices.Contains(a.admins, common.HexToAddress(address).Hex())
+}
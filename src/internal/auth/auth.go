@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+)
+
+const (
+	nonceTTL   = 10 * time.Minute // How long a nonce can wait to be signed
+	nonceBytes = 16               // Hex-encoded: EIP-4361 requires at least 8 alphanumeric characters
+	clockSkew  = time.Minute      // Tolerance for the wallet's clock in Issued At / Not Before
+
+	noncesPerClient = 10          // Nonces a client may request per nonceWindow
+	nonceWindow     = time.Minute // Window of the per-client nonce limit
+	maxNonces       = 10000       // Unused nonces outstanding across all clients
+)
+
+var (
+	// ErrInvalidSignature is returned when a sign-in message was not signed by its address
+	ErrInvalidSignature = errors.New("signature does not match the message address")
+	// ErrInvalidNonce is returned when a sign-in message's nonce was never issued, expired or was already used
+	ErrInvalidNonce = errors.New("nonce is unknown, expired or already used")
+	// ErrInvalidSession is returned for a missing, unknown or expired session token
+	ErrInvalidSession = errors.New("invalid or expired session")
+	// ErrTooManyNonces is returned when a client, or everyone together, requests nonces too fast
+	ErrTooManyNonces = errors.New("too many sign-in nonces requested, try again later")
+	// ErrSignInDisabled is returned when no sign-in domain is configured (header auth development setups)
+	ErrSignInDisabled = errors.New("sign-in is not configured: set AUTH_DOMAIN")
+)
+
+// Authenticator runs the Sign-In with Ethereum (EIP-4361) flow and validates the session
+// tokens and API keys it issues. Only hashes of tokens and keys are stored.
+type Authenticator struct {
+	store      *storage.Storage
+	domain     string // Message domain; empty disables sign-in
+	chainID    int64
+	sessionTTL time.Duration
+	admins     []string // Checksummed addresses allowed to manage API keys
+	nonces     *limiter // Nonce requests per client
+}
+
+func NewAuthenticator(store *storage.Storage, domain string, chainID *big.Int, sessionTTL time.Duration, admins []string) *Authenticator {
+	return &Authenticator{
+		store:      store,
+		domain:     domain,
+		chainID:    chainID.Int64(),
+		sessionTTL: sessionTTL,
+		admins:     admins,
+		nonces:     newLimiter(noncesPerClient, nonceWindow),
+	}
+}
+
+// NewNonce issues a single-use nonce for a sign-in message. client identifies the caller
+// (its IP) for the per-client rate limit.
+func (a *Authenticator) NewNonce(client string) (*types.AuthNonce, error) {
+	if a.domain == "" {
+		return nil, ErrSignInDisabled
+	}
+	now := time.Now()
+	if !a.nonces.allow(client, now) {
+		return nil, ErrTooManyNonces
+	}
+
+	raw := make([]byte, nonceBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := &types.AuthNonce{
+		Nonce:      hex.EncodeToString(raw),
+		Domain:     a.domain,
+		ChainID:    a.chainID,
+		IntentSalt: IntentSalt(a.domain).Hex(),
+		ExpiresAt:  now.Add(nonceTTL).Unix(),
+	}
+	saved, err := a.store.SaveAuthNonce(nonce.Nonce, now.Unix(), nonce.ExpiresAt, maxNonces)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrTooManyNonces
+	}
+	return nonce, nil
+}
+
+// Verify checks a signed sign-in message against the server's domain and chain, the
+// nonce it issued and the signer, and opens a session for the message's address
+func (a *Authenticator) Verify(rawMessage string, signature string) (*types.Session, error) {
+	if a.domain == "" {
+		return nil, ErrSignInDisabled
+	}
+	msg, err := ParseMessage(rawMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case msg.Version != "1":
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidMessage, msg.Version)
+	case !strings.EqualFold(msg.Domain, a.domain):
+		return nil, fmt.Errorf("%w: domain %q does not match %q", ErrInvalidMessage, msg.Domain, a.domain)
+	case msg.ChainID != a.chainID:
+		return nil, fmt.Errorf("%w: chain ID %d does not match %d", ErrInvalidMessage, msg.ChainID, a.chainID)
+	case msg.IssuedAt.After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidMessage)
+	case msg.NotBefore != nil && msg.NotBefore.After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidMessage)
+	case msg.ExpirationTime != nil && !msg.ExpirationTime.After(now):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidMessage)
+	}
+
+	// Check the signature before spending the nonce, so a forged message cannot burn it
+	signer, err := RecoverAddress(rawMessage, signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if signer != msg.Address {
+		return nil, ErrInvalidSignature
+	}
+
+	valid, err := a.store.ConsumeAuthNonce(msg.Nonce, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidNonce
+	}
+
+	// The session lasts sessionTTL, or until the message expires if that is sooner
+	expiresAt := now.Add(a.sessionTTL)
+	if msg.ExpirationTime != nil && msg.ExpirationTime.Before(expiresAt) {
+		expiresAt = *msg.ExpirationTime
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	session := &types.Session{
+		Token:     "tfs_" + hex.EncodeToString(token),
+		Address:   msg.Address.Hex(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+	if err := a.store.SaveSession(hashToken(session.Token), session.Address, now.Unix(), session.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Authenticate returns the address a session token was issued to
+func (a *Authenticator) Authenticate(token string) (string, error) {
+	if token == "" {
+		return "", ErrInvalidSession
+	}
+	address, found, err := a.store.GetSession(hashToken(token), time.Now().Unix())
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", ErrInvalidSession
+	}
+	return address, nil
+}
+
+// hashToken is how session tokens are stored: a leaked database does not leak live sessions
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
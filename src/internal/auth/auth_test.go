@@ -0,0 +1,337 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"trustflow/src/internal/auth"
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const domain = "trustflow.example.com"
+
+func newAuthenticator(t *testing.T, admins ...string) *auth.Authenticator {
+	t.Helper()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	return auth.NewAuthenticator(store, domain, big.NewInt(240), time.Hour, admins)
+}
+
+// signIn builds an EIP-4361 message as a wallet would present it and signs it with personal_sign
+func signIn(t *testing.T, nonce string, chainID int64, issuedAt time.Time) (string, string) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return signInAs(t, key, nonce, chainID, issuedAt)
+}
+
+func signInAs(t *testing.T, key *ecdsa.PrivateKey, nonce string, chainID int64, issuedAt time.Time) (string, string) {
+	t.Helper()
+	message := fmt.Sprintf(`%s wants you to sign in with your Ethereum account:
+%s
+
+Sign in to TrustFlow.
+
+URI: https://%s/login
+Version: 1
+Chain ID: %d
+Nonce: %s
+Issued At: %s
+Resources:
+- https://%s/intents`, domain, crypto.PubkeyToAddress(key.PublicKey).Hex(), domain, chainID, nonce, issuedAt.UTC().Format(time.RFC3339), domain)
+
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+	return message, hexutil.Encode(sig)
+}
+
+func TestParseMessage(t *testing.T) {
+	message, _ := signIn(t, "abc12345", 240, time.Unix(1700000000, 0))
+	msg, err := auth.ParseMessage(message)
+	require.NoError(t, err)
+	assert.Equal(t, domain, msg.Domain)
+	assert.Equal(t, "Sign in to TrustFlow.", msg.Statement)
+	assert.Equal(t, "1", msg.Version)
+	assert.Equal(t, int64(240), msg.ChainID)
+	assert.Equal(t, "abc12345", msg.Nonce)
+	assert.Equal(t, int64(1700000000), msg.IssuedAt.Unix())
+	assert.Equal(t, []string{"https://" + domain + "/intents"}, msg.Resources)
+	assert.Nil(t, msg.ExpirationTime)
+
+	for name, bad := range map[string]string{
+		"No Preamble":  "hello\n0x71C7656EC7ab88b098defB751B7401B5f6d8976F",
+		"Not Checksum": domain + " wants you to sign in with your Ethereum account:\n0x71c7656ec7ab88b098defb751b7401b5f6d8976f\n\nURI: x\nVersion: 1\nChain ID: 1\nNonce: abc12345\nIssued At: 2024-01-01T00:00:00Z",
+		"No Nonce":     domain + " wants you to sign in with your Ethereum account:\n0x71C7656EC7ab88b098defB751B7401B5f6d8976F\n\nURI: x\nVersion: 1\nChain ID: 1\nIssued At: 2024-01-01T00:00:00Z",
+	} {
+		_, err := auth.ParseMessage(bad)
+		assert.ErrorIs(t, err, auth.ErrInvalidMessage, name)
+	}
+}
+
+func TestAuthenticator_Verify(t *testing.T) {
+	authn := newAuthenticator(t)
+
+	t.Run("Sign In", func(t *testing.T) {
+		nonce, err := authn.NewNonce("192.0.2.1")
+		require.NoError(t, err)
+		assert.Equal(t, domain, nonce.Domain)
+		assert.Equal(t, auth.IntentSalt(domain).Hex(), nonce.IntentSalt)
+		assert.Equal(t, int64(240), nonce.ChainID)
+
+		message, signature := signIn(t, nonce.Nonce, 240, time.Now())
+		session, err := authn.Verify(message, signature)
+		require.NoError(t, err)
+		msg, _ := auth.ParseMessage(message)
+		assert.Equal(t, msg.Address.Hex(), session.Address)
+
+		address, err := authn.Authenticate(session.Token)
+		require.NoError(t, err)
+		assert.Equal(t, session.Address, address)
+
+		_, err = authn.Verify(message, signature)
+		assert.ErrorIs(t, err, auth.ErrInvalidNonce, "a nonce signs in once")
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		nonce, err := authn.NewNonce("192.0.2.1")
+		require.NoError(t, err)
+
+		message, _ := signIn(t, nonce.Nonce, 1, time.Now())
+		_, err = authn.Verify(message, "0x00")
+		assert.ErrorIs(t, err, auth.ErrInvalidMessage, "wrong chain")
+
+		message, _ = signIn(t, nonce.Nonce, 240, time.Now().Add(time.Hour))
+		_, err = authn.Verify(message, "0x00")
+		assert.ErrorIs(t, err, auth.ErrInvalidMessage, "issued in the future")
+
+		message, _ = signIn(t, nonce.Nonce, 240, time.Now())
+		_, forged := signIn(t, nonce.Nonce, 240, time.Now()) // Signed by a different key
+		_, err = authn.Verify(message, forged)
+		assert.ErrorIs(t, err, auth.ErrInvalidSignature)
+
+		message, signature := signIn(t, "never-issued", 240, time.Now())
+		_, err = authn.Verify(message, signature)
+		assert.ErrorIs(t, err, auth.ErrInvalidNonce)
+	})
+
+	_, err := authn.Authenticate("tfs_unknown")
+	assert.ErrorIs(t, err, auth.ErrInvalidSession)
+}
+
+func TestAuthenticator_NonceLimits(t *testing.T) {
+	authn := newAuthenticator(t)
+	for i := 0; i < 10; i++ {
+		_, err := authn.NewNonce("192.0.2.1")
+		require.NoError(t, err)
+	}
+	_, err := authn.NewNonce("192.0.2.1")
+	assert.ErrorIs(t, err, auth.ErrTooManyNonces, "a client gets 10 nonces a minute")
+	_, err = authn.NewNonce("192.0.2.2")
+	assert.NoError(t, err, "other clients are not affected")
+
+	// Without a domain there is nothing to sign in to
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	disabled := auth.NewAuthenticator(store, "", big.NewInt(240), time.Hour, nil)
+	_, err = disabled.NewNonce("192.0.2.1")
+	assert.ErrorIs(t, err, auth.ErrSignInDisabled)
+	message, signature := signIn(t, "abc12345", 240, time.Now())
+	_, err = disabled.Verify(message, signature)
+	assert.ErrorIs(t, err, auth.ErrSignInDisabled)
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authn := newAuthenticator(t)
+	nonce, err := authn.NewNonce("192.0.2.1")
+	require.NoError(t, err)
+	message, signature := signIn(t, nonce.Nonce, 240, time.Now())
+	session, err := authn.Verify(message, signature)
+	require.NoError(t, err)
+
+	serve := func(allowHeader bool, headers map[string]string) (int, string) {
+		router := gin.New()
+		router.Use(authn.Middleware(allowHeader))
+		router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, auth.UserAddress(c)) })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	const claimed = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+
+	code, body := serve(false, map[string]string{"Authorization": "Bearer " + session.Token})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, session.Address, body)
+
+	code, _ = serve(false, map[string]string{"X-User-Address": claimed})
+	assert.Equal(t, http.StatusUnauthorized, code, "the header alone is not trusted")
+
+	code, _ = serve(false, map[string]string{"Authorization": "Bearer " + session.Token, "X-User-Address": claimed})
+	assert.Equal(t, http.StatusForbidden, code, "a session cannot act as another address")
+
+	code, _ = serve(false, map[string]string{"Authorization": "Bearer tfs_unknown"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, body = serve(true, map[string]string{"X-User-Address": strings.ToLower(claimed)})
+	assert.Equal(t, http.StatusOK, code, "development mode trusts the header")
+	assert.Equal(t, claimed, body, "the header address is checksummed like a session's")
+
+	code, _ = serve(true, map[string]string{"X-User-Address": "0x742d35Cc6634C0532925a3b844Bc454e4438f4zz"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const agent = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+
+	adminKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	authn := newAuthenticator(t, crypto.PubkeyToAddress(adminKey.PublicKey).Hex())
+	nonce, err := authn.NewNonce("192.0.2.1")
+	require.NoError(t, err)
+	message, signature := signInAs(t, adminKey, nonce.Nonce, 240, time.Now())
+	admin, err := authn.Verify(message, signature)
+	require.NoError(t, err)
+
+	_, err = authn.CreateAPIKey("admin", "not-an-address", "", []string{auth.ScopeIntentRead}, 0)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+	_, err = authn.CreateAPIKey("admin", agent, "", nil, 0)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey, "a key needs a scope")
+	_, err = authn.CreateAPIKey("admin", agent, "", []string{"intent:everything"}, 0)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+
+	key, err := authn.CreateAPIKey("admin", strings.ToLower(agent), "trading-agent", []string{auth.ScopeIntentRead, auth.ScopeIntentRead}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, agent, key.Address, "addresses are checksummed")
+	assert.Equal(t, []string{auth.ScopeIntentRead}, key.Scopes)
+	assert.True(t, strings.HasPrefix(key.Key, key.Prefix))
+
+	serve := func(token string) int {
+		router := gin.New()
+		router.Use(authn.Middleware(false))
+		router.GET("/intents", auth.Require(auth.ScopeIntentRead), func(c *gin.Context) { c.String(http.StatusOK, auth.UserAddress(c)) })
+		router.POST("/intent", auth.Require(auth.ScopeIntentSubmit), func(c *gin.Context) { c.Status(http.StatusAccepted) })
+		router.GET("/admin", authn.RequireAdmin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodGet, "/intents", nil)
+		rec := httptest.NewRecorder()
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code
+		}
+		assert.Equal(t, agent, rec.Body.String())
+
+		for _, r := range []*http.Request{httptest.NewRequest(http.MethodPost, "/intent", nil), httptest.NewRequest(http.MethodGet, "/admin", nil)} {
+			rec := httptest.NewRecorder()
+			r.Header.Set("Authorization", "Bearer "+token)
+			router.ServeHTTP(rec, r)
+			assert.Equal(t, http.StatusForbidden, rec.Code, "%s is out of scope", r.URL.Path)
+		}
+		return http.StatusOK
+	}
+	assert.Equal(t, http.StatusOK, serve(key.Key))
+	assert.Equal(t, http.StatusUnauthorized, serve("tfk_unknown"))
+
+	router := gin.New()
+	router.GET("/admin", authn.Middleware(false), authn.RequireAdmin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "an admin session manages keys")
+
+	keys, err := authn.ListAPIKeys(agent)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Empty(t, keys[0].Key, "the key is shown only once")
+	assert.NotZero(t, keys[0].LastUsedAt)
+
+	require.NoError(t, authn.RevokeAPIKey(key.ID))
+	assert.Equal(t, http.StatusUnauthorized, serve(key.Key))
+	assert.ErrorIs(t, authn.RevokeAPIKey(key.ID), auth.ErrAPIKeyNotFound)
+
+	expiring, err := authn.CreateAPIKey("admin", agent, "", []string{auth.ScopeIntentRead}, time.Nanosecond)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, serve(expiring.Key))
+}
+
+func TestVerifyIntent(t *testing.T) {
+	authn := newAuthenticator(t)
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	agent := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	intent := types.Intent{ID: "intent-1", Steps: []types.IntentStep{
+		{Action: "payment", Params: map[string]string{"recipient": "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "amount": "100"}},
+		{Action: "contract_call", Params: map[string]string{"contract": "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "function": "f(uint256)"},
+			TypedParams: map[string]json.RawMessage{"args": json.RawMessage(`[ 1 ]`)}},
+	}}
+	sign := func(intent types.Intent, chainID int64, salt common.Hash) string {
+		typedData, err := auth.IntentTypedData(intent, big.NewInt(chainID), salt)
+		require.NoError(t, err)
+		hash, _, err := apitypes.TypedDataAndHash(typedData)
+		require.NoError(t, err)
+		sig, err := crypto.Sign(hash, key)
+		require.NoError(t, err)
+		sig[crypto.RecoveryIDOffset] += 27
+		return hexutil.Encode(sig)
+	}
+
+	assert.NoError(t, authn.VerifyIntent(intent, agent, false), "unsigned intents pass unless required")
+	assert.ErrorIs(t, authn.VerifyIntent(intent, agent, true), auth.ErrUnsignedIntent)
+
+	salt := auth.IntentSalt(domain)
+	intent.Signature = sign(intent, 240, salt)
+	assert.NoError(t, authn.VerifyIntent(intent, agent, true))
+	assert.NoError(t, authn.VerifyIntent(intent, strings.ToLower(agent), true))
+	assert.ErrorIs(t, authn.VerifyIntent(intent, "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", true), auth.ErrInvalidIntentSignature,
+		"another address's intent")
+
+	// Whitespace in typed_params is not significant; any other change is
+	reformatted := intent
+	reformatted.Steps = slices.Clone(intent.Steps)
+	reformatted.Steps[1].TypedParams = map[string]json.RawMessage{"args": json.RawMessage(`[1]`)}
+	assert.NoError(t, authn.VerifyIntent(reformatted, agent, true))
+
+	tampered := intent
+	tampered.Steps = slices.Clone(intent.Steps)
+	tampered.Steps[0].Params = map[string]string{"recipient": "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "amount": "1000"}
+	assert.ErrorIs(t, authn.VerifyIntent(tampered, agent, true), auth.ErrInvalidIntentSignature)
+
+	otherChain := intent
+	otherChain.Signature = sign(intent, 1, salt)
+	assert.ErrorIs(t, authn.VerifyIntent(otherChain, agent, true), auth.ErrInvalidIntentSignature)
+
+	otherDeployment := intent
+	otherDeployment.Signature = sign(intent, 240, auth.IntentSalt("staging."+domain))
+	assert.ErrorIs(t, authn.VerifyIntent(otherDeployment, agent, true), auth.ErrInvalidIntentSignature,
+		"a deployment on the same chain does not accept it")
+
+	noID := intent
+	noID.ID = ""
+	noID.Signature = sign(noID, 240, salt)
+	assert.ErrorIs(t, authn.VerifyIntent(noID, agent, true), auth.ErrInvalidIntentSignature, "a signed intent names its ID")
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+var (
+	// ErrUnsignedIntent is returned when signed intents are required and an intent has no signature
+	ErrUnsignedIntent = errors.New("intent must carry an EIP-712 signature")
+	// ErrInvalidIntentSignature is returned when an intent's signature is malformed or not by the submitter
+	ErrInvalidIntentSignature = errors.New("intent signature does not match the authenticated address")
+)
+
+// intentTypes is the EIP-712 schema of a signed intent. Maps have no EIP-712 type, so params
+// and typed_params are signed as key/value lists sorted by key, typed_params values as their
+// JSON without insignificant whitespace.
+var intentTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "salt", Type: "bytes32"},
+	},
+	"Intent": {
+		{Name: "id", Type: "string"},
+		{Name: "steps", Type: "Step[]"},
+	},
+	"Step": {
+		{Name: "action", Type: "string"},
+		{Name: "params", Type: "Param[]"},
+		{Name: "typedParams", Type: "Param[]"},
+	},
+	"Param": {
+		{Name: "key", Type: "string"},
+		{Name: "value", Type: "string"},
+	},
+}
+
+// IntentSalt is the EIP-712 domain salt of a deployment: the keccak256 of its sign-in domain,
+// so an intent signed for one deployment is not valid on another on the same chain
+func IntentSalt(domain string) common.Hash {
+	return crypto.Keccak256Hash([]byte(domain))
+}
+
+// IntentTypedData returns the EIP-712 typed data an agent signs (eth_signTypedData_v4) to
+// authorize an intent: its ID and normalized steps, bound to the chain and the deployment's salt
+func IntentTypedData(intent types.Intent, chainID *big.Int, salt common.Hash) (apitypes.TypedData, error) {
+	steps := []interface{}{}
+	for i, step := range intent.WorkflowSteps() {
+		typedParams := []interface{}{}
+		for _, key := range slices.Sorted(maps.Keys(step.TypedParams)) {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, step.TypedParams[key]); err != nil {
+				return apitypes.TypedData{}, fmt.Errorf("step %d typed_params %q: %w", i, key, err)
+			}
+			typedParams = append(typedParams, map[string]interface{}{"key": key, "value": compact.String()})
+		}
+		params := []interface{}{}
+		for _, key := range slices.Sorted(maps.Keys(step.Params)) {
+			params = append(params, map[string]interface{}{"key": key, "value": step.Params[key]})
+		}
+		steps = append(steps, map[string]interface{}{"action": step.Action, "params": params, "typedParams": typedParams})
+	}
+
+	return apitypes.TypedData{
+		Types:       intentTypes,
+		PrimaryType: "Intent",
+		Domain: apitypes.TypedDataDomain{
+			Name:    "TrustFlow",
+			Version: "1",
+			ChainId: (*math.HexOrDecimal256)(chainID),
+			Salt:    salt.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{"id": intent.ID, "steps": steps},
+	}, nil
+}
+
+// VerifyIntent checks that intent.Signature is address's EIP-712 signature over the intent.
+// An unsigned intent passes unless required.
+func (a *Authenticator) VerifyIntent(intent types.Intent, address string, required bool) error {
+	if intent.Signature == "" {
+		if required {
+			return ErrUnsignedIntent
+		}
+		return nil
+	}
+	if intent.ID == "" {
+		// The ID is what stops a signed intent being submitted again as a new one
+		return fmt.Errorf("%w: a signed intent must carry its id", ErrInvalidIntentSignature)
+	}
+
+	typedData, err := IntentTypedData(intent, big.NewInt(a.chainID), IntentSalt(a.domain))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIntentSignature, err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIntentSignature, err)
+	}
+	signer, err := recoverSigner(hash, intent.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIntentSignature, err)
+	}
+	if !common.IsHexAddress(address) || signer != common.HexToAddress(address) {
+		return ErrInvalidIntentSignature
+	}
+	return nil
+}
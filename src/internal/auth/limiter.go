@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// limiter allows each client a number of requests per fixed window. It only remembers
+// clients whose window is still open.
+type limiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	clients   map[string]*clientWindow
+	lastSweep time.Time
+}
+
+type clientWindow struct {
+	start time.Time
+	count int
+}
+
+func newLimiter(limit int, window time.Duration) *limiter {
+	return &limiter{limit: limit, window: window, clients: make(map[string]*clientWindow)}
+}
+
+// allow counts a request from client at now and reports whether it is within the limit
+func (l *limiter) allow(client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= l.window {
+		for id, w := range l.clients {
+			if now.Sub(w.start) >= l.window {
+				delete(l.clients, id)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.clients[client]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &clientWindow{start: now}
+		l.clients[client] = w
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
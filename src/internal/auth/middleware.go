@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// UserKey is the gin context key holding the authenticated user's address
+const UserKey = "user_address"
+
+// scopesKey holds the request's API key scopes; it is unset for sessions, which may do anything
+const scopesKey = "api_key_scopes"
+
+// sessionKey is set when the request carries a sign-in session
+const sessionKey = "signed_in"
+
+// UserAddress returns the address the request was authenticated as
+func UserAddress(c *gin.Context) string {
+	return c.GetString(UserKey)
+}
+
+// Middleware authenticates each request by its "Authorization: Bearer <token>", either a
+// session token or an API key ("tfk_..."), and records the address it acts as for
+// UserAddress. With allowHeader, requests without a token may instead name any address in
+// X-User-Address (development only: unverified).
+func (a *Authenticator) Middleware(allowHeader bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, hasToken := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		token = strings.TrimSpace(token)
+		claimed := c.GetHeader("X-User-Address")
+
+		if !hasToken {
+			if !allowHeader {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing session token or API key; sign in with POST /auth/verify"})
+				c.Abort()
+				return
+			}
+			if claimed == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Missing X-User-Address header"})
+				c.Abort()
+				return
+			}
+			if !strings.HasPrefix(claimed, "0x") || !common.IsHexAddress(claimed) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address"})
+				c.Abort()
+				return
+			}
+			c.Set(UserKey, common.HexToAddress(claimed).Hex()) // Same key as a signed-in session
+			c.Next()
+			return
+		}
+
+		var address string
+		var err error
+		if strings.HasPrefix(token, "tfk_") {
+			key, keyErr := a.authenticateKey(token)
+			if keyErr == nil {
+				address = key.Address
+				c.Set(scopesKey, key.Scopes)
+			}
+			err = keyErr
+		} else {
+			address, err = a.Authenticate(token)
+			c.Set(sessionKey, true)
+		}
+		if errors.Is(err, ErrInvalidSession) || errors.Is(err, ErrUnknownAPIKey) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Printf("Credential lookup failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			c.Abort()
+			return
+		}
+		// A header naming someone else is a client bug worth surfacing, not silently ignoring
+		if claimed != "" && (!common.IsHexAddress(claimed) || common.HexToAddress(claimed).Hex() != address) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "X-User-Address does not match the authenticated address"})
+			c.Abort()
+			return
+		}
+
+		c.Set(UserKey, address)
+		c.Next()
+	}
+}
+
+// Require rejects requests made with an API key that lacks scope. Sessions (and header
+// authentication in development) carry every scope.
+func Require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scopes, ok := c.Get(scopesKey); ok && !slices.Contains(scopes.([]string), scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin admits only admins signed in with a session: API keys cannot manage keys,
+// and an unverified X-User-Address is never an admin
+func (a *Authenticator) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool(sessionKey) || !a.IsAdmin(UserAddress(c)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin session required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
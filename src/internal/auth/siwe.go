@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrInvalidMessage is returned when a sign-in message is not a well-formed EIP-4361 message
+var ErrInvalidMessage = errors.New("invalid sign-in message")
+
+const preambleSuffix = " wants you to sign in with your Ethereum account:"
+
+// Message is a parsed EIP-4361 (Sign-In with Ethereum) message
+type Message struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// ParseMessage parses the plain-text EIP-4361 message a wallet signed
+func ParseMessage(raw string) (*Message, error) {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidMessage, fmt.Sprintf(format, args...))
+	}
+	if len(lines) < 2 {
+		return nil, invalid("too short")
+	}
+
+	// ${domain} wants you to sign in with your Ethereum account:
+	domain, ok := strings.CutSuffix(lines[0], preambleSuffix)
+	if !ok || domain == "" {
+		return nil, invalid("missing preamble")
+	}
+	if _, host, found := strings.Cut(domain, "://"); found {
+		domain = host // The scheme is optional
+	}
+	msg := &Message{Domain: domain}
+
+	// ${address}, EIP-55 checksummed
+	if !common.IsHexAddress(lines[1]) || common.HexToAddress(lines[1]).Hex() != lines[1] {
+		return nil, invalid("address must be an EIP-55 checksummed address")
+	}
+	msg.Address = common.HexToAddress(lines[1])
+
+	// Blank line, optional statement, blank line
+	i := 2
+	for i < len(lines) && lines[i] == "" {
+		i++
+	}
+	if i < len(lines) && !strings.HasPrefix(lines[i], "URI: ") {
+		msg.Statement = lines[i]
+		i++
+		for i < len(lines) && lines[i] == "" {
+			i++
+		}
+	}
+
+	fields := make(map[string]string)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		if line == "Resources:" {
+			for i++; i < len(lines) && strings.HasPrefix(lines[i], "- "); i++ {
+				msg.Resources = append(msg.Resources, strings.TrimPrefix(lines[i], "- "))
+			}
+			i--
+			continue
+		}
+		key, value, found := strings.Cut(line, ": ")
+		if !found {
+			return nil, invalid("malformed line %q", line)
+		}
+		if _, dup := fields[key]; dup {
+			return nil, invalid("duplicate field %q", key)
+		}
+		fields[key] = value
+	}
+
+	for _, required := range []string{"URI", "Version", "Chain ID", "Nonce", "Issued At"} {
+		if fields[required] == "" {
+			return nil, invalid("missing %s", required)
+		}
+	}
+	msg.URI = fields["URI"]
+	msg.Version = fields["Version"]
+	msg.Nonce = fields["Nonce"]
+	msg.RequestID = fields["Request ID"]
+
+	chainID, err := strconv.ParseInt(fields["Chain ID"], 10, 64)
+	if err != nil {
+		return nil, invalid("malformed Chain ID")
+	}
+	msg.ChainID = chainID
+
+	if msg.IssuedAt, err = time.Parse(time.RFC3339, fields["Issued At"]); err != nil {
+		return nil, invalid("malformed Issued At")
+	}
+	for key, dst := range map[string]**time.Time{"Expiration Time": &msg.ExpirationTime, "Not Before": &msg.NotBefore} {
+		if raw, ok := fields[key]; ok {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, invalid("malformed %s", key)
+			}
+			*dst = &t
+		}
+	}
+
+	return msg, nil
+}
+
+// RecoverAddress returns the account whose key produced an EIP-191 personal_sign signature
+// (65 bytes, hex) over message
+func RecoverAddress(message string, signature string) (common.Address, error) {
+	return recoverSigner(accounts.TextHash([]byte(message)), signature)
+}
+
+// recoverSigner returns the address whose key produced signature over hash
+func recoverSigner(hash []byte, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, errors.New("signature must be 65 hex-encoded bytes")
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Wallets return v as 27/28
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
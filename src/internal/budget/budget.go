@@ -0,0 +1,95 @@
+package budget
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"trustflow/src/internal/policy"
+	"trustflow/src/pkg/types"
+)
+
+// RuleDailyBudget is reported when a step would exceed the rolling spend cap
+const RuleDailyBudget = "daily_budget"
+
+// SpendSource reports how much a user has already spent, backed by the audit log
+type SpendSource interface {
+	SumExecutedValue(userID string, since int64) (*big.Int, error)
+}
+
+// Tracker enforces a rolling spend cap per user over a sliding window. Only native value
+// counts: ERC-20 amounts are not priced, so token spend is left to policy and approvals.
+type Tracker struct {
+	source SpendSource
+	limit  *big.Int // nil means unlimited
+	window time.Duration
+	now    func() time.Time
+}
+
+func NewTracker(source SpendSource, limit *big.Int, window time.Duration) *Tracker {
+	return &Tracker{
+		source: source,
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+// Spent returns the value executed by the user within the current window
+func (t *Tracker) Spent(userID string) (*big.Int, error) {
+	since := t.now().Add(-t.window).Unix()
+	spent, err := t.source.SumExecutedValue(userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum executed value: %w", err)
+	}
+	return spent, nil
+}
+
+// Check returns a violation if spending value now would breach the user's cap
+func (t *Tracker) Check(userID string, value *big.Int) (*policy.Violation, error) {
+	if t == nil || t.limit == nil {
+		return nil, nil
+	}
+
+	spent, err := t.Spent(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	total := new(big.Int).Add(spent, value)
+	if total.Cmp(t.limit) > 0 {
+		return &policy.Violation{
+			Rule: RuleDailyBudget,
+			Message: fmt.Sprintf("value %s wei would bring spend to %s wei, over the limit of %s wei per %s",
+				value, total, t.limit, t.window),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// Status summarizes the user's spend and remaining budget
+func (t *Tracker) Status(userID string) (*types.BudgetStatus, error) {
+	spent, err := t.Spent(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &types.BudgetStatus{
+		UserAddress:   userID,
+		Spent:         spent.String(),
+		WindowSeconds: int64(t.window.Seconds()),
+		Unlimited:     t.limit == nil,
+	}
+
+	if t.limit != nil {
+		remaining := new(big.Int).Sub(t.limit, spent)
+		if remaining.Sign() < 0 {
+			remaining.SetInt64(0)
+		}
+		status.Limit = t.limit.String()
+		status.Remaining = remaining.String()
+	}
+
+	return status, nil
+}
@@ -0,0 +1,59 @@
+package budget_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+	"trustflow/src/internal/budget"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	spent *big.Int
+	since int64
+}
+
+func (f *fakeSource) SumExecutedValue(userID string, since int64) (*big.Int, error) {
+	f.since = since
+	return new(big.Int).Set(f.spent), nil
+}
+
+func TestTracker_Check(t *testing.T) {
+	source := &fakeSource{spent: big.NewInt(700)}
+	tracker := budget.NewTracker(source, big.NewInt(1000), 24*time.Hour)
+
+	t.Run("Within Budget", func(t *testing.T) {
+		violation, err := tracker.Check("0xuser", big.NewInt(300))
+		require.NoError(t, err)
+		assert.Nil(t, violation)
+		assert.InDelta(t, time.Now().Add(-24*time.Hour).Unix(), source.since, 2)
+	})
+
+	t.Run("Over Budget", func(t *testing.T) {
+		violation, err := tracker.Check("0xuser", big.NewInt(301))
+		require.NoError(t, err)
+		require.NotNil(t, violation)
+		assert.Equal(t, budget.RuleDailyBudget, violation.Rule)
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		unlimited := budget.NewTracker(source, nil, time.Hour)
+		violation, err := unlimited.Check("0xuser", big.NewInt(1e18))
+		require.NoError(t, err)
+		assert.Nil(t, violation)
+	})
+}
+
+func TestTracker_Status(t *testing.T) {
+	tracker := budget.NewTracker(&fakeSource{spent: big.NewInt(1200)}, big.NewInt(1000), time.Hour)
+
+	status, err := tracker.Status("0xuser")
+	require.NoError(t, err)
+	assert.Equal(t, "1200", status.Spent)
+	assert.Equal(t, "1000", status.Limit)
+	assert.Equal(t, "0", status.Remaining) // Never negative
+	assert.Equal(t, int64(3600), status.WindowSeconds)
+	assert.False(t, status.Unlimited)
+}
@@ -2,26 +2,41 @@ package chain
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
 	"strings"
+	"time"
 
 	"trustflow/src/internal/config"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// ErrReceiptTimeout is returned when a transaction is not mined and confirmed in time
+var ErrReceiptTimeout = errors.New("timed out waiting for transaction receipt")
+
+// ErrSimulateUnsupported is returned when the node does not implement eth_simulateV1
+var ErrSimulateUnsupported = errors.New("eth_simulateV1 not supported by node")
+
+// ErrBroadcastUncertain is returned when a journaled transaction was sent but the node never
+// answered, so it may be in the pool: its nonce stays taken and the journal must be reconciled
+var ErrBroadcastUncertain = errors.New("broadcast outcome unknown")
+
 type ChainClient struct {
-	client     *ethclient.Client
-	privateKey *ecdsa.PrivateKey
-	address    common.Address
-	chainID    *big.Int
+	client  *ethclient.Client
+	signer  Signer
+	address common.Address
+	chainID *big.Int
+	nonces  *NonceManager
+
+	confirmations  uint64
+	receiptTimeout time.Duration
 }
 
 // NewChainClient initializes the connection and loads the wallet
@@ -32,21 +47,14 @@ func NewChainClient(cfg *config.Config) (*ChainClient, error) {
 		return nil, fmt.Errorf("failed to connect to RPC: %w", err)
 	}
 
-	// 2. Parse Private Key
-	// Strip "0x" prefix if present
-	pkStr := strings.TrimPrefix(cfg.PrivateKey, "0x")
-	privateKey, err := crypto.HexToECDSA(pkStr)
+	// 2. Load Signer (remote signer, encrypted keystore or raw key)
+	signer, err := NewSigner(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("invalid private key: %w", err)
+		return nil, err
 	}
 
 	// 3. Derive Public Address
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, errors.New("error casting public key to ECDSA")
-	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+	fromAddress := signer.Address()
 
 	// 4. Get Chain ID
 	chainID, err := client.ChainID(context.Background())
@@ -54,14 +62,36 @@ func NewChainClient(cfg *config.Config) (*ChainClient, error) {
 		return nil, fmt.Errorf("failed to get chain ID: %w", err)
 	}
 
+	// 5. Confirmation Settings
+	confirmations := cfg.Confirmations
+	if confirmations == 0 {
+		confirmations = 1
+	}
+	receiptTimeout := cfg.ReceiptTimeout
+	if receiptTimeout <= 0 {
+		receiptTimeout = 2 * time.Minute
+	}
+
 	return &ChainClient{
-		client:     client,
-		privateKey: privateKey,
-		address:    fromAddress,
-		chainID:    chainID,
+		client:         client,
+		signer:         signer,
+		address:        fromAddress,
+		chainID:        chainID,
+		nonces:         NewNonceManager(client, nil),
+		confirmations:  confirmations,
+		receiptTimeout: receiptTimeout,
 	}, nil
 }
 
+// WithSigner returns a client that sends from and simulates as signer's account. It shares
+// the connection and nonce manager with c; only the original client should be closed.
+func (c *ChainClient) WithSigner(signer Signer) *ChainClient {
+	clone := *c
+	clone.signer = signer
+	clone.address = signer.Address()
+	return &clone
+}
+
 // GetBalance returns the balance of the connected wallet in Wei
 func (c *ChainClient) GetBalance(ctx context.Context) (*big.Int, error) {
 	return c.client.BalanceAt(ctx, c.address, nil)
@@ -77,41 +107,262 @@ func (c *ChainClient) EstimateGas(ctx context.Context, callMsg ethereum.CallMsg)
 	return c.client.EstimateGas(ctx, callMsg)
 }
 
-// SendTransaction builds, signs, and broadcasts a transaction
-func (c *ChainClient) SendTransaction(ctx context.Context, to *common.Address, value *big.Int, data []byte, gasLimit uint64) (string, error) {
-	// 1. Get Nonce
-	nonce, err := c.client.PendingNonceAt(ctx, c.address)
+// CallContract executes a read-only call against the latest block
+func (c *ChainClient) CallContract(ctx context.Context, to common.Address, data []byte) ([]byte, error) {
+	return c.client.CallContract(ctx, ethereum.CallMsg{From: c.address, To: &to, Data: data}, nil)
+}
+
+// SimulateCalls executes calls in order within a single simulated block on top of the
+// latest state, so each call observes the state changes of the ones before it
+func (c *ChainClient) SimulateCalls(ctx context.Context, calls []ethereum.CallMsg) ([]ethclient.SimulateCallResult, error) {
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	blocks, err := c.client.SimulateV1(ctx, ethclient.SimulateOptions{
+		BlockStateCalls: []ethclient.SimulateBlock{{Calls: calls}},
+	}, &latest)
 	if err != nil {
-		return "", fmt.Errorf("failed to get nonce: %w", err)
+		if simulateUnsupported(err) {
+			return nil, fmt.Errorf("%w: %v", ErrSimulateUnsupported, err)
+		}
+		return nil, err
+	}
+	if len(blocks) != 1 || len(blocks[0].Calls) != len(calls) {
+		return nil, fmt.Errorf("unexpected eth_simulateV1 result shape")
 	}
+	return blocks[0].Calls, nil
+}
 
-	// 2. Get Gas Price (using suggestion)
-	gasPrice, err := c.client.SuggestGasPrice(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get gas price: %w", err)
+// simulateUnsupported reports whether a node rejected eth_simulateV1 itself rather than the
+// calls: -32601, or the wordings providers and proxies use for a method they do not serve
+func simulateUnsupported(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return true
 	}
+	message := strings.ToLower(err.Error())
+	if !strings.Contains(message, "method") && !strings.Contains(message, "eth_simulatev1") {
+		return false
+	}
+	for _, phrase := range []string{"not found", "does not exist", "not supported", "unsupported", "not available", "not allowed", "not whitelisted"} {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
 
-	// 3. Create Transaction (Legacy type for broad compatibility)
-	var tx *types.Transaction
+// isRejection reports whether the node answered a broadcast with an error, so the transaction
+// was definitely not accepted. Transport failures, timeouts and gateway errors leave that unknown.
+func isRejection(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode < 500
+}
+
+// isAlreadyKnown reports whether the node already has the transaction in its pool
+func isAlreadyKnown(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
+}
+
+// SetNonceStore persists allocated nonces so they survive restarts
+func (c *ChainClient) SetNonceStore(store NonceStore) {
+	c.nonces = NewNonceManager(c.client, store)
+}
+
+// SentTx describes a broadcast transaction
+type SentTx struct {
+	Hash  string
+	Nonce uint64
+	Fees  *FeeParams
+	Raw   []byte // Signed transaction, as broadcast
+}
+
+// SendTransaction builds, signs, and broadcasts a transaction, as EIP-1559 where the chain supports it
+func (c *ChainClient) SendTransaction(ctx context.Context, to *common.Address, value *big.Int, data []byte, gasLimit uint64) (*SentTx, error) {
+	return c.SendJournaledTransaction(ctx, to, value, data, gasLimit, nil)
+}
+
+// SendJournaledTransaction is SendTransaction with a write-ahead journal: journal is handed the
+// signed transaction before it is broadcast, and nothing is broadcast if it fails. A process
+// that dies mid-send thus always leaves a record of what may have reached the network. When the
+// node does not answer the broadcast, the error wraps ErrBroadcastUncertain and the journaled
+// transaction must be treated as possibly sent.
+func (c *ChainClient) SendJournaledTransaction(ctx context.Context, to *common.Address, value *big.Int, data []byte, gasLimit uint64, journal func(*SentTx) error) (*SentTx, error) {
 	if to == nil {
-		return "", errors.New("contract creation not yet supported")
+		return nil, errors.New("contract creation not yet supported")
 	}
 
-	tx = types.NewTransaction(nonce, *to, value, gasLimit, gasPrice, data)
+	// 1. Price the Transaction (dynamic fees on London chains, legacy gas price otherwise)
+	fees, err := c.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Allocate Nonce (serialized across concurrent sends from this wallet)
+	nonce, err := c.nonces.Acquire(ctx, c.address)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Create Transaction
+	tx := newTransaction(c.chainID, nonce, to, value, gasLimit, data, fees)
 
 	// 4. Sign Transaction
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(c.chainID), c.privateKey)
+	signedTx, err := c.signer.SignTx(ctx, tx, c.chainID)
+	if err != nil {
+		c.nonces.Release(c.address, nonce)
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	raw, err := signedTx.MarshalBinary()
 	if err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %w", err)
+		c.nonces.Release(c.address, nonce)
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
 	}
+	sent := &SentTx{Hash: signedTx.Hash().Hex(), Nonce: nonce, Fees: fees, Raw: raw}
 
-	// 5. Broadcast
+	// 5. Journal, then Broadcast
+	if journal != nil {
+		if err := journal(sent); err != nil {
+			c.nonces.Release(c.address, nonce)
+			return nil, fmt.Errorf("failed to journal transaction: %w", err)
+		}
+	}
 	err = c.client.SendTransaction(ctx, signedTx)
+	switch {
+	case err == nil, isAlreadyKnown(err):
+	case isNonceError(err):
+		c.nonces.Commit(c.address, nonce)
+		c.nonces.Reset(c.address)
+		return nil, fmt.Errorf("failed to broadcast transaction: %w", err)
+	case isUnderpriced(err):
+		c.nonces.Commit(c.address, nonce)
+		return nil, fmt.Errorf("failed to broadcast transaction: %w", err)
+	case isRejection(err):
+		c.nonces.Release(c.address, nonce)
+		return nil, fmt.Errorf("failed to broadcast transaction: %w", err)
+	default:
+		// A timeout or dropped connection: the node may have taken it, so the nonce is not reused
+		c.nonces.Commit(c.address, nonce)
+		return nil, fmt.Errorf("%w: %v", ErrBroadcastUncertain, err)
+	}
+	c.nonces.Commit(c.address, nonce)
+
+	return sent, nil
+}
+
+// SendRawTransaction rebroadcasts an already signed transaction
+func (c *ChainClient) SendRawTransaction(ctx context.Context, raw []byte) error {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return fmt.Errorf("invalid signed transaction: %w", err)
+	}
+	if err := c.client.SendTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("failed to broadcast transaction: %w", err)
+	}
+	return nil
+}
+
+// MinedNonce returns the number of transactions from the wallet included in the latest block:
+// every nonce below it is used up, whichever transaction used it
+func (c *ChainClient) MinedNonce(ctx context.Context) (uint64, error) {
+	nonce, err := c.client.NonceAt(ctx, c.address, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to broadcast transaction: %w", err)
+		return 0, fmt.Errorf("failed to get mined nonce: %w", err)
 	}
+	return nonce, nil
+}
 
-	return signedTx.Hash().Hex(), nil
+// WaitForReceipt polls until the transaction is mined and buried under the configured
+// number of confirmations, or the receipt timeout elapses. A mined but reverted
+// transaction is returned as a receipt with status 0, not as an error.
+func (c *ChainClient) WaitForReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	return c.WaitForAnyReceipt(ctx, func() []string { return []string{txHash} })
+}
+
+// WaitForAnyReceipt is WaitForReceipt for a transaction that may have been replaced at the
+// same nonce: hashes is re-read on every poll and the first confirmed receipt wins.
+func (c *ChainClient) WaitForAnyReceipt(ctx context.Context, hashes func() []string) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.receiptTimeout)
+	defer cancel()
+
+	pollInterval := min(2*time.Second, c.receiptTimeout/10)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := c.FindReceipt(ctx, hashes())
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		}
+
+		if receipt != nil {
+			head, err := c.client.BlockNumber(ctx)
+			if err != nil && ctx.Err() == nil {
+				return nil, fmt.Errorf("failed to fetch block number: %w", err)
+			}
+			if err == nil && head+1 >= receipt.BlockNumber.Uint64()+c.confirmations {
+				return receipt, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w: %s after %s", ErrReceiptTimeout, strings.Join(hashes(), ", "), c.receiptTimeout)
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// FindReceipt returns the receipt of whichever of the hashes has been mined, or nil if none has
+func (c *ChainClient) FindReceipt(ctx context.Context, hashes []string) (*types.Receipt, error) {
+	for _, txHash := range hashes {
+		receipt, err := c.client.TransactionReceipt(ctx, common.HexToHash(txHash))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch receipt: %w", err)
+		}
+		return receipt, nil
+	}
+	return nil, nil
+}
+
+// ReplaceTransaction rebroadcasts at an already used nonce with fees bumped past the
+// previous attempt, so the node replaces the pending transaction
+func (c *ChainClient) ReplaceTransaction(ctx context.Context, nonce uint64, to *common.Address, value *big.Int, data []byte, gasLimit uint64, previous *FeeParams) (*SentTx, error) {
+	current, err := c.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fees := BumpFees(previous, current)
+
+	tx := newTransaction(c.chainID, nonce, to, value, gasLimit, data, fees)
+	signedTx, err := c.signer.SignTx(ctx, tx, c.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign replacement: %w", err)
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode replacement: %w", err)
+	}
+	if err := c.client.SendTransaction(ctx, signedTx); err != nil {
+		return nil, fmt.Errorf("failed to broadcast replacement: %w", err)
+	}
+
+	return &SentTx{Hash: signedTx.Hash().Hex(), Nonce: nonce, Fees: fees, Raw: raw}, nil
+}
+
+// CancelTransaction replaces whatever is pending at nonce with a zero-value transfer to self
+func (c *ChainClient) CancelTransaction(ctx context.Context, nonce uint64, previous *FeeParams) (*SentTx, error) {
+	return c.ReplaceTransaction(ctx, nonce, &c.address, big.NewInt(0), nil, params.TxGas, previous)
 }
 
 // GetAddress returns the public address of the wallet
@@ -119,7 +370,15 @@ func (c *ChainClient) GetAddress() common.Address {
 	return c.address
 }
 
-// Close closes the underlying client connection
+// ChainID returns the ID of the chain the client is connected to
+func (c *ChainClient) ChainID() *big.Int {
+	return new(big.Int).Set(c.chainID)
+}
+
+// Close closes the underlying client connection, and the signer's if it holds one
 func (c *ChainClient) Close() {
 	c.client.Close()
+	if closer, ok := c.signer.(interface{ Close() }); ok {
+		closer.Close()
+	}
 }
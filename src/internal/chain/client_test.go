@@ -0,0 +1,325 @@
+package chain_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"trustflow/src/internal/chain"
+	"trustflow/src/internal/config"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPrivateKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	testTxHash     = "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060"
+)
+
+// rpcError is returned by a fakeNode handler to answer with a JSON-RPC error
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// fakeNode serves JSON-RPC requests from handle; eth_chainId is answered for NewChainClient
+func fakeNode(t *testing.T, cfg *config.Config, handle func(method string) interface{}) *chain.ChainClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if req.Method == "eth_chainId" {
+			resp["result"] = "0x1"
+		} else {
+			resp["result"] = handle(req.Method)
+			if rpcErr, ok := resp["result"].(rpcError); ok {
+				delete(resp, "result")
+				resp["error"] = rpcErr
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg.RPCURL = server.URL
+	cfg.PrivateKey = testPrivateKey
+	client, err := chain.NewChainClient(cfg)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func receipt(status string) map[string]interface{} {
+	return map[string]interface{}{
+		"transactionHash":   testTxHash,
+		"blockHash":         "0x" + strings.Repeat("11", 32),
+		"blockNumber":       "0x10",
+		"transactionIndex":  "0x0",
+		"status":            status,
+		"gasUsed":           "0x5208",
+		"cumulativeGasUsed": "0x5208",
+		"logsBloom":         "0x" + strings.Repeat("0", 512),
+		"logs":              []interface{}{},
+		"type":              "0x0",
+	}
+}
+
+func TestWaitForReceipt(t *testing.T) {
+	t.Run("Waits For Confirmations", func(t *testing.T) {
+		var polls, head atomic.Int64
+		head.Store(0x10)
+		client := fakeNode(t, &config.Config{Confirmations: 3, ReceiptTimeout: 5 * time.Second}, func(method string) interface{} {
+			switch method {
+			case "eth_getTransactionReceipt":
+				if polls.Add(1) == 1 {
+					return nil // Not mined yet
+				}
+				return receipt("0x1")
+			case "eth_blockNumber":
+				return hexutil.EncodeUint64(uint64(head.Add(1) - 1)) // Chain advances one block per poll
+			}
+			t.Fatalf("unexpected method %s", method)
+			return nil
+		})
+
+		r, err := client.WaitForReceipt(context.Background(), testTxHash)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), r.Status)
+		assert.Equal(t, uint64(0x10), r.BlockNumber.Uint64())
+		assert.Equal(t, uint64(21000), r.GasUsed)
+		assert.GreaterOrEqual(t, head.Load()-1, int64(0x12), "head must be 2 blocks past the receipt for 3 confirmations")
+	})
+
+	t.Run("Reverted Receipt Is Not An Error", func(t *testing.T) {
+		client := fakeNode(t, &config.Config{ReceiptTimeout: 5 * time.Second}, func(method string) interface{} {
+			if method == "eth_getTransactionReceipt" {
+				return receipt("0x0")
+			}
+			return "0x10"
+		})
+
+		r, err := client.WaitForReceipt(context.Background(), testTxHash)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), r.Status)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		client := fakeNode(t, &config.Config{ReceiptTimeout: 300 * time.Millisecond}, func(method string) interface{} {
+			return nil // Never mined
+		})
+
+		_, err := client.WaitForReceipt(context.Background(), testTxHash)
+		assert.ErrorIs(t, err, chain.ErrReceiptTimeout)
+	})
+}
+
+// header is a minimal latest block header; an empty baseFee makes it pre-London
+func header(baseFee string) map[string]interface{} {
+	zero := "0x" + strings.Repeat("0", 64)
+	h := map[string]interface{}{
+		"parentHash": zero, "sha3Uncles": zero, "miner": "0x" + strings.Repeat("0", 40),
+		"stateRoot": zero, "transactionsRoot": zero, "receiptsRoot": zero,
+		"logsBloom": "0x" + strings.Repeat("0", 512), "difficulty": "0x0", "number": "0x10",
+		"gasLimit": "0x1c9c380", "gasUsed": "0x0", "timestamp": "0x1", "extraData": "0x",
+	}
+	if baseFee != "" {
+		h["baseFeePerGas"] = baseFee
+	}
+	return h
+}
+
+func TestSuggestFees(t *testing.T) {
+	t.Run("Dynamic Fees From Fee History", func(t *testing.T) {
+		client := fakeNode(t, &config.Config{}, func(method string) interface{} {
+			switch method {
+			case "eth_getBlockByNumber":
+				return header("0x64") // 100 wei
+			case "eth_feeHistory":
+				return map[string]interface{}{
+					"oldestBlock":   "0xe",
+					"baseFeePerGas": []string{"0x64", "0x64", "0x78"}, // Next block: 120 wei
+					"gasUsedRatio":  []float64{0.5, 0.5},
+					"reward":        [][]string{{"0x3"}, {"0x0"}, {"0x5"}},
+				}
+			}
+			t.Fatalf("unexpected method %s", method)
+			return nil
+		})
+
+		fees, err := client.SuggestFees(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, chain.TxTypeDynamic, fees.Type)
+		assert.Equal(t, "120", fees.BaseFee.String())
+		assert.Equal(t, "5", fees.GasTipCap.String(), "median of the non-empty blocks")
+		assert.Equal(t, "245", fees.GasFeeCap.String(), "2 * base fee + tip")
+		assert.Equal(t, fees.GasFeeCap, fees.MaxPricePerGas())
+		assert.Empty(t, fees.Details().GasPrice)
+	})
+
+	t.Run("Legacy Without Base Fee", func(t *testing.T) {
+		client := fakeNode(t, &config.Config{}, func(method string) interface{} {
+			switch method {
+			case "eth_getBlockByNumber":
+				return header("")
+			case "eth_gasPrice":
+				return "0x3b9aca00"
+			}
+			t.Fatalf("unexpected method %s", method)
+			return nil
+		})
+
+		fees, err := client.SuggestFees(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, chain.TxTypeLegacy, fees.Type)
+		assert.Equal(t, "1000000000", fees.MaxPricePerGas().String())
+		assert.Equal(t, "1000000000", fees.Details().GasPrice)
+	})
+}
+
+func TestSendJournaledTransaction(t *testing.T) {
+	to := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+
+	var broadcast func() interface{} // Answers eth_sendRawTransaction when set
+	node := func(calls *[]string) func(method string) interface{} {
+		return func(method string) interface{} {
+			*calls = append(*calls, method)
+			switch method {
+			case "eth_getBlockByNumber":
+				return header("")
+			case "eth_gasPrice":
+				return "0x3b9aca00"
+			case "eth_getTransactionCount":
+				return "0x7"
+			case "eth_sendRawTransaction":
+				if broadcast != nil {
+					return broadcast()
+				}
+				return testTxHash
+			}
+			t.Fatalf("unexpected method %s", method)
+			return nil
+		}
+	}
+
+	t.Run("Journals Before Broadcast", func(t *testing.T) {
+		var calls []string
+		client := fakeNode(t, &config.Config{}, node(&calls))
+
+		var journaled *chain.SentTx
+		sent, err := client.SendJournaledTransaction(context.Background(), &to, big.NewInt(1), nil, 21000, func(sent *chain.SentTx) error {
+			assert.NotContains(t, calls, "eth_sendRawTransaction", "journal runs before the broadcast")
+			journaled = sent
+			return nil
+		})
+		require.NoError(t, err)
+		require.NotNil(t, journaled)
+		assert.Equal(t, sent.Hash, journaled.Hash)
+		assert.Equal(t, uint64(7), journaled.Nonce)
+
+		var tx types.Transaction
+		require.NoError(t, tx.UnmarshalBinary(journaled.Raw))
+		assert.Equal(t, sent.Hash, tx.Hash().Hex(), "the journal holds the exact signed transaction")
+		assert.Contains(t, calls, "eth_sendRawTransaction")
+	})
+
+	t.Run("Journal Failure Prevents Broadcast", func(t *testing.T) {
+		var calls []string
+		client := fakeNode(t, &config.Config{}, node(&calls))
+
+		_, err := client.SendJournaledTransaction(context.Background(), &to, big.NewInt(1), nil, 21000, func(*chain.SentTx) error {
+			return errors.New("disk full")
+		})
+		require.ErrorContains(t, err, "disk full")
+		assert.NotContains(t, calls, "eth_sendRawTransaction")
+	})
+	t.Run("Rejection Frees Nonce", func(t *testing.T) {
+		var calls []string
+		client := fakeNode(t, &config.Config{}, node(&calls))
+		broadcast = func() interface{} {
+			return rpcError{Code: -32000, Message: "insufficient funds for gas * price + value"}
+		}
+		defer func() { broadcast = nil }()
+
+		_, err := client.SendJournaledTransaction(context.Background(), &to, big.NewInt(1), nil, 21000, func(*chain.SentTx) error { return nil })
+		require.ErrorContains(t, err, "insufficient funds")
+		assert.NotErrorIs(t, err, chain.ErrBroadcastUncertain, "the node answered: nothing was sent")
+
+		broadcast = nil
+		sent, err := client.SendJournaledTransaction(context.Background(), &to, big.NewInt(1), nil, 21000, nil)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(7), sent.Nonce, "the rejected nonce is reused")
+	})
+
+	t.Run("Underpriced Replacement Is Not A Nonce Error", func(t *testing.T) {
+		var calls []string
+		client := fakeNode(t, &config.Config{}, node(&calls))
+		broadcast = func() interface{} {
+			return rpcError{Code: -32000, Message: "replacement transaction underpriced"}
+		}
+		defer func() { broadcast = nil }()
+
+		_, err := client.SendJournaledTransaction(context.Background(), &to, big.NewInt(1), nil, 21000, func(*chain.SentTx) error { return nil })
+		require.ErrorContains(t, err, "underpriced")
+
+		broadcast = nil
+		sent, err := client.SendJournaledTransaction(context.Background(), &to, big.NewInt(1), nil, 21000, nil)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(8), sent.Nonce, "the nonce is taken by the pending transaction, and the counter is not reset")
+	})
+
+	t.Run("Replacement Carries Signed Transaction", func(t *testing.T) {
+		var calls []string
+		client := fakeNode(t, &config.Config{}, node(&calls))
+
+		previous := &chain.FeeParams{Type: chain.TxTypeLegacy, GasPrice: big.NewInt(1e9)}
+		sent, err := client.ReplaceTransaction(context.Background(), 7, &to, big.NewInt(1), nil, 21000, previous)
+		require.NoError(t, err)
+
+		var tx types.Transaction
+		require.NoError(t, tx.UnmarshalBinary(sent.Raw))
+		assert.Equal(t, sent.Hash, tx.Hash().Hex(), "the replacement can be journaled for rebroadcast")
+		assert.Equal(t, uint64(7), tx.Nonce())
+	})
+
+	t.Run("Timeout After Broadcast Keeps Nonce", func(t *testing.T) {
+		var calls []string
+		client := fakeNode(t, &config.Config{}, node(&calls))
+		broadcast = func() interface{} {
+			time.Sleep(200 * time.Millisecond) // The node takes the transaction but answers too late
+			return testTxHash
+		}
+		defer func() { broadcast = nil }()
+
+		var journaled *chain.SentTx
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := client.SendJournaledTransaction(ctx, &to, big.NewInt(1), nil, 21000, func(sent *chain.SentTx) error {
+			journaled = sent
+			return nil
+		})
+		require.ErrorIs(t, err, chain.ErrBroadcastUncertain)
+		require.NotNil(t, journaled, "the possibly sent transaction is journaled")
+		assert.Equal(t, uint64(7), journaled.Nonce)
+
+		broadcast = nil
+		sent, err := client.SendJournaledTransaction(context.Background(), &to, big.NewInt(1), nil, 21000, nil)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(8), sent.Nonce, "a nonce that may be in the pool is not handed out again")
+	})
+}
@@ -0,0 +1,220 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// Transaction types reported in FeeParams.Type
+const (
+	TxTypeLegacy  = "legacy"
+	TxTypeDynamic = "dynamic_fee" // EIP-1559
+)
+
+const (
+	replacementBumpPercent = 125  // Nodes require at least +10% on every fee to replace a pending tx
+	feeHistoryBlocks       = 10   // Blocks sampled for the priority fee
+	feeHistoryPercentile   = 50.0 // Tip percentile paid within each sampled block
+	baseFeeHeadroom        = 2    // Fee cap covers this many times the next base fee
+)
+
+// FeeParams are the gas pricing fields of a transaction
+type FeeParams struct {
+	Type      string
+	GasPrice  *big.Int // Legacy only
+	GasTipCap *big.Int // EIP-1559: max priority fee per gas
+	GasFeeCap *big.Int // EIP-1559: max fee per gas
+	BaseFee   *big.Int // EIP-1559: expected base fee of the next block
+}
+
+// MaxPricePerGas is the most the transaction can pay per unit of gas. Nodes require
+// the sender to cover gasLimit * MaxPricePerGas + value.
+func (f *FeeParams) MaxPricePerGas() *big.Int {
+	if f.Type == TxTypeDynamic {
+		return f.GasFeeCap
+	}
+	return f.GasPrice
+}
+
+// Details converts the fee parameters into their API representation
+func (f *FeeParams) Details() *types.FeeParams {
+	details := &types.FeeParams{Type: f.Type}
+	if f.GasPrice != nil {
+		details.GasPrice = f.GasPrice.String()
+	}
+	if f.GasTipCap != nil {
+		details.MaxPriorityFeePerGas = f.GasTipCap.String()
+	}
+	if f.GasFeeCap != nil {
+		details.MaxFeePerGas = f.GasFeeCap.String()
+	}
+	if f.BaseFee != nil {
+		details.BaseFee = f.BaseFee.String()
+	}
+	return details
+}
+
+// FeeParamsFromDetails parses fee parameters back from their API representation
+func FeeParamsFromDetails(details *types.FeeParams) (*FeeParams, error) {
+	var err error
+	fees := &FeeParams{Type: details.Type}
+	if fees.GasPrice, err = parseWei(details.GasPrice); err != nil {
+		return nil, err
+	}
+	if fees.GasTipCap, err = parseWei(details.MaxPriorityFeePerGas); err != nil {
+		return nil, err
+	}
+	if fees.GasFeeCap, err = parseWei(details.MaxFeePerGas); err != nil {
+		return nil, err
+	}
+	if fees.BaseFee, err = parseWei(details.BaseFee); err != nil {
+		return nil, err
+	}
+
+	if fees.MaxPricePerGas() == nil || (fees.Type == TxTypeDynamic && fees.GasTipCap == nil) {
+		return nil, fmt.Errorf("incomplete %s fee parameters", fees.Type)
+	}
+	return fees, nil
+}
+
+// parseWei parses an optional decimal Wei amount
+func parseWei(raw string) (*big.Int, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	n, ok := new(big.Int).SetString(raw, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid fee value %q", raw)
+	}
+	return n, nil
+}
+
+// BumpFees prices a replacement for a transaction sent with previous: every fee is raised
+// by the replacement minimum, or to the current market price when that is higher
+func BumpFees(previous, current *FeeParams) *FeeParams {
+	bump := func(old, market *big.Int) *big.Int {
+		bumped := new(big.Int).Mul(old, big.NewInt(replacementBumpPercent))
+		bumped.Add(bumped, big.NewInt(99)) // Round up so small values still increase
+		bumped.Div(bumped, big.NewInt(100))
+		if market != nil && market.Cmp(bumped) > 0 {
+			return new(big.Int).Set(market)
+		}
+		return bumped
+	}
+
+	sameType := current != nil && current.Type == previous.Type
+	if previous.Type == TxTypeDynamic {
+		var marketTip, marketCap, baseFee *big.Int
+		if sameType {
+			marketTip, marketCap, baseFee = current.GasTipCap, current.GasFeeCap, current.BaseFee
+		}
+		fees := &FeeParams{
+			Type:      TxTypeDynamic,
+			GasTipCap: bump(previous.GasTipCap, marketTip),
+			GasFeeCap: bump(previous.GasFeeCap, marketCap),
+			BaseFee:   baseFee,
+		}
+		if fees.GasFeeCap.Cmp(fees.GasTipCap) < 0 {
+			fees.GasFeeCap = new(big.Int).Set(fees.GasTipCap) // The cap must cover the tip
+		}
+		return fees
+	}
+
+	var marketPrice *big.Int
+	if sameType {
+		marketPrice = current.GasPrice
+	}
+	return &FeeParams{Type: TxTypeLegacy, GasPrice: bump(previous.GasPrice, marketPrice)}
+}
+
+// SuggestFees prices a transaction for the current network conditions. London chains
+// (whose latest header carries a base fee) get EIP-1559 fees with the tip taken from
+// eth_feeHistory; other chains get a legacy gas price.
+func (c *ChainClient) SuggestFees(ctx context.Context) (*FeeParams, error) {
+	head, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest header: %w", err)
+	}
+
+	if head.BaseFee == nil {
+		gasPrice, err := c.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gas price: %w", err)
+		}
+		return &FeeParams{Type: TxTypeLegacy, GasPrice: gasPrice}, nil
+	}
+
+	baseFee := head.BaseFee
+	var tip *big.Int
+	history, err := c.client.FeeHistory(ctx, feeHistoryBlocks, nil, []float64{feeHistoryPercentile})
+	if err != nil {
+		log.Printf("⚠️ eth_feeHistory failed, falling back to eth_maxPriorityFeePerGas: %v", err)
+	} else {
+		tip = medianReward(history.Reward)
+		if n := len(history.BaseFee); n > 0 && history.BaseFee[n-1] != nil {
+			baseFee = history.BaseFee[n-1] // Base fee of the block after the sampled range
+		}
+	}
+	if tip == nil {
+		if tip, err = c.client.SuggestGasTipCap(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get priority fee: %w", err)
+		}
+	}
+
+	feeCap := new(big.Int).Mul(baseFee, big.NewInt(baseFeeHeadroom))
+	feeCap.Add(feeCap, tip)
+
+	return &FeeParams{
+		Type:      TxTypeDynamic,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		BaseFee:   baseFee,
+	}, nil
+}
+
+// medianReward takes the median of the per-block priority fees, ignoring empty blocks
+func medianReward(rewards [][]*big.Int) *big.Int {
+	var tips []*big.Int
+	for _, block := range rewards {
+		if len(block) > 0 && block[0] != nil && block[0].Sign() > 0 {
+			tips = append(tips, block[0])
+		}
+	}
+	if len(tips) == 0 {
+		return nil
+	}
+	slices.SortFunc(tips, func(a, b *big.Int) int { return a.Cmp(b) })
+	return new(big.Int).Set(tips[len(tips)/2])
+}
+
+// newTransaction builds an unsigned transaction of the type the fees call for
+func newTransaction(chainID *big.Int, nonce uint64, to *common.Address, value *big.Int, gasLimit uint64, data []byte, fees *FeeParams) *ethtypes.Transaction {
+	if fees.Type == TxTypeDynamic {
+		return ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: fees.GasTipCap,
+			GasFeeCap: fees.GasFeeCap,
+			Gas:       gasLimit,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		})
+	}
+	return ethtypes.NewTx(&ethtypes.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: fees.GasPrice,
+		Gas:      gasLimit,
+		To:       to,
+		Value:    value,
+		Data:     data,
+	})
+}
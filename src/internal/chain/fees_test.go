@@ -0,0 +1,54 @@
+package chain_test
+
+import (
+	"math/big"
+	"testing"
+	"trustflow/src/internal/chain"
+	"trustflow/src/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBumpFees(t *testing.T) {
+	t.Run("Dynamic Bumps Past Replacement Minimum", func(t *testing.T) {
+		previous := &chain.FeeParams{Type: chain.TxTypeDynamic, GasTipCap: big.NewInt(100), GasFeeCap: big.NewInt(1000)}
+		current := &chain.FeeParams{Type: chain.TxTypeDynamic, GasTipCap: big.NewInt(90), GasFeeCap: big.NewInt(900)}
+
+		bumped := chain.BumpFees(previous, current)
+		assert.Equal(t, chain.TxTypeDynamic, bumped.Type)
+		assert.Equal(t, "125", bumped.GasTipCap.String())
+		assert.Equal(t, "1250", bumped.GasFeeCap.String())
+	})
+
+	t.Run("Market Above Bump Wins", func(t *testing.T) {
+		previous := &chain.FeeParams{Type: chain.TxTypeDynamic, GasTipCap: big.NewInt(100), GasFeeCap: big.NewInt(1000)}
+		current := &chain.FeeParams{Type: chain.TxTypeDynamic, GasTipCap: big.NewInt(300), GasFeeCap: big.NewInt(5000)}
+
+		bumped := chain.BumpFees(previous, current)
+		assert.Equal(t, "300", bumped.GasTipCap.String())
+		assert.Equal(t, "5000", bumped.GasFeeCap.String())
+	})
+
+	t.Run("Legacy Rounds Up", func(t *testing.T) {
+		previous := &chain.FeeParams{Type: chain.TxTypeLegacy, GasPrice: big.NewInt(1)}
+
+		bumped := chain.BumpFees(previous, nil)
+		assert.Equal(t, chain.TxTypeLegacy, bumped.Type)
+		assert.Equal(t, "2", bumped.GasPrice.String(), "a bump must always increase the price")
+	})
+}
+
+func TestFeeParamsFromDetails(t *testing.T) {
+	original := &chain.FeeParams{Type: chain.TxTypeDynamic, GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(250), BaseFee: big.NewInt(124)}
+
+	parsed, err := chain.FeeParamsFromDetails(original.Details())
+	require.NoError(t, err)
+	assert.Equal(t, original, parsed)
+
+	_, err = chain.FeeParamsFromDetails(&types.FeeParams{Type: chain.TxTypeDynamic, MaxFeePerGas: "250"})
+	assert.Error(t, err, "a dynamic fee without a tip cannot be bumped")
+
+	_, err = chain.FeeParamsFromDetails(&types.FeeParams{Type: chain.TxTypeLegacy, GasPrice: "abc"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceSource reports the next nonce the node expects from an address, counting pool transactions
+type NonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceStore persists the last nonce used by each signer so allocation survives restarts
+type NonceStore interface {
+	LoadNonce(address string) (nonce uint64, found bool, err error)
+	SaveNonce(address string, nonce uint64) error
+}
+
+// defaultGapTimeout is how long the node must stay at the same pending nonce below the local
+// one, with nothing in flight, before the local counter rewinds onto the gap
+const defaultGapTimeout = 2 * time.Minute
+
+// NonceManager hands out nonces serially per signer so concurrent sends from the
+// same wallet never collide. Nonces are allocated locally, moved forward to the
+// node's pending nonce when it is ahead, given back when a broadcast fails, and
+// rewound onto a gap the node has been stuck at for gapTimeout.
+type NonceManager struct {
+	source     NonceSource
+	store      NonceStore // Optional: nil keeps nonces in memory only
+	gapTimeout time.Duration
+
+	mu      sync.Mutex
+	signers map[common.Address]*signerNonces
+}
+
+// signerNonces is the allocation state of a single signer
+type signerNonces struct {
+	loaded   bool
+	next     uint64    // Next fresh nonce to hand out
+	released []uint64  // Nonces below next whose broadcast failed, reused first (sorted)
+	inFlight int       // Allocated nonces not yet confirmed broadcast or released
+	gapAt    uint64    // Pending nonce the node was last seen stuck at below next
+	gapSince time.Time // When it was first seen there; zero while there is no gap
+}
+
+// NewNonceManager creates a nonce manager backed by the node and an optional store
+func NewNonceManager(source NonceSource, store NonceStore) *NonceManager {
+	return &NonceManager{
+		source:     source,
+		store:      store,
+		gapTimeout: defaultGapTimeout,
+		signers:    make(map[common.Address]*signerNonces),
+	}
+}
+
+// SetGapTimeout sets how long the node must stay below the local nonce, with nothing in
+// flight, before the local counter rewinds onto the gap (default 2m)
+func (m *NonceManager) SetGapTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gapTimeout = timeout
+}
+
+// Acquire allocates the next nonce for address. Every acquired nonce must be
+// followed by exactly one Commit or Release.
+func (m *NonceManager) Acquire(ctx context.Context, address common.Address) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, err := m.state(address)
+	if err != nil {
+		return 0, err
+	}
+
+	// Reuse the lowest released nonce first: later transactions are stuck behind the gap it left
+	if len(state.released) > 0 {
+		nonce := state.released[0]
+		state.released = state.released[1:]
+		state.inFlight++
+		return nonce, nil
+	}
+
+	pending, err := m.source.PendingNonceAt(ctx, address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	switch {
+	case pending > state.next:
+		// Transactions were sent from this wallet outside of this manager
+		log.Printf("🔢 Nonce resync for %s: node is at %d, local at %d", address.Hex(), pending, state.next)
+		state.next = pending
+		state.gapSince = time.Time{}
+	case pending < state.next && state.inFlight == 0:
+		// A lagging or load-balanced node often reports a pending nonce below what was already
+		// broadcast, and rewinding onto it at once would replace a live transaction. A node that
+		// stays put is missing nonces for good: released before a restart forgot them, or never
+		// broadcast. Every later transaction is stuck behind that gap, so it is filled.
+		switch {
+		case state.gapSince.IsZero() || state.gapAt != pending:
+			state.gapAt, state.gapSince = pending, time.Now()
+		case time.Since(state.gapSince) >= m.gapTimeout:
+			log.Printf("🔢 Nonce gap for %s: node stuck at %d since %s, local at %d; rewinding", address.Hex(), pending, state.gapSince.Format(time.RFC3339), state.next)
+			state.next = pending
+			state.gapSince = time.Time{}
+		}
+	case pending == state.next:
+		state.gapSince = time.Time{}
+	}
+
+	nonce := state.next
+	state.next++
+	state.inFlight++
+	m.persist(address, nonce)
+	return nonce, nil
+}
+
+// Commit records that the transaction carrying nonce was accepted by the node
+func (m *NonceManager) Commit(address common.Address, nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.signers[address]; ok && state.inFlight > 0 {
+		state.inFlight--
+	}
+}
+
+// Release gives back a nonce whose transaction never reached the node, so it is reused
+func (m *NonceManager) Release(address common.Address, nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.signers[address]
+	if !ok {
+		return
+	}
+	if state.inFlight > 0 {
+		state.inFlight--
+	}
+
+	// Released nonces are kept in memory only: one forgotten by a restart, or nonce 0 which
+	// leaves nothing to store, is a gap that Acquire fills once the node stays below it
+	if nonce+1 == state.next {
+		state.next = nonce
+		if nonce > 0 {
+			m.persist(address, nonce-1)
+		}
+		return
+	}
+	if nonce < state.next && !slices.Contains(state.released, nonce) {
+		state.released = append(state.released, nonce)
+		slices.Sort(state.released)
+	}
+}
+
+// Reset drops the local view of address so the next Acquire starts over from the node.
+// Used when the node rejects a nonce outright.
+func (m *NonceManager) Reset(address common.Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.signers[address]; ok {
+		state.next = 0
+		state.released = nil
+		state.gapSince = time.Time{}
+	}
+}
+
+// state returns the allocation state of address, loading the last used nonce on first use
+func (m *NonceManager) state(address common.Address) (*signerNonces, error) {
+	state, ok := m.signers[address]
+	if !ok {
+		state = &signerNonces{}
+		m.signers[address] = state
+	}
+	if state.loaded || m.store == nil {
+		state.loaded = true
+		return state, nil
+	}
+
+	last, found, err := m.store.LoadNonce(address.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load nonce: %w", err)
+	}
+	if found {
+		state.next = last + 1
+	}
+	state.loaded = true
+	return state, nil
+}
+
+// persist saves the last used nonce; failures only cost a resync with the node
+func (m *NonceManager) persist(address common.Address, last uint64) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.SaveNonce(address.Hex(), last); err != nil {
+		log.Printf("⚠️ Failed to persist nonce %d for %s: %v", last, address.Hex(), err)
+	}
+}
+
+// isNonceError reports whether the node rejected a transaction because of its nonce
+func isNonceError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") || strings.Contains(msg, "nonce too high")
+}
+
+// isUnderpriced reports whether the node kept another pending transaction at the nonce because
+// ours did not pay enough more to replace it: a fee error, the nonce itself is taken
+func isUnderpriced(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "replacement transaction underpriced")
+}
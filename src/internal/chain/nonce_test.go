@@ -0,0 +1,192 @@
+package chain_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"trustflow/src/internal/chain"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var signer = common.HexToAddress("0x71C7656EC7ab88b098defB751B7401B5f6d8976F")
+
+// fakeSource plays the node's pending nonce
+type fakeSource struct {
+	mu      sync.Mutex
+	pending uint64
+}
+
+func (f *fakeSource) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pending, nil
+}
+
+func (f *fakeSource) set(n uint64) {
+	f.mu.Lock()
+	f.pending = n
+	f.mu.Unlock()
+}
+
+// memStore is an in-memory NonceStore
+type memStore map[string]uint64
+
+func (m memStore) LoadNonce(address string) (uint64, bool, error) {
+	n, ok := m[address]
+	return n, ok, nil
+}
+
+func (m memStore) SaveNonce(address string, nonce uint64) error {
+	m[address] = nonce
+	return nil
+}
+
+func TestNonceManager(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Concurrent Acquire Is Unique And Sequential", func(t *testing.T) {
+		nm := chain.NewNonceManager(&fakeSource{pending: 7}, nil)
+
+		const n = 50
+		nonces := make(chan uint64, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				nonce, err := nm.Acquire(ctx, signer)
+				assert.NoError(t, err)
+				nonces <- nonce
+			}()
+		}
+		wg.Wait()
+		close(nonces)
+
+		seen := make(map[uint64]bool)
+		for nonce := range nonces {
+			assert.False(t, seen[nonce], "nonce %d handed out twice", nonce)
+			seen[nonce] = true
+		}
+		for nonce := uint64(7); nonce < 7+n; nonce++ {
+			assert.True(t, seen[nonce], "nonce %d skipped", nonce)
+		}
+	})
+
+	t.Run("Released Nonce Is Reused", func(t *testing.T) {
+		nm := chain.NewNonceManager(&fakeSource{}, nil)
+
+		first, _ := nm.Acquire(ctx, signer)
+		second, _ := nm.Acquire(ctx, signer)
+		third, _ := nm.Acquire(ctx, signer)
+		require.Equal(t, []uint64{0, 1, 2}, []uint64{first, second, third})
+
+		// A failed broadcast in the middle leaves a gap that must be filled first
+		nm.Release(signer, second)
+		nm.Commit(signer, first)
+		nm.Commit(signer, third)
+
+		next, err := nm.Acquire(ctx, signer)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), next)
+	})
+
+	t.Run("Resyncs With Node", func(t *testing.T) {
+		source := &fakeSource{pending: 3}
+		nm := chain.NewNonceManager(source, nil)
+
+		nonce, _ := nm.Acquire(ctx, signer)
+		require.Equal(t, uint64(3), nonce)
+		nm.Commit(signer, nonce)
+
+		// Someone else sent from the same wallet
+		source.set(10)
+		nonce, _ = nm.Acquire(ctx, signer)
+		assert.Equal(t, uint64(10), nonce)
+		nm.Commit(signer, nonce)
+
+		// A stale node still at 10 never rewinds the counter onto a broadcast nonce
+		nonce, _ = nm.Acquire(ctx, signer)
+		assert.Equal(t, uint64(11), nonce)
+	})
+
+	t.Run("Stale Pending Nonce Does Not Override Persisted Nonce", func(t *testing.T) {
+		// A load-balanced backend lags behind the transactions this wallet already broadcast
+		store := memStore{signer.Hex(): 20}
+		source := &fakeSource{pending: 12}
+		nm := chain.NewNonceManager(source, store)
+
+		for want := uint64(21); want < 24; want++ {
+			nonce, err := nm.Acquire(ctx, signer)
+			require.NoError(t, err)
+			assert.Equal(t, want, nonce)
+			nm.Commit(signer, nonce)
+		}
+		assert.Equal(t, uint64(23), store[signer.Hex()])
+	})
+
+	t.Run("Fills Gap Node Stays At", func(t *testing.T) {
+		// Nonce 0 was released before a restart: the store says 0 was used, the node never saw it
+		store := memStore{}
+		nm := chain.NewNonceManager(&fakeSource{}, store)
+		nonce, _ := nm.Acquire(ctx, signer)
+		nm.Release(signer, nonce)
+
+		source := &fakeSource{}
+		restarted := chain.NewNonceManager(source, store)
+		restarted.SetGapTimeout(50 * time.Millisecond)
+		nonce, _ = restarted.Acquire(ctx, signer)
+		require.Equal(t, uint64(1), nonce, "a node below the local nonce is not trusted at once")
+		restarted.Commit(signer, nonce)
+
+		// Still stuck, but not for long enough
+		nonce, _ = restarted.Acquire(ctx, signer)
+		require.Equal(t, uint64(2), nonce)
+		restarted.Commit(signer, nonce)
+
+		time.Sleep(60 * time.Millisecond)
+		nonce, _ = restarted.Acquire(ctx, signer)
+		assert.Equal(t, uint64(0), nonce, "the gap is filled")
+		restarted.Commit(signer, nonce)
+
+		// The node then takes the queued transactions behind it
+		source.set(3)
+		nonce, _ = restarted.Acquire(ctx, signer)
+		assert.Equal(t, uint64(3), nonce)
+	})
+
+	t.Run("Moving Node Is Not A Gap", func(t *testing.T) {
+		source := &fakeSource{}
+		nm := chain.NewNonceManager(source, memStore{signer.Hex(): 9})
+		nm.SetGapTimeout(50 * time.Millisecond)
+
+		for _, pending := range []uint64{4, 5, 6} {
+			source.set(pending)
+			nonce, _ := nm.Acquire(ctx, signer)
+			nm.Commit(signer, nonce)
+			assert.Greater(t, nonce, uint64(9), "a node catching up is not rewound onto")
+			time.Sleep(30 * time.Millisecond)
+		}
+	})
+
+	t.Run("Persists Last Used Nonce", func(t *testing.T) {
+		store := memStore{}
+		nm := chain.NewNonceManager(&fakeSource{pending: 4}, store)
+
+		nonce, _ := nm.Acquire(ctx, signer)
+		assert.Equal(t, uint64(4), store[signer.Hex()])
+		nm.Release(signer, nonce)
+		assert.Equal(t, uint64(3), store[signer.Hex()])
+
+		// A restarted manager continues after the stored nonce
+		nonce, _ = nm.Acquire(ctx, signer)
+		nm.Commit(signer, nonce)
+		restarted := chain.NewNonceManager(&fakeSource{pending: 5}, store)
+		nonce, _ = restarted.Acquire(ctx, signer)
+		assert.Equal(t, uint64(5), nonce)
+		assert.Equal(t, uint64(5), store[signer.Hex()])
+	})
+}
@@ -0,0 +1,201 @@
+package chain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"trustflow/src/internal/config"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Signer holds the account transactions are sent from and signs for it
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// NewSigner picks the signing backend from the config: a remote signer, an encrypted
+// keystore, or a raw hex key, in that order of preference
+func NewSigner(cfg *config.Config) (Signer, error) {
+	switch {
+	case cfg.RemoteSignerURL != "":
+		if !common.IsHexAddress(cfg.RemoteSignerAddress) {
+			return nil, errors.New("REMOTE_SIGNER_ADDRESS must be set to the remote signer's account")
+		}
+		return NewRemoteSigner(cfg.RemoteSignerURL, common.HexToAddress(cfg.RemoteSignerAddress))
+	case cfg.KeystoreFile != "":
+		return NewKeystoreSigner(cfg.KeystoreFile, cfg.KeystorePassphraseFile)
+	case cfg.PrivateKey != "":
+		return NewKeySigner(cfg.PrivateKey)
+	default:
+		return nil, errors.New("no signer configured")
+	}
+}
+
+// KeySigner signs locally with an in-memory private key
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner parses a hex private key, with or without the 0x prefix
+func NewKeySigner(hexKey string) (*KeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return NewKeySignerFromKey(key), nil
+}
+
+// NewKeystoreSigner decrypts a go-ethereum JSON keystore file with the passphrase read from
+// passphraseFile. Surrounding whitespace in the passphrase file is ignored.
+func NewKeystoreSigner(keyFile, passphraseFile string) (*KeySigner, error) {
+	keyJSON, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+	if passphraseFile == "" {
+		return nil, errors.New("keystore requires a passphrase file")
+	}
+	passphrase, err := os.ReadFile(passphraseFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, strings.TrimSpace(string(passphrase)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+	}
+	return NewKeySignerFromKey(key.PrivateKey), nil
+}
+
+// NewKeySignerFromKey wraps an already loaded private key
+func NewKeySignerFromKey(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// Address returns the account the key signs for
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs tx for chainID
+func (s *KeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// RemoteSigner delegates signing to an external service (e.g. Clef, an HSM or KMS bridge)
+// that answers eth_signTransaction over HTTP JSON-RPC. The key never enters this process.
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// NewRemoteSigner connects to a signer that holds the key for address
+func NewRemoteSigner(url string, address common.Address) (*RemoteSigner, error) {
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %w", err)
+	}
+	return &RemoteSigner{client: client, address: address}, nil
+}
+
+// Address returns the account the remote signer signs for
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// signTxArgs is the eth_signTransaction request object
+type signTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// SignTx asks the remote signer to sign tx and checks that the result is tx, signed by our account
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := signTxArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	var result json.RawMessage
+	if err := s.client.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("remote signer: %w", err)
+	}
+	raw, err := rawSignedTx(result)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid transaction: %w", err)
+	}
+
+	// Never broadcast something other than what we asked to have signed
+	ethSigner := types.LatestSignerForChainID(chainID)
+	if ethSigner.Hash(signed) != ethSigner.Hash(tx) {
+		return nil, errors.New("remote signer returned a different transaction")
+	}
+	sender, err := types.Sender(ethSigner, signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid signature: %w", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("remote signer signed as %s, expected %s", sender.Hex(), s.address.Hex())
+	}
+	return signed, nil
+}
+
+// Close closes the connection to the remote signer
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// rawSignedTx accepts both eth_signTransaction result shapes: a bare raw transaction
+// (hex string) and geth's {"raw": ..., "tx": ...} object
+func rawSignedTx(result json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+
+	var wrapped struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &wrapped); err != nil || len(wrapped.Raw) == 0 {
+		return nil, errors.New("remote signer returned no raw transaction")
+	}
+	return wrapped.Raw, nil
+}
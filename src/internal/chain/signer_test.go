@@ -0,0 +1,170 @@
+package chain_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"trustflow/src/internal/chain"
+	"trustflow/src/internal/config"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var chainID = big.NewInt(338)
+
+func unsignedTx() *types.Transaction {
+	to := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID: chainID, Nonce: 3, GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(250),
+		Gas: 21000, To: &to, Value: big.NewInt(1000),
+	})
+}
+
+func assertSignedBy(t *testing.T, signed *types.Transaction, want common.Address) {
+	t.Helper()
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	assert.Equal(t, want, sender)
+}
+
+func TestKeySigner(t *testing.T) {
+	signer, err := chain.NewKeySigner("0x" + testPrivateKey)
+	require.NoError(t, err)
+
+	key, _ := crypto.HexToECDSA(testPrivateKey)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer.Address())
+
+	signed, err := signer.SignTx(context.Background(), unsignedTx(), chainID)
+	require.NoError(t, err)
+	assertSignedBy(t, signed, signer.Address())
+
+	_, err = chain.NewKeySigner("not-a-key")
+	assert.Error(t, err)
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	dir := t.TempDir()
+	key, err := crypto.HexToECDSA(testPrivateKey)
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{Id: uuid.New(), Address: address, PrivateKey: key},
+		"correct horse", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "key.json")
+	require.NoError(t, os.WriteFile(keyFile, keyJSON, 0o600))
+
+	passFile := filepath.Join(dir, "pass")
+	require.NoError(t, os.WriteFile(passFile, []byte("correct horse\n"), 0o600))
+
+	signer, err := chain.NewSigner(&config.Config{KeystoreFile: keyFile, KeystorePassphraseFile: passFile})
+	require.NoError(t, err)
+	assert.Equal(t, address, signer.Address())
+
+	signed, err := signer.SignTx(context.Background(), unsignedTx(), chainID)
+	require.NoError(t, err)
+	assertSignedBy(t, signed, address)
+
+	t.Run("Wrong Passphrase", func(t *testing.T) {
+		wrong := filepath.Join(dir, "wrong")
+		require.NoError(t, os.WriteFile(wrong, []byte("battery staple"), 0o600))
+		_, err := chain.NewKeystoreSigner(keyFile, wrong)
+		assert.Error(t, err)
+	})
+}
+
+// signerStub is a remote signer that signs with key, after letting tamper alter the transaction
+func signerStub(t *testing.T, keyHex string, tamper func(*types.DynamicFeeTx)) *httptest.Server {
+	t.Helper()
+	key, err := crypto.HexToECDSA(keyHex)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []struct {
+				To                   *common.Address `json:"to"`
+				Gas                  hexutil.Uint64  `json:"gas"`
+				MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+				MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+				Value                *hexutil.Big    `json:"value"`
+				Nonce                hexutil.Uint64  `json:"nonce"`
+				Data                 hexutil.Bytes   `json:"data"`
+				ChainID              *hexutil.Big    `json:"chainId"`
+			} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "eth_signTransaction", req.Method)
+		args := req.Params[0]
+
+		inner := &types.DynamicFeeTx{
+			ChainID: args.ChainID.ToInt(), Nonce: uint64(args.Nonce), Gas: uint64(args.Gas), To: args.To,
+			GasTipCap: args.MaxPriorityFeePerGas.ToInt(), GasFeeCap: args.MaxFeePerGas.ToInt(),
+			Value: args.Value.ToInt(), Data: args.Data,
+		}
+		if tamper != nil {
+			tamper(inner)
+		}
+		signed, err := types.SignNewTx(key, types.LatestSignerForChainID(inner.ChainID), inner)
+		require.NoError(t, err)
+		raw, _ := signed.MarshalBinary()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": req.ID,
+			"result": map[string]interface{}{"raw": hexutil.Encode(raw), "tx": signed},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, _ := crypto.HexToECDSA(testPrivateKey)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	t.Run("Signs Via eth_signTransaction", func(t *testing.T) {
+		server := signerStub(t, testPrivateKey, nil)
+		signer, err := chain.NewSigner(&config.Config{RemoteSignerURL: server.URL, RemoteSignerAddress: address.Hex()})
+		require.NoError(t, err)
+
+		tx := unsignedTx()
+		signed, err := signer.SignTx(context.Background(), tx, chainID)
+		require.NoError(t, err)
+		assertSignedBy(t, signed, address)
+		assert.Equal(t, tx.Nonce(), signed.Nonce())
+		assert.Equal(t, tx.Value(), signed.Value())
+	})
+
+	t.Run("Rejects Altered Transaction", func(t *testing.T) {
+		server := signerStub(t, testPrivateKey, func(tx *types.DynamicFeeTx) { tx.Value = big.NewInt(1e18) })
+		signer, err := chain.NewRemoteSigner(server.URL, address)
+		require.NoError(t, err)
+
+		_, err = signer.SignTx(context.Background(), unsignedTx(), chainID)
+		assert.ErrorContains(t, err, "different transaction")
+	})
+
+	t.Run("Rejects Wrong Account", func(t *testing.T) {
+		other := "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+		server := signerStub(t, other, nil)
+		signer, err := chain.NewRemoteSigner(server.URL, address)
+		require.NoError(t, err)
+
+		_, err = signer.SignTx(context.Background(), unsignedTx(), chainID)
+		assert.ErrorContains(t, err, "expected "+address.Hex())
+	})
+}
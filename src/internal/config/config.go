@@ -1,13 +1,58 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	RPCURL     string
-	PrivateKey string
+	PrivateKey string // Hex key; development only, prefer a keystore or remote signer
+
+	KeystoreFile           string // go-ethereum encrypted JSON key file
+	KeystorePassphraseFile string // File holding the keystore passphrase
+	RemoteSignerURL        string // JSON-RPC endpoint answering eth_signTransaction
+	RemoteSignerAddress    string // Account the remote signer signs for
+
+	HDMnemonicFile   string // Optional BIP-39 mnemonic; each user then gets their own derived agent wallet
+	HDPassphraseFile string // Optional BIP-39 passphrase for HDMnemonicFile
+
+	PolicyFile   string // Optional path to a YAML/JSON policy rule set
+	ApprovalFile string // Optional path to YAML/JSON human approval thresholds and approvers
+
+	BudgetLimit  string        // Optional per-user spend cap in Wei over BudgetWindow
+	BudgetWindow time.Duration // Sliding window for BudgetLimit (default 24h)
+
+	Workers         int           // Number of background intent workers (default 4)
+	ShutdownTimeout time.Duration // How long shutdown waits for workers to finish their intents (default 30s)
+
+	Confirmations  uint64        // Blocks a receipt must be buried under before a step succeeds (default 1)
+	ReceiptTimeout time.Duration // How long to wait for a receipt before giving up (default 2m)
+
+	StuckTxAfter time.Duration // Unmined time after which a step's transaction is sped up (default 45s)
+	MaxSpeedups  int           // Automatic speed-ups per step; 0 disables the stuck-tx watcher (default 3)
+
+	WebhookMaxAttempts  int           // Delivery attempts before a webhook event is given up (default 8)
+	WebhookRetryBase    time.Duration // Delay before the first retry, doubling after each failure (default 30s)
+	WebhookAllowPrivate bool          // Development only: let webhooks reach loopback and private addresses
+
+	AuthDomain      string        // Domain sign-in messages must name; required unless AllowHeaderAuth
+	SessionTTL      time.Duration // Lifetime of a sign-in session (default 24h)
+	AllowHeaderAuth bool          // Development only: trust an unauthenticated X-User-Address header
+	AdminAddresses  []string      // Signed-in addresses allowed to manage API keys (checksummed)
+	TrustedProxies  []string      // Proxy IPs/CIDRs whose X-Forwarded-For names the client; none by default
+
+	RequireSignedIntents bool // Reject intents without an EIP-712 signature by the submitting address
+
+	AnchorInterval time.Duration // How often new audit events are anchored on-chain; 0 disables anchoring
+	AnchorContract string        // Optional contract receiving anchor(bytes32,uint256,uint256) calls (checksummed)
 }
 
 func LoadConfig() (*Config, error) {
@@ -19,13 +64,217 @@ func LoadConfig() (*Config, error) {
 		return nil, os.ErrNotExist // Simplified error for missing env
 	}
 
+	// Exactly one signing backend is used: remote signer, then keystore, then raw key
 	privateKey := os.Getenv("PRIVATE_KEY")
-	if privateKey == "" {
+	keystoreFile := os.Getenv("KEYSTORE_FILE")
+	remoteSignerURL := os.Getenv("REMOTE_SIGNER_URL")
+	if privateKey == "" && keystoreFile == "" && remoteSignerURL == "" {
 		return nil, os.ErrNotExist
 	}
 
+	budgetWindow := 24 * time.Hour
+	if raw := os.Getenv("BUDGET_WINDOW"); raw != "" {
+		window, err := time.ParseDuration(raw)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid BUDGET_WINDOW: %s", raw)
+		}
+		budgetWindow = window
+	}
+
+	workers := 4
+	if raw := os.Getenv("WORKER_COUNT"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid WORKER_COUNT: %s", raw)
+		}
+		workers = n
+	}
+
+	shutdownTimeout := 30 * time.Second
+	if raw := os.Getenv("SHUTDOWN_TIMEOUT"); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %s", raw)
+		}
+		shutdownTimeout = timeout
+	}
+
+	confirmations := uint64(1)
+	if raw := os.Getenv("CONFIRMATIONS"); raw != "" {
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid CONFIRMATIONS: %s", raw)
+		}
+		confirmations = n
+	}
+
+	receiptTimeout := 2 * time.Minute
+	if raw := os.Getenv("RECEIPT_TIMEOUT"); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid RECEIPT_TIMEOUT: %s", raw)
+		}
+		receiptTimeout = timeout
+	}
+
+	stuckTxAfter := 45 * time.Second
+	if raw := os.Getenv("STUCK_TX_AFTER"); raw != "" {
+		after, err := time.ParseDuration(raw)
+		if err != nil || after <= 0 {
+			return nil, fmt.Errorf("invalid STUCK_TX_AFTER: %s", raw)
+		}
+		stuckTxAfter = after
+	}
+
+	maxSpeedups := 3
+	if raw := os.Getenv("STUCK_TX_MAX_SPEEDUPS"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid STUCK_TX_MAX_SPEEDUPS: %s", raw)
+		}
+		maxSpeedups = n
+	}
+
+	webhookMaxAttempts := 8
+	if raw := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: %s", raw)
+		}
+		webhookMaxAttempts = n
+	}
+
+	webhookRetryBase := 30 * time.Second
+	if raw := os.Getenv("WEBHOOK_RETRY_BASE"); raw != "" {
+		base, err := time.ParseDuration(raw)
+		if err != nil || base <= 0 {
+			return nil, fmt.Errorf("invalid WEBHOOK_RETRY_BASE: %s", raw)
+		}
+		webhookRetryBase = base
+	}
+
+	webhookAllowPrivate := false
+	if raw := os.Getenv("WEBHOOK_ALLOW_PRIVATE"); raw != "" {
+		allow, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WEBHOOK_ALLOW_PRIVATE: %s", raw)
+		}
+		webhookAllowPrivate = allow
+	}
+
+	sessionTTL := 24 * time.Hour
+	if raw := os.Getenv("SESSION_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid SESSION_TTL: %s", raw)
+		}
+		sessionTTL = ttl
+	}
+
+	allowHeaderAuth := false
+	if raw := os.Getenv("ALLOW_HEADER_AUTH"); raw != "" {
+		allow, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALLOW_HEADER_AUTH: %s", raw)
+		}
+		allowHeaderAuth = allow
+	}
+
+	// A Host-derived domain would let any site that can reach the server phish sign-ins for it
+	authDomain := os.Getenv("AUTH_DOMAIN")
+	if authDomain == "" && !allowHeaderAuth {
+		return nil, fmt.Errorf("AUTH_DOMAIN is required unless ALLOW_HEADER_AUTH is set")
+	}
+
+	requireSignedIntents := false
+	if raw := os.Getenv("REQUIRE_SIGNED_INTENTS"); raw != "" {
+		require, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REQUIRE_SIGNED_INTENTS: %s", raw)
+		}
+		requireSignedIntents = require
+	}
+
+	var adminAddresses []string
+	for _, raw := range strings.Split(os.Getenv("ADMIN_ADDRESSES"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		if !common.IsHexAddress(raw) {
+			return nil, fmt.Errorf("invalid ADMIN_ADDRESSES entry: %s", raw)
+		}
+		adminAddresses = append(adminAddresses, common.HexToAddress(raw).Hex())
+	}
+
+	var trustedProxies []string
+	for _, raw := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		if net.ParseIP(raw) == nil {
+			if _, _, err := net.ParseCIDR(raw); err != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry: %s", raw)
+			}
+		}
+		trustedProxies = append(trustedProxies, raw)
+	}
+
+	var anchorInterval time.Duration
+	if raw := os.Getenv("ANCHOR_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < 0 {
+			return nil, fmt.Errorf("invalid ANCHOR_INTERVAL: %s", raw)
+		}
+		anchorInterval = interval
+	}
+
+	var anchorContract string
+	if raw := strings.TrimSpace(os.Getenv("ANCHOR_CONTRACT")); raw != "" {
+		if !common.IsHexAddress(raw) {
+			return nil, fmt.Errorf("invalid ANCHOR_CONTRACT: %s", raw)
+		}
+		anchorContract = common.HexToAddress(raw).Hex()
+	}
+
 	return &Config{
 		RPCURL:     rpcURL,
 		PrivateKey: privateKey,
+
+		KeystoreFile:           keystoreFile,
+		KeystorePassphraseFile: os.Getenv("KEYSTORE_PASSPHRASE_FILE"),
+		RemoteSignerURL:        remoteSignerURL,
+		RemoteSignerAddress:    os.Getenv("REMOTE_SIGNER_ADDRESS"),
+
+		HDMnemonicFile:   os.Getenv("HD_MNEMONIC_FILE"),
+		HDPassphraseFile: os.Getenv("HD_PASSPHRASE_FILE"),
+
+		PolicyFile:   os.Getenv("POLICY_FILE"),
+		ApprovalFile: os.Getenv("APPROVAL_FILE"),
+		BudgetLimit:  os.Getenv("BUDGET_LIMIT_WEI"),
+		BudgetWindow: budgetWindow,
+		Workers:      workers,
+
+		ShutdownTimeout: shutdownTimeout,
+
+		Confirmations:  confirmations,
+		ReceiptTimeout: receiptTimeout,
+
+		StuckTxAfter: stuckTxAfter,
+		MaxSpeedups:  maxSpeedups,
+
+		WebhookMaxAttempts:  webhookMaxAttempts,
+		WebhookRetryBase:    webhookRetryBase,
+		WebhookAllowPrivate: webhookAllowPrivate,
+
+		AuthDomain:      authDomain,
+		SessionTTL:      sessionTTL,
+		AllowHeaderAuth: allowHeaderAuth,
+		AdminAddresses:  adminAddresses,
+		TrustedProxies:  trustedProxies,
+
+		RequireSignedIntents: requireSignedIntents,
+
+		AnchorInterval: anchorInterval,
+		AnchorContract: anchorContract,
 	}, nil
 }
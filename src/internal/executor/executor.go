@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"trustflow/src/internal/chain"
 	"trustflow/src/internal/simulator"
+
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type Executor struct {
@@ -17,17 +19,71 @@ func NewExecutor(client *chain.ChainClient) *Executor {
 
 // Execute signs and broadcasts the transaction candidate
 func (e *Executor) Execute(ctx context.Context, candidate *simulator.TxCandidate, gasLimit uint64) (string, error) {
-	// Call the ChainClient's SendTransaction method
-	txHash, err := e.client.SendTransaction(
+	sent, err := e.Send(ctx, candidate, gasLimit, nil)
+	if err != nil {
+		return "", err
+	}
+	return sent.Hash, nil
+}
+
+// Send signs and broadcasts the transaction candidate, reporting its nonce and fees.
+// journal, if set, records the signed transaction before it is broadcast.
+func (e *Executor) Send(ctx context.Context, candidate *simulator.TxCandidate, gasLimit uint64, journal func(*chain.SentTx) error) (*chain.SentTx, error) {
+	// Call the ChainClient's SendJournaledTransaction method
+	sent, err := e.client.SendJournaledTransaction(
 		ctx,
 		candidate.ToAddress,
 		candidate.Value,
 		candidate.Data,
 		gasLimit,
+		journal,
 	)
 	if err != nil {
-		return "", fmt.Errorf("execution failed: %w", err)
+		return nil, fmt.Errorf("execution failed: %w", err)
+	}
+
+	return sent, nil
+}
+
+// Replace rebroadcasts a stuck transaction at its nonce with fees bumped past previous
+func (e *Executor) Replace(ctx context.Context, nonce uint64, candidate *simulator.TxCandidate, gasLimit uint64, previous *chain.FeeParams) (*chain.SentTx, error) {
+	sent, err := e.client.ReplaceTransaction(ctx, nonce, candidate.ToAddress, candidate.Value, candidate.Data, gasLimit, previous)
+	if err != nil {
+		return nil, fmt.Errorf("speed-up failed: %w", err)
 	}
+	return sent, nil
+}
+
+// Cancel replaces a stuck transaction with a zero-value transfer to self at its nonce
+func (e *Executor) Cancel(ctx context.Context, nonce uint64, previous *chain.FeeParams) (*chain.SentTx, error) {
+	sent, err := e.client.CancelTransaction(ctx, nonce, previous)
+	if err != nil {
+		return nil, fmt.Errorf("cancel failed: %w", err)
+	}
+	return sent, nil
+}
+
+// Rebroadcast sends an already signed transaction again, e.g. one journaled before a restart
+func (e *Executor) Rebroadcast(ctx context.Context, raw []byte) error {
+	return e.client.SendRawTransaction(ctx, raw)
+}
+
+// MinedNonce returns the wallet's nonce as of the latest block
+func (e *Executor) MinedNonce(ctx context.Context) (uint64, error) {
+	return e.client.MinedNonce(ctx)
+}
+
+// WaitForReceipt blocks until the broadcast transaction is mined and confirmed
+func (e *Executor) WaitForReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	return e.client.WaitForReceipt(ctx, txHash)
+}
+
+// WaitForAnyReceipt blocks until one of the transactions broadcast at a nonce is mined and confirmed
+func (e *Executor) WaitForAnyReceipt(ctx context.Context, hashes func() []string) (*types.Receipt, error) {
+	return e.client.WaitForAnyReceipt(ctx, hashes)
+}
 
-	return txHash, nil
+// FindReceipt returns the receipt of whichever of the hashes has been mined, if any
+func (e *Executor) FindReceipt(ctx context.Context, hashes []string) (*types.Receipt, error) {
+	return e.client.FindReceipt(ctx, hashes)
 }
@@ -0,0 +1,154 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"trustflow/src/internal/approval"
+	"trustflow/src/internal/simulator"
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// approvalExpiryInterval is how often intents past their approval deadline are expired
+const approvalExpiryInterval = 30 * time.Second
+
+var (
+	// ErrApprovalNotFound is returned when an intent does not exist or never needed approval
+	ErrApprovalNotFound = errors.New("intent not found or not subject to approval")
+	// ErrNotApprover is returned when the caller is not one of the intent's approvers
+	ErrNotApprover = errors.New("caller is not an approver of this intent")
+	// ErrApprovalClosed is returned when the intent is no longer awaiting approval
+	ErrApprovalClosed = storage.ErrApprovalClosed
+	// ErrAlreadyDecided is returned when the approver already approved or rejected the intent
+	ErrAlreadyDecided = storage.ErrAlreadyDecided
+)
+
+// requireApproval parks a workflow that exceeds the approval gate until enough approvers agree.
+// It returns the response to stop with, or nil when the workflow may be executed.
+func (o *Orchestrator) requireApproval(intentID, userID string, steps []types.IntentStep, candidates []*simulator.TxCandidate) *types.IntentResponse {
+	requirement, violation := o.approvals.Evaluate(userID, steps, candidates)
+	if violation != nil {
+		return o.blockStep(intentID, userID, 0, nil, violation)
+	}
+	if requirement == nil {
+		return nil
+	}
+
+	existing, _, err := o.store.GetApproval(intentID)
+	if err != nil {
+		return o.failStep(intentID, userID, 0, nil, fmt.Errorf("approval check failed: %w", err))
+	}
+	if existing != nil && existing.ApprovedAt != 0 {
+		log.Printf("✅ Intent %s approved by %d of %d approvers, resuming", intentID, existing.Required, len(existing.Approvers))
+		return nil
+	}
+
+	state := requirement.State(time.Now())
+	if err := o.store.RequestApproval(intentID, userID, state); err != nil {
+		return o.failStep(intentID, userID, 0, nil, fmt.Errorf("approval request failed: %w", err))
+	}
+	if existing != nil {
+		state = existing // Still waiting on the original request
+	}
+	log.Printf("✋ Intent %s awaiting %d of %d approvals: %s", intentID, state.Required, len(state.Approvers), state.Reason)
+
+	return &types.IntentResponse{
+		Status:   "awaiting_approval",
+		IntentID: intentID,
+		Message:  fmt.Sprintf("Awaiting %d of %d approvals until %s: %s", state.Required, len(state.Approvers), time.Unix(state.ExpiresAt, 0).UTC().Format(time.RFC3339), state.Reason),
+		Approval: state,
+	}
+}
+
+// ApproveIntent records an approver's approval. The approval that completes the quorum
+// puts the intent back in the queue, where a worker re-checks and executes it.
+func (o *Orchestrator) ApproveIntent(approver string, intentID string, comment string) (*types.IntentResponse, error) {
+	return o.decide(approver, intentID, storage.DecisionApprove, comment)
+}
+
+// RejectIntent records an approver's rejection, which ends the intent
+func (o *Orchestrator) RejectIntent(approver string, intentID string, comment string) (*types.IntentResponse, error) {
+	return o.decide(approver, intentID, storage.DecisionReject, comment)
+}
+
+func (o *Orchestrator) decide(approver string, intentID string, decision string, comment string) (*types.IntentResponse, error) {
+	state, _, err := o.store.GetApproval(intentID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ErrApprovalNotFound
+	}
+	if !approval.IsApprover(state, approver) {
+		return nil, ErrNotApprover
+	}
+
+	status, err := o.store.DecideApproval(intentID, common.HexToAddress(approver).Hex(), decision, comment, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	if status == "pending" {
+		log.Printf("▶️ Intent %s approved, queued for execution", intentID)
+		o.wakeWorker()
+	}
+
+	state, _, err = o.store.GetApproval(intentID)
+	if err != nil {
+		return nil, err
+	}
+	approvals := 0
+	for _, d := range state.Decisions {
+		if d.Decision == storage.DecisionApprove {
+			approvals++
+		}
+	}
+
+	var message string
+	switch status {
+	case "pending":
+		message = "Approved; the intent is queued for execution"
+	case "rejected":
+		message = fmt.Sprintf("Rejected by %s", approver)
+	default:
+		message = fmt.Sprintf("%d of %d required approvals", approvals, state.Required)
+	}
+	return &types.IntentResponse{
+		Status:   status,
+		IntentID: intentID,
+		Message:  message,
+		Approval: state,
+	}, nil
+}
+
+// WatchApprovals expires intents still awaiting approval past their deadline.
+// It stops when ctx is cancelled.
+func (o *Orchestrator) WatchApprovals(ctx context.Context) {
+	if o.approvals == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(approvalExpiryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expired, err := o.store.ExpireApprovals(time.Now().Unix())
+				if err != nil {
+					log.Printf("❌ Approval expiry check failed: %v", err)
+					continue
+				}
+				for _, id := range expired {
+					log.Printf("⌛ Intent %s expired awaiting approval", id)
+				}
+			}
+		}
+	}()
+}
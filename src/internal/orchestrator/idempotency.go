@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"trustflow/src/pkg/types"
+)
+
+var (
+	// ErrIdempotencyMismatch is returned when an idempotency key or intent ID is reused for a different request
+	ErrIdempotencyMismatch = errors.New("idempotency key or intent ID was already used for a different request")
+	// ErrIntentIDTaken is returned when a client-supplied intent ID belongs to another user
+	ErrIntentIDTaken = errors.New("intent ID is already taken")
+)
+
+// findDuplicate returns the response for a replayed submission: the intent already saved under
+// the user's idempotency key or, failing that, under the client-supplied intent ID. It returns
+// nil when the request is new.
+func (o *Orchestrator) findDuplicate(userID string, idempotencyKey string, intentID string, fingerprint string) (*types.IntentResponse, error) {
+	var existingID, hash string
+	var found bool
+	var err error
+	if idempotencyKey != "" {
+		existingID, hash, found, err = o.store.FindIntentByKey(userID, idempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !found && intentID != "" {
+		var owner string
+		owner, hash, found, err = o.store.FindIntentByID(intentID)
+		if err != nil {
+			return nil, err
+		}
+		if found && owner != userID {
+			return nil, ErrIntentIDTaken
+		}
+		existingID = intentID
+	}
+	if !found {
+		return nil, nil
+	}
+
+	// Intents saved before fingerprinting have no hash to compare
+	if hash != "" && hash != fingerprint {
+		return nil, ErrIdempotencyMismatch
+	}
+
+	state, err := o.store.GetIntent(existingID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("intent %s not found", existingID)
+	}
+	return replayResponse(state), nil
+}
+
+// replayResponse reports the current state of an intent to a client retrying its submission
+func replayResponse(state *types.IntentState) *types.IntentResponse {
+	response := &types.IntentResponse{
+		Status:   state.Status,
+		IntentID: state.IntentID,
+		Message: fmt.Sprintf("Duplicate of intent %s accepted at %s; poll /status/%s for progress",
+			state.IntentID, time.Unix(state.CreatedAt, 0).UTC().Format(time.RFC3339), state.IntentID),
+		Replayed: true,
+	}
+	for _, step := range state.Steps {
+		if step.TxHash != "" {
+			response.TxHashes = append(response.TxHashes, step.TxHash)
+		}
+	}
+	if len(response.TxHashes) > 0 {
+		response.TxHash = response.TxHashes[len(response.TxHashes)-1]
+	}
+	return response
+}
+
+// requestFingerprint hashes the executable content of a submission: its workflow steps
+func requestFingerprint(steps []types.IntentStep) string {
+	raw, _ := json.Marshal(steps)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,27 +2,66 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"time"
+	"math/big"
+	"sync"
+	"trustflow/src/internal/approval"
+	"trustflow/src/internal/budget"
+	"trustflow/src/internal/chain"
 	"trustflow/src/internal/executor"
+	"trustflow/src/internal/policy"
 	"trustflow/src/internal/simulator"
 	"trustflow/src/internal/storage"
+	"trustflow/src/internal/wallet"
+	"trustflow/src/internal/webhook"
 	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 type Orchestrator struct {
-	sim   *simulator.Simulator
-	exec  *executor.Executor
-	store *storage.Storage
+	wallets   *wallet.Manager // Chain access as each user's agent wallet
+	store     *storage.Storage
+	policy    *policy.Engine // Optional: nil allows every step
+	budget    *budget.Tracker
+	approvals *approval.Gate      // Optional: nil never asks for human approval
+	hooks     *webhook.Dispatcher // Optional: nil sends no lifecycle webhooks
+	wake      chan struct{}       // Nudges idle workers when an intent is submitted or approved
+	running   sync.WaitGroup      // Workers started by Start
 }
 
-func NewOrchestrator(sim *simulator.Simulator, exec *executor.Executor, store *storage.Storage) *Orchestrator {
+func NewOrchestrator(wallets *wallet.Manager, store *storage.Storage, policy *policy.Engine, budget *budget.Tracker, approvals *approval.Gate, hooks *webhook.Dispatcher) *Orchestrator {
 	return &Orchestrator{
-		sim:   sim,
-		exec:  exec,
-		store: store,
+		wallets:   wallets,
+		store:     store,
+		policy:    policy,
+		budget:    budget,
+		approvals: approvals,
+		hooks:     hooks,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// account returns a simulator and an executor acting as the user's agent wallet
+func (o *Orchestrator) account(userID string) (*simulator.Simulator, *executor.Executor, error) {
+	client, err := o.wallets.ClientFor(userID)
+	if err != nil {
+		return nil, nil, err
 	}
+	return simulator.NewSimulator(client), executor.NewExecutor(client), nil
+}
+
+// Simulator returns a simulator that runs calls from the user's agent wallet
+func (o *Orchestrator) Simulator(userID string) (*simulator.Simulator, error) {
+	sim, _, err := o.account(userID)
+	return sim, err
+}
+
+// GetWallet reports the user's agent wallet address and balance
+func (o *Orchestrator) GetWallet(ctx context.Context, userID string) (*types.WalletInfo, error) {
+	return o.wallets.Info(ctx, userID)
 }
 
 // GetIntentStatus retrieves the current state of an intent
@@ -35,89 +74,318 @@ func (o *Orchestrator) ListIntents(userID string, limit int) ([]types.IntentStat
 	return o.store.GetRecentIntents(userID, limit)
 }
 
-// ProcessIntent handles both single and multi-step intents
-func (o *Orchestrator) ProcessIntent(ctx context.Context, userID string, intent types.Intent) (*types.IntentResponse, error) {
+// GetBudget reports the user's rolling spend and remaining budget
+func (o *Orchestrator) GetBudget(userID string) (*types.BudgetStatus, error) {
+	return o.budget.Status(userID)
+}
+
+// ErrNoActions is returned when an intent has neither an action nor steps
+var ErrNoActions = errors.New("no actions found in intent")
+
+// SubmitIntent persists the intent and its steps as pending and returns immediately.
+// The worker pool started by Start picks it up and drives it to completion. A retry
+// carrying the same idempotency key, or the same client-supplied intent ID, returns the
+// intent already accepted instead of queueing it a second time.
+func (o *Orchestrator) SubmitIntent(userID string, idempotencyKey string, intent types.Intent) (*types.IntentResponse, error) {
 	// 1. Normalize: Convert single action to a 1-step workflow
-	steps := intent.Steps
-	if len(steps) == 0 && intent.Action != "" {
-		steps = []types.IntentStep{
-			{Action: intent.Action, Params: intent.Params},
+	steps := intent.WorkflowSteps()
+	if len(steps) == 0 {
+		return nil, ErrNoActions
+	}
+	fingerprint := requestFingerprint(steps)
+
+	// 2. Replays of an accepted request get the original intent back
+	if replay, err := o.findDuplicate(userID, idempotencyKey, intent.ID, fingerprint); replay != nil || err != nil {
+		return replay, err
+	}
+
+	// Save Intent and every Step to DB up front so the whole workflow is visible while it runs
+	created, err := o.store.CreateIntent(intent, userID, idempotencyKey, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save intent: %w", err)
+	}
+	if !created {
+		// A concurrent retry saved it first
+		replay, err := o.findDuplicate(userID, idempotencyKey, intent.ID, fingerprint)
+		if err == nil && replay == nil {
+			err = fmt.Errorf("intent %s could not be saved or found", intent.ID)
 		}
+		return replay, err
+	}
+
+	o.hooks.Notify(userID, types.WebhookEvent{Type: webhook.EventIntentCreated, IntentID: intent.ID, Status: "pending"})
+	o.wakeWorker()
+
+	return &types.IntentResponse{
+		Status:   "pending",
+		IntentID: intent.ID,
+		Message:  fmt.Sprintf("Intent accepted with %d steps; poll /status/%s for progress", len(steps), intent.ID),
+	}, nil
+}
+
+// wakeWorker nudges an idle worker (non-blocking: a pending nudge is as good as two)
+func (o *Orchestrator) wakeWorker() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
 	}
+}
 
+// ProcessIntent drives a submitted intent (single or multi-step) to completion. An intent
+// interrupted by a restart resumes after its last settled step, waiting out any step whose
+// transaction was already broadcast rather than sending it again.
+func (o *Orchestrator) ProcessIntent(ctx context.Context, userID string, intent types.Intent) (*types.IntentResponse, error) {
+	steps := intent.WorkflowSteps()
 	if len(steps) == 0 {
-		return nil, fmt.Errorf("no actions found in intent")
+		return nil, ErrNoActions
 	}
 
-	var txHashes []string
+	// Every step is simulated and sent from the user's own agent wallet
+	sim, exec, err := o.account(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Save Intent to DB
-	if err := o.store.SaveIntent(intent, userID); err != nil {
-		log.Printf("Failed to save intent: %v", err)
+	// 0. Resume: skip the steps that already took effect, settle the one in flight
+	start, txHashes, inFlight, err := o.resumePoint(intent.ID, userID, len(steps))
+	if err != nil {
+		return o.failStep(intent.ID, userID, start, txHashes, err), nil
+	}
+	if inFlight {
+		log.Printf("⏯️ Resuming intent %s at in-flight step %d", intent.ID, start+1)
+		hashes, response := o.awaitInFlightStep(ctx, exec, intent.ID, userID, start, txHashes)
+		if response != nil {
+			return response, nil
+		}
+		txHashes = hashes
+		start++
+	}
+	if start == len(steps) {
+		return o.completeIntent(intent.ID, userID, len(steps), txHashes), nil
 	}
+	if start > 0 {
+		log.Printf("⏯️ Resuming intent %s at step %d/%d", intent.ID, start+1, len(steps))
+	}
+	remaining := steps[start:]
 
-	// 2. Execution Loop
-	for i, step := range steps {
-		log.Printf("🔄 Processing Step %d/%d: %s", i+1, len(steps), step.Action)
+	// 1. Preflight: parse and simulate the whole workflow before anything is broadcast
+	candidates, gasLimits, failedIdx, err := o.preflight(ctx, sim, remaining)
+	if err != nil {
+		return o.failStep(intent.ID, userID, start+failedIdx, txHashes, err), nil
+	}
 
-		// Save Step to DB
-		if err := o.store.SaveStep(intent.ID, userID, i, step.Action); err != nil {
-			log.Printf("Failed to save step: %v", err)
+	// 2. Solvency Check: the wallet must cover every value plus the gas of every step
+	report, err := sim.CheckWorkflowSolvency(ctx, candidates, gasLimits)
+	if err != nil {
+		failedIdx := 0
+		if report != nil && report.FirstShortfallAt != nil {
+			failedIdx = *report.FirstShortfallAt
 		}
+		response := o.failStep(intent.ID, userID, start+failedIdx, txHashes, fmt.Errorf("solvency check failed: %w", err))
+		response.Solvency = report
+		return response, nil
+	}
 
-		// Helper to return partial failure
-		returnFailure := func(err error) (*types.IntentResponse, error) {
-			o.store.UpdateIntentStatus(intent.ID, userID, "failed", err.Error())
-			o.store.UpdateStepStatus(intent.ID, userID, i, "failed", "", err.Error())
-
-			failedIdx := i
-			return &types.IntentResponse{
-				Status:          "failed",
-				IntentID:        intent.ID,
-				Message:         fmt.Sprintf("Execution halted at step %d: %v", i+1, err),
-				TxHashes:        txHashes,
-				FailedStepIndex: &failedIdx,
-				Error:           err.Error(),
-			}, nil // We return nil error because we want to return the structured response
+	// 3. Policy & Budget Check for the whole workflow
+	totalValue := new(big.Int)
+	for i, step := range remaining {
+		totalValue.Add(totalValue, candidates[i].Value)
+		if violation := o.policy.Evaluate(step.Action, candidates[i], gasLimits[i]); violation != nil {
+			return o.blockStep(intent.ID, userID, start+i, txHashes, violation), nil
 		}
+	}
+	violation, err := o.budget.Check(userID, totalValue)
+	if err != nil {
+		return o.failStep(intent.ID, userID, start, txHashes, fmt.Errorf("budget check failed: %w", err)), nil
+	}
+	if violation != nil {
+		return o.blockStep(intent.ID, userID, start, txHashes, violation), nil
+	}
+
+	// 4. Human Approval: high-value or risky workflows wait for M-of-N approvers
+	if response := o.requireApproval(intent.ID, userID, remaining, candidates); response != nil {
+		return response, nil
+	}
 
-		// A. Parse Step
-		tempIntent := types.Intent{Action: step.Action, Params: step.Params}
-		candidate, err := simulator.ParseIntent(tempIntent)
+	// 5. Execution Loop
+	for i := start; i < len(steps); i++ {
+		step := steps[i]
+		log.Printf("🔄 Processing Step %d/%d: %s", i+1, len(steps), step.Action)
+		candidate := candidates[i-start]
+
+		// A. Simulate against the live state left by the previous steps for a precise gas limit
+		gasLimit, err := sim.Simulate(ctx, candidate)
 		if err != nil {
-			return returnFailure(fmt.Errorf("parse failed: %w", err))
+			return o.failStep(intent.ID, userID, i, txHashes, fmt.Errorf("simulation failed: %w", err)), nil
 		}
 
-		// B. Simulate (Safety Check)
-		gasLimit, err := o.sim.Simulate(ctx, candidate)
-		if err != nil {
-			return returnFailure(fmt.Errorf("simulation failed: %w", err))
+		// B. Policy & Budget Check (re-evaluated: gas and concurrent spend may have moved)
+		violation := o.policy.Evaluate(step.Action, candidate, gasLimit)
+		if violation == nil {
+			violation, err = o.budget.Check(userID, candidate.Value)
+			if err != nil {
+				return o.failStep(intent.ID, userID, i, txHashes, fmt.Errorf("budget check failed: %w", err)), nil
+			}
+		}
+		if violation != nil {
+			return o.blockStep(intent.ID, userID, i, txHashes, violation), nil
 		}
 
-		// C. Execute
-		txHash, err := o.exec.Execute(ctx, candidate, gasLimit)
+		// C. Execute, journaling the signed transaction first so a crash mid-broadcast can be reconciled
+		sent, err := exec.Send(ctx, candidate, gasLimit, func(sent *chain.SentTx) error {
+			if err := o.store.MarkStepExecuted(intent.ID, userID, i, sent.Hash, candidate.Value, sent.Fees.Details()); err != nil {
+				return err
+			}
+			return o.store.SaveStepTx(intent.ID, userID, i, sent.Nonce, candidate.ToAddress.Hex(), hexutil.Encode(candidate.Data), gasLimit, hexutil.Encode(sent.Raw))
+		})
+		if errors.Is(err, chain.ErrBroadcastUncertain) {
+			// The node may have the transaction: keep the step submitted and settle it from the journal
+			log.Printf("⚠️ Step %d broadcast unanswered, reconciling: %v", i+1, err)
+			hashes, response := o.awaitInFlightStep(ctx, exec, intent.ID, userID, i, txHashes)
+			if response != nil {
+				return response, nil
+			}
+			txHashes = hashes
+			continue
+		}
 		if err != nil {
-			return returnFailure(fmt.Errorf("execution failed: %w", err))
+			// The node rejected it outright (nonce, funds, underpriced...): nothing was broadcast
+			return o.failStep(intent.ID, userID, i, txHashes, fmt.Errorf("execution failed: %w", err)), nil
 		}
+		txHash := sent.Hash
 
-		log.Printf("✅ Step %d Executed. Hash: %s", i+1, txHash)
+		log.Printf("📡 Step %d Broadcast (%s, nonce %d). Hash: %s", i+1, sent.Fees.Type, sent.Nonce, txHash)
+		o.hooks.Notify(userID, types.WebhookEvent{Type: webhook.EventStepBroadcast, IntentID: intent.ID, Status: "submitted", StepIndex: &i, TxHash: txHash})
 		txHashes = append(txHashes, txHash)
-		o.store.UpdateStepStatus(intent.ID, userID, i, "success", txHash, "")
 
-		// D. Wait for Confirmation (if there are more steps)
-		if i < len(steps)-1 {
-			log.Printf("⏳ Waiting for confirmation of %s...", txHash)
-			time.Sleep(5 * time.Second)
+		// D. Wait for a confirmed receipt of the transaction or of any replacement at its nonce
+		log.Printf("⏳ Waiting for confirmation of %s...", txHash)
+		receipt, err := exec.WaitForAnyReceipt(ctx, func() []string {
+			return o.stepHashes(intent.ID, userID, i, txHash)
+		})
+		if err != nil {
+			err = fmt.Errorf("confirmation failed: %w", err)
+			o.store.UpdateStepStatus(intent.ID, userID, i, "unconfirmed", txHash, err.Error())
+			return o.haltBroadcastStep(intent.ID, userID, i, txHashes, err), nil
 		}
+
+		// E. Advance only if the step took effect (not reverted, not cancelled)
+		txHashes[len(txHashes)-1] = receipt.TxHash.Hex()
+		if err := o.settleStep(intent.ID, userID, i, receipt); err != nil {
+			return o.haltBroadcastStep(intent.ID, userID, i, txHashes, err), nil
+		}
+		log.Printf("✅ Step %d Confirmed in block %d (gas used %d)", i+1, receipt.BlockNumber.Uint64(), receipt.GasUsed)
 	}
 
-	o.store.UpdateIntentStatus(intent.ID, userID, "success", "All steps executed successfully")
+	return o.completeIntent(intent.ID, userID, len(steps), txHashes), nil
+}
+
+// completeIntent records a workflow whose every step took effect and builds the success response
+func (o *Orchestrator) completeIntent(intentID, userID string, stepCount int, txHashes []string) *types.IntentResponse {
+	o.store.UpdateIntentStatus(intentID, userID, "success", "All steps executed successfully")
+	o.hooks.Notify(userID, types.WebhookEvent{
+		Type:     webhook.EventIntentSucceeded,
+		IntentID: intentID,
+		Status:   "success",
+		TxHash:   txHashes[len(txHashes)-1],
+		Message:  "All steps executed successfully",
+	})
 
 	return &types.IntentResponse{
 		Status:   "success",
-		IntentID: intent.ID,
-		Message:  fmt.Sprintf("Successfully executed %d steps", len(steps)),
+		IntentID: intentID,
+		Message:  fmt.Sprintf("Successfully executed %d steps", stepCount),
 		TxHashes: txHashes,
 		TxHash:   txHashes[len(txHashes)-1], // Last hash for backward compatibility
-	}, nil
+	}
+}
+
+// preflight parses every step and simulates them in order against cumulative state.
+// On failure it returns the index of the offending step.
+func (o *Orchestrator) preflight(ctx context.Context, sim *simulator.Simulator, steps []types.IntentStep) ([]*simulator.TxCandidate, []uint64, int, error) {
+	candidates := make([]*simulator.TxCandidate, len(steps))
+	for i, step := range steps {
+		candidate, err := sim.Parse(ctx, step.AsIntent())
+		if err != nil {
+			return nil, nil, i, fmt.Errorf("parse failed: %w", err)
+		}
+		candidates[i] = candidate
+	}
+
+	gasLimits, _, err := sim.SimulateWorkflow(ctx, candidates) // Independent simulation is logged by the simulator
+	if err != nil {
+		failedIdx := 0
+		var wfErr *simulator.WorkflowError
+		if errors.As(err, &wfErr) {
+			failedIdx, err = wfErr.StepIndex, wfErr.Err
+		}
+		return nil, nil, failedIdx, fmt.Errorf("simulation failed: %w", err)
+	}
+
+	return candidates, gasLimits, 0, nil
+}
+
+// failStep records a failed step, skips the ones after it and builds the failure response
+func (o *Orchestrator) failStep(intentID, userID string, stepIndex int, txHashes []string, err error) *types.IntentResponse {
+	o.store.UpdateIntentStatus(intentID, userID, "failed", err.Error())
+	o.store.UpdateStepStatus(intentID, userID, stepIndex, "failed", "", err.Error())
+	o.store.SkipRemainingSteps(intentID, userID, stepIndex)
+	o.notifyHalted(intentID, userID, webhook.EventIntentFailed, "failed", stepIndex, err.Error())
+
+	return &types.IntentResponse{
+		Status:          "failed",
+		IntentID:        intentID,
+		Message:         fmt.Sprintf("Execution halted at step %d: %v", stepIndex+1, err),
+		TxHashes:        txHashes,
+		FailedStepIndex: &stepIndex,
+		Error:           err.Error(),
+	}
+}
+
+// haltBroadcastStep fails the intent at a step whose transaction was already broadcast.
+// The caller records the step's own state so its tx hash is never overwritten.
+func (o *Orchestrator) haltBroadcastStep(intentID, userID string, stepIndex int, txHashes []string, err error) *types.IntentResponse {
+	o.store.UpdateIntentStatus(intentID, userID, "failed", err.Error())
+	o.store.SkipRemainingSteps(intentID, userID, stepIndex)
+	o.notifyHalted(intentID, userID, webhook.EventIntentFailed, "failed", stepIndex, err.Error())
+
+	return &types.IntentResponse{
+		Status:          "failed",
+		IntentID:        intentID,
+		Message:         fmt.Sprintf("Execution halted at step %d: %v", stepIndex+1, err),
+		TxHashes:        txHashes,
+		TxHash:          txHashes[len(txHashes)-1],
+		FailedStepIndex: &stepIndex,
+		Error:           err.Error(),
+	}
+}
+
+// blockStep records a policy violation, skips the remaining steps and builds the blocked response
+func (o *Orchestrator) blockStep(intentID, userID string, stepIndex int, txHashes []string, violation *policy.Violation) *types.IntentResponse {
+	log.Printf("🛑 Step %d Blocked by policy: %s", stepIndex+1, violation.Error())
+	o.store.UpdateIntentStatus(intentID, userID, "blocked", violation.Error())
+	o.store.UpdateStepStatus(intentID, userID, stepIndex, "blocked", "", violation.Error())
+	o.store.SkipRemainingSteps(intentID, userID, stepIndex)
+	o.notifyHalted(intentID, userID, webhook.EventIntentBlocked, "blocked", stepIndex, violation.Error())
+
+	return &types.IntentResponse{
+		Status:          "blocked",
+		IntentID:        intentID,
+		Message:         fmt.Sprintf("Blocked at step %d by policy rule %s", stepIndex+1, violation.Rule),
+		TxHashes:        txHashes,
+		FailedStepIndex: &stepIndex,
+		Error:           violation.Message,
+		BlockedRule:     violation.Rule,
+	}
+}
+
+// notifyHalted reports an intent that stopped at a step without completing
+func (o *Orchestrator) notifyHalted(intentID, userID string, eventType string, status string, stepIndex int, message string) {
+	o.hooks.Notify(userID, types.WebhookEvent{
+		Type:      eventType,
+		IntentID:  intentID,
+		Status:    status,
+		StepIndex: &stepIndex,
+		Message:   message,
+	})
 }
@@ -0,0 +1,184 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"trustflow/src/internal/executor"
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrNonceConsumed is returned when a step's nonce was used up by a transaction other than the step's own
+var ErrNonceConsumed = errors.New("nonce was consumed by another transaction")
+
+// Recover reconciles intents a restart interrupted mid-workflow and queues them to resume.
+// Steps broadcast before the restart are checked against the chain by tx hash and nonce:
+// mined ones are settled, ones whose nonce went to another transaction fail the intent, and
+// pending ones are rebroadcast from the journal. Run it before Start.
+func (o *Orchestrator) Recover(ctx context.Context) error {
+	interrupted, err := o.store.ListIntentsByStatus("processing")
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range interrupted {
+		log.Printf("🩹 Recovering intent %s interrupted by a restart", ref.ID)
+		if o.reconcileIntent(ctx, ref) {
+			o.store.UpdateIntentStatus(ref.ID, ref.UserID, "pending", "Resuming after restart")
+		}
+	}
+	if len(interrupted) > 0 {
+		log.Printf("🩹 Recovered %d interrupted intents", len(interrupted))
+		o.wakeWorker()
+	}
+	return nil
+}
+
+// reconcileIntent settles the broadcast steps of an interrupted intent and reports whether it can resume
+func (o *Orchestrator) reconcileIntent(ctx context.Context, ref storage.IntentRef) bool {
+	state, err := o.store.GetIntent(ref.ID, ref.UserID)
+	if err != nil || state == nil {
+		log.Printf("❌ Failed to load interrupted intent %s: %v", ref.ID, err)
+		return true // ProcessIntent reports whatever is wrong with it
+	}
+	_, exec, err := o.account(ref.UserID)
+	if err != nil {
+		log.Printf("❌ No wallet to reconcile intent %s: %v", ref.ID, err)
+		return true
+	}
+
+	for _, step := range state.Steps {
+		if step.Status != "submitted" {
+			continue
+		}
+		stepTx, err := o.store.GetStepTx(ref.ID, ref.UserID, step.StepIndex)
+		if err != nil || stepTx == nil {
+			continue // Left for ProcessIntent to settle
+		}
+
+		receipt, err := o.reconcileStep(ctx, exec, *stepTx)
+		switch {
+		case errors.Is(err, ErrNonceConsumed):
+			o.store.UpdateStepStatus(ref.ID, ref.UserID, step.StepIndex, "failed", stepTx.TxHash, err.Error())
+			o.haltBroadcastStep(ref.ID, ref.UserID, step.StepIndex, []string{stepTx.TxHash}, err)
+			return false
+		case err != nil:
+			log.Printf("⚠️ Could not reconcile step %d of %s yet: %v", step.StepIndex+1, ref.ID, err)
+		case receipt != nil:
+			log.Printf("🧾 Step %d of %s was mined in block %d while down", step.StepIndex+1, ref.ID, receipt.BlockNumber.Uint64())
+			if err := o.settleStep(ref.ID, ref.UserID, step.StepIndex, receipt); err != nil {
+				o.haltBroadcastStep(ref.ID, ref.UserID, step.StepIndex, []string{receipt.TxHash.Hex()}, err)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// reconcileStep checks a broadcast step against the chain. It returns the receipt if any of the
+// step's transactions was mined, ErrNonceConsumed if something else took its nonce, and otherwise
+// rebroadcasts the latest journaled transaction, original or replacement (harmless if the node
+// still has it), and returns nil.
+func (o *Orchestrator) reconcileStep(ctx context.Context, exec *executor.Executor, stepTx storage.StepTx) (*ethtypes.Receipt, error) {
+	hashes := o.stepHashes(stepTx.IntentID, stepTx.UserID, stepTx.StepIndex, stepTx.TxHash)
+	receipt, err := exec.FindReceipt(ctx, hashes)
+	if err != nil || receipt != nil {
+		return receipt, err
+	}
+
+	mined, err := exec.MinedNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if mined > stepTx.Nonce {
+		// The nonce is used up; look once more in case ours was mined in between
+		if receipt, err := exec.FindReceipt(ctx, hashes); err != nil || receipt != nil {
+			return receipt, err
+		}
+		return nil, fmt.Errorf("%w: nonce %d of step %d", ErrNonceConsumed, stepTx.Nonce, stepTx.StepIndex+1)
+	}
+
+	if stepTx.RawTx != "" {
+		raw, err := hexutil.Decode(stepTx.RawTx)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt journaled transaction: %w", err)
+		}
+		if err := exec.Rebroadcast(ctx, raw); err != nil {
+			log.Printf("📡 Rebroadcast of step %d of %s: %v", stepTx.StepIndex+1, stepTx.IntentID, err)
+		} else {
+			log.Printf("📡 Rebroadcast step %d of %s at nonce %d", stepTx.StepIndex+1, stepTx.IntentID, stepTx.Nonce)
+		}
+	}
+	return nil, nil
+}
+
+// resumePoint returns the index of the first step of an intent that has not taken effect, the
+// tx hashes of the steps before it, and whether that step was already broadcast. A fresh
+// intent resumes at 0.
+func (o *Orchestrator) resumePoint(intentID, userID string, stepCount int) (int, []string, bool, error) {
+	state, err := o.store.GetIntent(intentID, userID)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	if state == nil || len(state.Steps) != stepCount {
+		return 0, nil, false, nil // Steps are saved with the intent; nothing to resume from
+	}
+
+	var txHashes []string
+	for i, step := range state.Steps {
+		switch step.Status {
+		case "success":
+			txHashes = append(txHashes, step.TxHash)
+		case "pending":
+			return i, txHashes, false, nil
+		case "submitted":
+			return i, txHashes, true, nil
+		default:
+			return i, txHashes, false, fmt.Errorf("cannot resume: step %d is %s", i+1, step.Status)
+		}
+	}
+	return stepCount, txHashes, false, nil
+}
+
+// awaitInFlightStep settles a step broadcast before a restart, rebroadcasting it if needed and
+// waiting for any of its transactions to be mined. It returns the hashes with the step's
+// appended, or the response to stop with.
+func (o *Orchestrator) awaitInFlightStep(ctx context.Context, exec *executor.Executor, intentID, userID string, stepIndex int, txHashes []string) ([]string, *types.IntentResponse) {
+	stepTx, err := o.store.GetStepTx(intentID, userID, stepIndex)
+	if err == nil && stepTx == nil {
+		err = errors.New("no journaled transaction")
+	}
+	if err != nil {
+		return nil, o.failStep(intentID, userID, stepIndex, txHashes, fmt.Errorf("cannot resume in-flight step: %w", err))
+	}
+	txHashes = append(txHashes, stepTx.TxHash)
+
+	receipt, err := o.reconcileStep(ctx, exec, *stepTx)
+	if err == nil && receipt == nil {
+		log.Printf("⏳ Waiting for confirmation of %s...", stepTx.TxHash)
+		receipt, err = exec.WaitForAnyReceipt(ctx, func() []string {
+			return o.stepHashes(intentID, userID, stepIndex, stepTx.TxHash)
+		})
+	}
+	if err != nil {
+		status := "unconfirmed"
+		if errors.Is(err, ErrNonceConsumed) {
+			status = "failed"
+		}
+		err = fmt.Errorf("confirmation failed: %w", err)
+		o.store.UpdateStepStatus(intentID, userID, stepIndex, status, stepTx.TxHash, err.Error())
+		return nil, o.haltBroadcastStep(intentID, userID, stepIndex, txHashes, err)
+	}
+
+	txHashes[len(txHashes)-1] = receipt.TxHash.Hex()
+	if err := o.settleStep(intentID, userID, stepIndex, receipt); err != nil {
+		return nil, o.haltBroadcastStep(intentID, userID, stepIndex, txHashes, err)
+	}
+	log.Printf("✅ Step %d Confirmed in block %d (gas used %d)", stepIndex+1, receipt.BlockNumber.Uint64(), receipt.GasUsed)
+	return txHashes, nil
+}
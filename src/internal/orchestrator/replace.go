@@ -0,0 +1,258 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+	"trustflow/src/internal/chain"
+	"trustflow/src/internal/simulator"
+	"trustflow/src/internal/storage"
+	"trustflow/src/internal/webhook"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// Kinds of replacement transaction
+const (
+	ReplacementSpeedUp = "speedup" // Same call, same nonce, bumped fees
+	ReplacementCancel  = "cancel"  // Zero-value self-transfer at the same nonce
+)
+
+var (
+	// ErrStepNotFound is returned when a step does not exist or was never broadcast
+	ErrStepNotFound = errors.New("step not found or never broadcast")
+	// ErrStepNotPending is returned when a step's transaction is already mined or settled
+	ErrStepNotPending = errors.New("step transaction is no longer pending")
+)
+
+// SpeedUpStep rebroadcasts a step's pending transaction at the same nonce with bumped fees
+func (o *Orchestrator) SpeedUpStep(ctx context.Context, userID string, intentID string, stepIndex int) (*types.TxReplacement, error) {
+	return o.replaceStep(ctx, userID, intentID, stepIndex, ReplacementSpeedUp)
+}
+
+// CancelStep replaces a step's pending transaction with a zero-value self-transfer at the same nonce
+func (o *Orchestrator) CancelStep(ctx context.Context, userID string, intentID string, stepIndex int) (*types.TxReplacement, error) {
+	return o.replaceStep(ctx, userID, intentID, stepIndex, ReplacementCancel)
+}
+
+func (o *Orchestrator) replaceStep(ctx context.Context, userID string, intentID string, stepIndex int, kind string) (*types.TxReplacement, error) {
+	stepTx, err := o.store.GetStepTx(intentID, userID, stepIndex)
+	if err != nil {
+		return nil, err
+	}
+	if stepTx == nil {
+		return nil, ErrStepNotFound
+	}
+	return o.replace(ctx, *stepTx, kind)
+}
+
+// replace broadcasts a replacement for a step's pending transaction and records it
+func (o *Orchestrator) replace(ctx context.Context, stepTx storage.StepTx, kind string) (*types.TxReplacement, error) {
+	if stepTx.Status != "submitted" && stepTx.Status != "unconfirmed" {
+		return nil, fmt.Errorf("%w: status is %s", ErrStepNotPending, stepTx.Status)
+	}
+
+	// The replacement must come from the wallet that sent the original
+	_, exec, err := o.account(stepTx.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing to replace once any transaction at this nonce has been mined
+	hashes := o.stepHashes(stepTx.IntentID, stepTx.UserID, stepTx.StepIndex, stepTx.TxHash)
+	receipt, err := exec.FindReceipt(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if receipt != nil {
+		return nil, fmt.Errorf("%w: %s already mined", ErrStepNotPending, receipt.TxHash.Hex())
+	}
+
+	if stepTx.Fees == nil {
+		return nil, errors.New("step has no recorded fees to bump")
+	}
+	previous, err := chain.FeeParamsFromDetails(stepTx.Fees)
+	if err != nil {
+		return nil, err
+	}
+
+	var sent *chain.SentTx
+	switch kind {
+	case ReplacementSpeedUp:
+		candidate, err := stepCandidate(stepTx)
+		if err != nil {
+			return nil, err
+		}
+		sent, err = exec.Replace(ctx, stepTx.Nonce, candidate, stepTx.GasLimit, previous)
+		if err != nil {
+			return nil, err
+		}
+	case ReplacementCancel:
+		sent, err = exec.Cancel(ctx, stepTx.Nonce, previous)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown replacement kind %q", kind)
+	}
+
+	log.Printf("🔁 Step %d of %s: %s broadcast at nonce %d. Hash: %s", stepTx.StepIndex+1, stepTx.IntentID, kind, sent.Nonce, sent.Hash)
+	replacement := &types.TxReplacement{
+		Kind:      kind,
+		TxHash:    sent.Hash,
+		Fees:      sent.Fees.Details(),
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := o.store.AddStepReplacement(stepTx.IntentID, stepTx.UserID, stepTx.StepIndex, kind, sent.Hash, replacement.Fees, hexutil.Encode(sent.Raw)); err != nil {
+		return nil, err
+	}
+	return replacement, nil
+}
+
+// stepCandidate rebuilds the call a step broadcast from its stored transaction
+func stepCandidate(stepTx storage.StepTx) (*simulator.TxCandidate, error) {
+	if !common.IsHexAddress(stepTx.To) {
+		return nil, fmt.Errorf("invalid stored recipient %q", stepTx.To)
+	}
+	to := common.HexToAddress(stepTx.To)
+
+	value, ok := new(big.Int).SetString(stepTx.Value, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid stored value %q", stepTx.Value)
+	}
+
+	data, err := hexutil.Decode(stepTx.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored calldata: %w", err)
+	}
+
+	return &simulator.TxCandidate{ToAddress: &to, Value: value, Data: data}, nil
+}
+
+// stepHashes lists every transaction broadcast at a step's nonce: the original and its replacements
+func (o *Orchestrator) stepHashes(intentID, userID string, stepIndex int, original string) []string {
+	hashes := []string{original}
+	replacements, err := o.store.GetStepReplacements(intentID, userID, stepIndex)
+	if err != nil {
+		log.Printf("⚠️ Failed to load replacements of step %d of %s: %v", stepIndex+1, intentID, err)
+		return hashes
+	}
+	for _, r := range replacements {
+		hashes = append(hashes, r.TxHash)
+	}
+	return hashes
+}
+
+// settleStep records the mined outcome of a step. It returns the error that halts the
+// workflow when the step did not take effect: reverted on-chain, or cancelled.
+func (o *Orchestrator) settleStep(intentID, userID string, stepIndex int, receipt *ethtypes.Receipt) error {
+	txHash := receipt.TxHash.Hex()
+	blockNumber := receipt.BlockNumber.Uint64()
+
+	replacements, err := o.store.GetStepReplacements(intentID, userID, stepIndex)
+	if err != nil {
+		log.Printf("⚠️ Failed to load replacements of step %d of %s: %v", stepIndex+1, intentID, err)
+	}
+	for _, r := range replacements {
+		if r.Kind == ReplacementCancel && strings.EqualFold(r.TxHash, txHash) {
+			err := fmt.Errorf("step cancelled: replacement %s mined in block %d", txHash, blockNumber)
+			o.store.RecordStepReceipt(intentID, userID, stepIndex, txHash, "cancelled", blockNumber, receipt.GasUsed, receipt.Status, err.Error())
+			o.notifyConfirmed(intentID, userID, stepIndex, txHash, "cancelled", err.Error())
+			return err
+		}
+	}
+
+	if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		err := fmt.Errorf("transaction %s reverted on-chain in block %d", txHash, blockNumber)
+		o.store.RecordStepReceipt(intentID, userID, stepIndex, txHash, "failed", blockNumber, receipt.GasUsed, receipt.Status, err.Error())
+		o.notifyConfirmed(intentID, userID, stepIndex, txHash, "failed", err.Error())
+		return err
+	}
+
+	o.store.RecordStepReceipt(intentID, userID, stepIndex, txHash, "success", blockNumber, receipt.GasUsed, receipt.Status, "")
+	o.notifyConfirmed(intentID, userID, stepIndex, txHash, "success", fmt.Sprintf("Mined in block %d", blockNumber))
+	return nil
+}
+
+// notifyConfirmed reports a step whose transaction (or a replacement of it) was mined
+func (o *Orchestrator) notifyConfirmed(intentID, userID string, stepIndex int, txHash string, status string, message string) {
+	o.hooks.Notify(userID, types.WebhookEvent{
+		Type:      webhook.EventStepConfirmed,
+		IntentID:  intentID,
+		Status:    status,
+		StepIndex: &stepIndex,
+		TxHash:    txHash,
+		Message:   message,
+	})
+}
+
+// WatchStuckTransactions speeds up steps whose transaction has not been mined within
+// stuckAfter, at most maxSpeedups times each, and settles unconfirmed steps whose
+// transaction was mined after their workflow gave up on it. It stops when ctx is cancelled.
+func (o *Orchestrator) WatchStuckTransactions(ctx context.Context, stuckAfter time.Duration, maxSpeedups int) {
+	if maxSpeedups < 1 || stuckAfter <= 0 {
+		log.Println("⏸️ Stuck transaction watcher disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(max(stuckAfter/3, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				o.speedUpStuck(ctx, stuckAfter, maxSpeedups)
+				o.settleUnconfirmed(ctx)
+			}
+		}
+	}()
+	log.Printf("🩺 Watching for transactions unmined after %s (max %d speed-ups)", stuckAfter, maxSpeedups)
+}
+
+func (o *Orchestrator) speedUpStuck(ctx context.Context, stuckAfter time.Duration, maxSpeedups int) {
+	steps, err := o.store.ListBroadcastSteps("submitted", time.Now().Add(-stuckAfter).Unix())
+	if err != nil {
+		log.Printf("❌ Stuck transaction check failed: %v", err)
+		return
+	}
+
+	for _, step := range steps {
+		if step.Speedups >= maxSpeedups {
+			continue
+		}
+		log.Printf("🐢 Step %d of %s unmined since %s, speeding up", step.StepIndex+1, step.IntentID, time.Unix(step.BroadcastAt, 0).Format(time.RFC3339))
+		if _, err := o.replace(ctx, step, ReplacementSpeedUp); err != nil && !errors.Is(err, ErrStepNotPending) {
+			log.Printf("❌ Speed-up of step %d of %s failed: %v", step.StepIndex+1, step.IntentID, err)
+		}
+	}
+}
+
+func (o *Orchestrator) settleUnconfirmed(ctx context.Context) {
+	steps, err := o.store.ListBroadcastSteps("unconfirmed", time.Now().Unix()+1)
+	if err != nil {
+		log.Printf("❌ Unconfirmed step check failed: %v", err)
+		return
+	}
+
+	for _, step := range steps {
+		_, exec, err := o.account(step.UserID)
+		if err != nil {
+			continue
+		}
+		receipt, err := exec.FindReceipt(ctx, o.stepHashes(step.IntentID, step.UserID, step.StepIndex, step.TxHash))
+		if err != nil || receipt == nil {
+			continue
+		}
+		log.Printf("🧾 Unconfirmed step %d of %s was mined in block %d", step.StepIndex+1, step.IntentID, receipt.BlockNumber.Uint64())
+		o.settleStep(step.IntentID, step.UserID, step.StepIndex, receipt)
+	}
+}
@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"time"
+	"trustflow/src/internal/webhook"
+	"trustflow/src/pkg/types"
+)
+
+// pollInterval bounds how long a pending intent can wait if a wake-up is missed
+const pollInterval = 2 * time.Second
+
+// Start launches a pool of workers that pull pending intents from storage and process
+// them in the background. Workers stop claiming intents when ctx is cancelled; Wait lets
+// them finish the ones they hold.
+func (o *Orchestrator) Start(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		o.running.Add(1)
+		go func(id int) {
+			defer o.running.Done()
+			o.worker(ctx, id)
+		}(i + 1)
+	}
+	log.Printf("👷 Started %d intent workers", workers)
+}
+
+// Wait blocks until every worker has stopped after ctx was cancelled, or timeout passes.
+// Intents still processing then are resumed by Recover on the next start.
+func (o *Orchestrator) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		o.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (o *Orchestrator) worker(ctx context.Context, id int) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before going idle
+		for o.runNext(ctx, id) {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims and processes one pending intent, reporting whether there was one
+func (o *Orchestrator) runNext(ctx context.Context, workerID int) bool {
+	intent, userID, err := o.store.ClaimPendingIntent()
+	if err != nil {
+		log.Printf("❌ Worker %d failed to claim intent: %v", workerID, err)
+		return false
+	}
+	if intent == nil {
+		return false
+	}
+
+	// A claimed intent runs to completion through shutdown: cancelling it mid-step would
+	// record a transaction that may still be mined as failed
+	log.Printf("👷 Worker %d processing intent %s", workerID, intent.ID)
+	response, err := o.ProcessIntent(context.WithoutCancel(ctx), userID, *intent)
+	if err != nil {
+		log.Printf("❌ Worker %d failed intent %s: %v", workerID, intent.ID, err)
+		o.store.UpdateIntentStatus(intent.ID, userID, "failed", err.Error())
+		o.hooks.Notify(userID, types.WebhookEvent{Type: webhook.EventIntentFailed, IntentID: intent.ID, Status: "failed", Message: err.Error()})
+		return true
+	}
+
+	log.Printf("🏁 Worker %d finished intent %s: %s", workerID, intent.ID, response.Status)
+	return true
+}
@@ -0,0 +1,245 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"trustflow/src/internal/simulator"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule names reported when a candidate is blocked
+const (
+	RuleMaxValuePerTx     = "max_value_per_tx"
+	RuleMaxValuePerStep   = "max_value_per_step"
+	RuleAllowedActions    = "allowed_actions"
+	RuleAllowedRecipients = "allowed_recipients"
+	RuleMaxGas            = "max_gas"
+	RuleMaxTokenAmount    = "max_token_amount"
+)
+
+// DefaultToken keys the max_token_amount limit applied to tokens without their own entry
+const DefaultToken = "*"
+
+// Rules is the declarative rule set loaded from the policy file.
+// Empty fields are not enforced. Value limits only see the native value, so an
+// ERC-20 transfer or approval that a value limit applies to is blocked unless
+// max_token_amount covers its token.
+type Rules struct {
+	MaxValuePerTx     string            `json:"max_value_per_tx" yaml:"max_value_per_tx"`     // Wei, applies to every transaction
+	MaxValuePerStep   map[string]string `json:"max_value_per_step" yaml:"max_value_per_step"` // Wei, keyed by step action
+	AllowedActions    []string          `json:"allowed_actions" yaml:"allowed_actions"`       // Whitelist of step actions
+	AllowedRecipients []string          `json:"allowed_recipients" yaml:"allowed_recipients"` // Whitelist of destination addresses
+	MaxGas            uint64            `json:"max_gas" yaml:"max_gas"`                       // Upper bound on the simulated gas limit
+	MaxTokenAmount    map[string]string `json:"max_token_amount" yaml:"max_token_amount"`     // Base units per ERC-20 token address, or "*" for every other token
+}
+
+// Violation describes the rule that blocked a transaction candidate
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("policy violation (%s): %s", v.Rule, v.Message)
+}
+
+// Engine evaluates transaction candidates against a compiled rule set
+type Engine struct {
+	maxValuePerTx     *big.Int
+	maxValuePerStep   map[string]*big.Int
+	allowedActions    map[string]bool
+	allowedRecipients map[common.Address]bool
+	maxGas            uint64
+	maxTokenAmount    map[string]*big.Int
+}
+
+// LoadFile reads a rule set from a YAML or JSON file
+func LoadFile(path string) (*Engine, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var rules Rules
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(raw, &rules)
+	default:
+		err = yaml.Unmarshal(raw, &rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+
+	return NewEngine(rules)
+}
+
+// NewEngine validates the rule set and prepares it for evaluation
+func NewEngine(rules Rules) (*Engine, error) {
+	e := &Engine{
+		maxValuePerStep: make(map[string]*big.Int),
+		maxGas:          rules.MaxGas,
+		maxTokenAmount:  make(map[string]*big.Int),
+	}
+
+	if rules.MaxValuePerTx != "" {
+		v, err := parseWei(rules.MaxValuePerTx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", RuleMaxValuePerTx, err)
+		}
+		e.maxValuePerTx = v
+	}
+
+	for action, limit := range rules.MaxValuePerStep {
+		v, err := parseWei(limit)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%s]: %w", RuleMaxValuePerStep, action, err)
+		}
+		e.maxValuePerStep[action] = v
+	}
+
+	for token, limit := range rules.MaxTokenAmount {
+		if token != DefaultToken && !common.IsHexAddress(token) {
+			return nil, fmt.Errorf("%s: invalid token address %q", RuleMaxTokenAmount, token)
+		}
+		v, ok := new(big.Int).SetString(limit, 10)
+		if !ok || v.Sign() < 0 {
+			return nil, fmt.Errorf("%s[%s]: invalid amount %q", RuleMaxTokenAmount, token, limit)
+		}
+		if token != DefaultToken {
+			token = common.HexToAddress(token).Hex()
+		}
+		e.maxTokenAmount[token] = v
+	}
+
+	if len(rules.AllowedActions) > 0 {
+		e.allowedActions = make(map[string]bool)
+		for _, action := range rules.AllowedActions {
+			e.allowedActions[action] = true
+		}
+	}
+
+	if len(rules.AllowedRecipients) > 0 {
+		e.allowedRecipients = make(map[common.Address]bool)
+		for _, addr := range rules.AllowedRecipients {
+			if !common.IsHexAddress(addr) {
+				return nil, fmt.Errorf("%s: invalid address %q", RuleAllowedRecipients, addr)
+			}
+			e.allowedRecipients[common.HexToAddress(addr)] = true
+		}
+	}
+
+	return e, nil
+}
+
+// Evaluate checks a simulated step against every rule and returns the first violation, or nil
+func (e *Engine) Evaluate(action string, candidate *simulator.TxCandidate, gasLimit uint64) *Violation {
+	if e == nil {
+		return nil // No policy configured: everything is allowed
+	}
+
+	if e.allowedActions != nil && !e.allowedActions[action] {
+		return &Violation{
+			Rule:    RuleAllowedActions,
+			Message: fmt.Sprintf("action %q is not allowed", action),
+		}
+	}
+
+	value := candidate.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+
+	if e.maxValuePerTx != nil && value.Cmp(e.maxValuePerTx) > 0 {
+		return &Violation{
+			Rule:    RuleMaxValuePerTx,
+			Message: fmt.Sprintf("value %s wei exceeds limit of %s wei", value, e.maxValuePerTx),
+		}
+	}
+
+	if limit, ok := e.maxValuePerStep[action]; ok && value.Cmp(limit) > 0 {
+		return &Violation{
+			Rule:    RuleMaxValuePerStep,
+			Message: fmt.Sprintf("value %s wei exceeds %s limit of %s wei", value, action, limit),
+		}
+	}
+
+	if v := e.evaluateToken(action, candidate); v != nil {
+		return v
+	}
+
+	if e.allowedRecipients != nil {
+		beneficiary := candidate.Beneficiary()
+		if beneficiary == nil || !e.allowedRecipients[*beneficiary] {
+			recipient := "<none>"
+			if beneficiary != nil {
+				recipient = beneficiary.Hex()
+			}
+			return &Violation{
+				Rule:    RuleAllowedRecipients,
+				Message: fmt.Sprintf("recipient %s is not allowed", recipient),
+			}
+		}
+	}
+
+	if e.maxGas > 0 && gasLimit > e.maxGas {
+		return &Violation{
+			Rule:    RuleMaxGas,
+			Message: fmt.Sprintf("gas limit %d exceeds limit of %d", gasLimit, e.maxGas),
+		}
+	}
+
+	return nil
+}
+
+// evaluateToken checks the ERC-20 amount a candidate moves or approves. Value limits
+// cannot see it, so a step they apply to needs a max_token_amount entry for its token.
+func (e *Engine) evaluateToken(action string, candidate *simulator.TxCandidate) *Violation {
+	if candidate.TokenAmount == nil || candidate.ToAddress == nil || candidate.TokenAmount.Sign() == 0 {
+		return nil // Not a token call, or a revocation
+	}
+
+	token := candidate.ToAddress.Hex()
+	limit, ok := e.maxTokenAmount[token]
+	if !ok {
+		limit, ok = e.maxTokenAmount[DefaultToken]
+	}
+	if ok {
+		if candidate.TokenAmount.Cmp(limit) > 0 {
+			return &Violation{
+				Rule:    RuleMaxTokenAmount,
+				Message: fmt.Sprintf("token %s amount %s exceeds limit of %s", token, candidate.TokenAmount, limit),
+			}
+		}
+		return nil
+	}
+
+	if e.maxValuePerTx != nil {
+		return &Violation{
+			Rule:    RuleMaxValuePerTx,
+			Message: fmt.Sprintf("token %s amount is not covered by the wei limit; set %s for it", token, RuleMaxTokenAmount),
+		}
+	}
+	if _, limited := e.maxValuePerStep[action]; limited {
+		return &Violation{
+			Rule:    RuleMaxValuePerStep,
+			Message: fmt.Sprintf("token %s amount is not covered by the %s wei limit; set %s for it", token, action, RuleMaxTokenAmount),
+		}
+	}
+	return nil
+}
+
+func parseWei(s string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, fmt.Errorf("invalid wei amount %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,177 @@
+package policy_test
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"trustflow/src/internal/policy"
+	"trustflow/src/internal/simulator"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func candidate(to string, wei int64) *simulator.TxCandidate {
+	addr := common.HexToAddress(to)
+	return &simulator.TxCandidate{ToAddress: &addr, Value: big.NewInt(wei)}
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	engine, err := policy.NewEngine(policy.Rules{
+		MaxValuePerTx:     "1000",
+		MaxValuePerStep:   map[string]string{"payment": "500"},
+		AllowedActions:    []string{"payment"},
+		AllowedRecipients: []string{"0x71C7656EC7ab88b098defB751B7401B5f6d8976F"},
+		MaxGas:            50000,
+	})
+	require.NoError(t, err)
+
+	allowed := "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+
+	t.Run("Allowed", func(t *testing.T) {
+		assert.Nil(t, engine.Evaluate("payment", candidate(allowed, 100), 21000))
+	})
+
+	t.Run("Action Not Allowed", func(t *testing.T) {
+		v := engine.Evaluate("swap", candidate(allowed, 100), 21000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleAllowedActions, v.Rule)
+	})
+
+	t.Run("Step Limit", func(t *testing.T) {
+		v := engine.Evaluate("payment", candidate(allowed, 600), 21000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleMaxValuePerStep, v.Rule)
+	})
+
+	t.Run("Recipient Not Allowed", func(t *testing.T) {
+		v := engine.Evaluate("payment", candidate("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", 100), 21000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleAllowedRecipients, v.Rule)
+	})
+
+	t.Run("Token Recipient Checked", func(t *testing.T) {
+		token := candidate("0x5FbDB2315678afecb367f032d93F642f64180aa3", 0)
+		beneficiary := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+		token.Recipient = &beneficiary
+
+		v := engine.Evaluate("payment", token, 21000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleAllowedRecipients, v.Rule)
+	})
+
+	t.Run("Gas Limit", func(t *testing.T) {
+		v := engine.Evaluate("payment", candidate(allowed, 100), 60000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleMaxGas, v.Rule)
+	})
+
+	t.Run("Nil Engine Allows Everything", func(t *testing.T) {
+		var none *policy.Engine
+		assert.Nil(t, none.Evaluate("anything", candidate(allowed, 1e18), 1e9))
+	})
+}
+
+func TestEngine_TxLimit(t *testing.T) {
+	engine, err := policy.NewEngine(policy.Rules{MaxValuePerTx: "1000"})
+	require.NoError(t, err)
+
+	v := engine.Evaluate("payment", candidate("0x71C7656EC7ab88b098defB751B7401B5f6d8976F", 1001), 21000)
+	require.NotNil(t, v)
+	assert.Equal(t, policy.RuleMaxValuePerTx, v.Rule)
+}
+
+func tokenCandidate(token string, amount *big.Int) *simulator.TxCandidate {
+	c := candidate(token, 0)
+	c.TokenAmount = amount
+	return c
+}
+
+func TestEngine_TokenLimit(t *testing.T) {
+	const token = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	const other = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+
+	t.Run("Large Transfer Blocked", func(t *testing.T) {
+		engine, err := policy.NewEngine(policy.Rules{
+			MaxValuePerTx:  "1000",
+			MaxTokenAmount: map[string]string{token: "1000000", policy.DefaultToken: "10"},
+		})
+		require.NoError(t, err)
+
+		assert.Nil(t, engine.Evaluate("erc20_transfer", tokenCandidate(token, big.NewInt(1000000)), 60000))
+
+		huge, _ := new(big.Int).SetString("1000000000000000000000000", 10)
+		v := engine.Evaluate("erc20_transfer", tokenCandidate(token, huge), 60000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleMaxTokenAmount, v.Rule)
+
+		v = engine.Evaluate("erc20_approve", tokenCandidate(other, big.NewInt(11)), 60000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleMaxTokenAmount, v.Rule)
+	})
+
+	t.Run("Value Limit Without Token Limit Blocks Tokens", func(t *testing.T) {
+		engine, err := policy.NewEngine(policy.Rules{MaxValuePerTx: "1000"})
+		require.NoError(t, err)
+
+		v := engine.Evaluate("erc20_transfer", tokenCandidate(token, big.NewInt(1)), 60000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleMaxValuePerTx, v.Rule)
+
+		assert.Nil(t, engine.Evaluate("erc20_approve", tokenCandidate(token, new(big.Int)), 60000), "revocations stay allowed")
+	})
+
+	t.Run("Step Limit Without Token Limit Blocks Tokens", func(t *testing.T) {
+		engine, err := policy.NewEngine(policy.Rules{MaxValuePerStep: map[string]string{"erc20_transfer": "0"}})
+		require.NoError(t, err)
+
+		v := engine.Evaluate("erc20_transfer", tokenCandidate(token, big.NewInt(1)), 60000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleMaxValuePerStep, v.Rule)
+
+		assert.Nil(t, engine.Evaluate("erc20_approve", tokenCandidate(token, big.NewInt(1)), 60000))
+	})
+
+	t.Run("Invalid Entries", func(t *testing.T) {
+		_, err := policy.NewEngine(policy.Rules{MaxTokenAmount: map[string]string{"usdc": "1"}})
+		assert.Error(t, err)
+		_, err = policy.NewEngine(policy.Rules{MaxTokenAmount: map[string]string{token: "-1"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("YAML", func(t *testing.T) {
+		path := filepath.Join(dir, "policy.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("max_value_per_tx: \"10\"\nallowed_actions: [payment]\n"), 0o600))
+
+		engine, err := policy.LoadFile(path)
+		require.NoError(t, err)
+		v := engine.Evaluate("payment", candidate("0x71C7656EC7ab88b098defB751B7401B5f6d8976F", 11), 21000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleMaxValuePerTx, v.Rule)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		path := filepath.Join(dir, "policy.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"max_gas": 30000}`), 0o600))
+
+		engine, err := policy.LoadFile(path)
+		require.NoError(t, err)
+		v := engine.Evaluate("payment", candidate("0x71C7656EC7ab88b098defB751B7401B5f6d8976F", 1), 40000)
+		require.NotNil(t, v)
+		assert.Equal(t, policy.RuleMaxGas, v.Rule)
+	})
+
+	t.Run("Invalid Amount", func(t *testing.T) {
+		path := filepath.Join(dir, "bad.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("max_value_per_tx: lots\n"), 0o600))
+
+		_, err := policy.LoadFile(path)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,308 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// EncodeCall ABI-encodes a call from a human-readable signature such as
+// "swap(address,uint256,uint256)" and a JSON array of arguments.
+// Tuples use Solidity syntax, e.g. "exec((address,uint256)[],bytes)", and are
+// passed as nested JSON arrays.
+func EncodeCall(signature string, rawArgs json.RawMessage) ([]byte, error) {
+	name, argTypes, err := parseSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make(abi.Arguments, len(argTypes))
+	for i, t := range argTypes {
+		marshaling, err := argumentMarshaling(fmt.Sprintf("arg%d", i), t)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := abi.NewType(marshaling.Type, "", marshaling.Components)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type %q: %w", t, err)
+		}
+		inputs[i] = abi.Argument{Name: marshaling.Name, Type: typ}
+	}
+
+	var args []interface{}
+	if len(bytes.TrimSpace(rawArgs)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(rawArgs))
+		decoder.UseNumber() // Keep uint256 values exact
+		if err := decoder.Decode(&args); err != nil {
+			return nil, fmt.Errorf("args must be a JSON array: %w", err)
+		}
+	}
+	if len(args) != len(inputs) {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name, len(inputs), len(args))
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := convertArg(inputs[i].Type, arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s): %w", i, inputs[i].Type.String(), err)
+		}
+		values[i] = v.Interface()
+	}
+
+	packed, err := inputs.Pack(values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	method := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil)
+	return append(method.ID, packed...), nil
+}
+
+// parseSignature splits "name(t1,t2,...)" into the name and top-level argument types
+func parseSignature(signature string) (string, []string, error) {
+	signature = strings.ReplaceAll(signature, " ", "")
+	open := strings.Index(signature, "(")
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return "", nil, fmt.Errorf("invalid function signature %q", signature)
+	}
+
+	name := signature[:open]
+	types, err := splitTypes(signature[open+1 : len(signature)-1])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid function signature %q: %w", signature, err)
+	}
+	return name, types, nil
+}
+
+// splitTypes splits a comma-separated type list, respecting nested tuples
+func splitTypes(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+
+	var types []string
+	depth, start := 0, 0
+	for i, ch := range list {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, errors.New("unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				types = append(types, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("unbalanced parentheses")
+	}
+	types = append(types, list[start:])
+
+	for _, t := range types {
+		if t == "" {
+			return nil, errors.New("empty argument type")
+		}
+	}
+	return types, nil
+}
+
+// argumentMarshaling turns a Solidity type string into go-ethereum's ABI description,
+// expanding "(t1,t2)[]" into a tuple with components
+func argumentMarshaling(name, t string) (abi.ArgumentMarshaling, error) {
+	if !strings.HasPrefix(t, "(") {
+		return abi.ArgumentMarshaling{Name: name, Type: canonicalType(t)}, nil
+	}
+
+	end := strings.LastIndex(t, ")")
+	inner, err := splitTypes(t[1:end])
+	if err != nil {
+		return abi.ArgumentMarshaling{}, err
+	}
+
+	components := make([]abi.ArgumentMarshaling, len(inner))
+	for i, c := range inner {
+		components[i], err = argumentMarshaling(fmt.Sprintf("field%d", i), c)
+		if err != nil {
+			return abi.ArgumentMarshaling{}, err
+		}
+	}
+	return abi.ArgumentMarshaling{Name: name, Type: "tuple" + t[end+1:], Components: components}, nil
+}
+
+// canonicalType expands the uint/int aliases, which go-ethereum does not accept
+func canonicalType(t string) string {
+	base, suffix := t, ""
+	if i := strings.Index(t, "["); i >= 0 {
+		base, suffix = t[:i], t[i:]
+	}
+	switch base {
+	case "uint":
+		base = "uint256"
+	case "int":
+		base = "int256"
+	}
+	return base + suffix
+}
+
+// convertArg converts a decoded JSON value into the Go type go-ethereum expects for t
+func convertArg(t abi.Type, value interface{}) (reflect.Value, error) {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		n, err := toBigInt(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if t.T == abi.UintTy && n.Sign() < 0 {
+			return reflect.Value{}, errors.New("negative value for unsigned type")
+		}
+		// uintN holds [0, 2^N-1] and intN holds [-2^(N-1), 2^(N-1)-1]
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size))
+		if t.T == abi.IntTy {
+			limit.Rsh(limit, 1)
+		}
+		if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return reflect.Value{}, fmt.Errorf("value %s overflows %d bits", n, t.Size)
+		}
+
+		goType := t.GetType()
+		if goType == reflect.TypeOf(&big.Int{}) {
+			return reflect.ValueOf(n), nil
+		}
+		v := reflect.New(goType).Elem()
+		if t.T == abi.IntTy {
+			v.SetInt(n.Int64())
+		} else {
+			v.SetUint(n.Uint64())
+		}
+		return v, nil
+
+	case abi.BoolTy:
+		switch b := value.(type) {
+		case bool:
+			return reflect.ValueOf(b), nil
+		case string:
+			if b == "true" || b == "false" {
+				return reflect.ValueOf(b == "true"), nil
+			}
+		}
+		return reflect.Value{}, errors.New("expected a boolean")
+
+	case abi.StringTy:
+		s, ok := value.(string)
+		if !ok {
+			return reflect.Value{}, errors.New("expected a string")
+		}
+		return reflect.ValueOf(s), nil
+
+	case abi.AddressTy:
+		s, ok := value.(string)
+		if !ok || !common.IsHexAddress(s) {
+			return reflect.Value{}, errors.New("expected a hex address")
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+
+	case abi.BytesTy:
+		b, err := toBytes(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b), nil
+
+	case abi.FixedBytesTy:
+		b, err := toBytes(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if len(b) != t.Size {
+			return reflect.Value{}, fmt.Errorf("expected %d bytes, got %d", t.Size, len(b))
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v, nil
+
+	case abi.SliceTy, abi.ArrayTy:
+		items, ok := value.([]interface{})
+		if !ok {
+			return reflect.Value{}, errors.New("expected an array")
+		}
+		var v reflect.Value
+		if t.T == abi.SliceTy {
+			v = reflect.MakeSlice(t.GetType(), len(items), len(items))
+		} else {
+			if len(items) != t.Size {
+				return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", t.Size, len(items))
+			}
+			v = reflect.New(t.GetType()).Elem()
+		}
+		for i, item := range items {
+			elem, err := convertArg(*t.Elem, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			v.Index(i).Set(elem)
+		}
+		return v, nil
+
+	case abi.TupleTy:
+		items, ok := value.([]interface{})
+		if !ok || len(items) != len(t.TupleElems) {
+			return reflect.Value{}, fmt.Errorf("expected a tuple of %d elements", len(t.TupleElems))
+		}
+		v := reflect.New(t.GetType()).Elem()
+		for i, item := range items {
+			field, err := convertArg(*t.TupleElems[i], item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %d: %w", i, err)
+			}
+			v.Field(i).Set(field)
+		}
+		return v, nil
+
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported type %s", t.String())
+	}
+}
+
+func toBigInt(value interface{}) (*big.Int, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, errors.New("expected an integer")
+	}
+
+	n, ok := new(big.Int).SetString(s, 0) // Accepts decimal and 0x-prefixed hex
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	return n, nil
+}
+
+func toBytes(value interface{}) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("expected a 0x-prefixed hex string")
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex bytes: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,113 @@
+package simulator_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"trustflow/src/internal/simulator"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeCall(t *testing.T) {
+	t.Run("Matches Known Selector", func(t *testing.T) {
+		data, err := simulator.EncodeCall("transfer(address,uint256)",
+			json.RawMessage(`["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "1000000000000000000000"]`))
+		require.NoError(t, err)
+		assert.Equal(t, "a9059cbb", hex.EncodeToString(data[:4]))
+
+		expected, _ := new(big.Int).SetString("1000000000000000000000", 10)
+		assert.Equal(t, expected, new(big.Int).SetBytes(data[4+32:]))
+	})
+
+	t.Run("Numbers, Aliases And Small Ints", func(t *testing.T) {
+		data, err := simulator.EncodeCall("setFee(uint, uint8, int32, bool)", json.RawMessage(`[42, "0xff", -7, true]`))
+		require.NoError(t, err)
+
+		reference, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"setFee","inputs":[
+			{"name":"a","type":"uint256"},{"name":"b","type":"uint8"},{"name":"c","type":"int32"},{"name":"d","type":"bool"}]}]`))
+		require.NoError(t, err)
+		expected, err := reference.Pack("setFee", big.NewInt(42), uint8(255), int32(-7), true)
+		require.NoError(t, err)
+		assert.Equal(t, expected, data)
+	})
+
+	t.Run("Tuples And Arrays", func(t *testing.T) {
+		data, err := simulator.EncodeCall("exec((address,uint256)[],bytes32,bytes)", json.RawMessage(`[
+			[["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "1"], ["0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "2"]],
+			"0x0000000000000000000000000000000000000000000000000000000000000001",
+			"0xdeadbeef"
+		]`))
+		require.NoError(t, err)
+
+		reference, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"exec","inputs":[
+			{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"value","type":"uint256"}]},
+			{"name":"salt","type":"bytes32"},{"name":"payload","type":"bytes"}]}]`))
+		require.NoError(t, err)
+		type call struct {
+			Target common.Address
+			Value  *big.Int
+		}
+		expected, err := reference.Pack("exec",
+			[]call{
+				{common.HexToAddress("0x71C7656EC7ab88b098defB751B7401B5f6d8976F"), big.NewInt(1)},
+				{common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e"), big.NewInt(2)},
+			},
+			common.BigToHash(big.NewInt(1)),
+			[]byte{0xde, 0xad, 0xbe, 0xef})
+		require.NoError(t, err)
+		assert.Equal(t, expected, data)
+	})
+
+	t.Run("Integer Bounds", func(t *testing.T) {
+		minInt256 := new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 255))
+		maxInt256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1))
+		for _, tc := range []struct {
+			signature string
+			arg       string
+			ok        bool
+		}{
+			{"set(int8)", "-128", true},
+			{"set(int8)", "127", true},
+			{"set(int8)", "-129", false},
+			{"set(int8)", "128", false},
+			{"set(uint8)", "255", true},
+			{"set(uint8)", "256", false},
+			{"set(int256)", minInt256.String(), true},
+			{"set(int256)", maxInt256.String(), true},
+			{"set(int256)", new(big.Int).Sub(minInt256, big.NewInt(1)).String(), false},
+			{"set(int256)", new(big.Int).Add(maxInt256, big.NewInt(1)).String(), false},
+			{"set(uint256)", simulator.MaxUint256.String(), true},
+			{"set(uint256)", new(big.Int).Add(simulator.MaxUint256, big.NewInt(1)).String(), false},
+		} {
+			_, err := simulator.EncodeCall(tc.signature, json.RawMessage(`["`+tc.arg+`"]`))
+			if tc.ok {
+				assert.NoError(t, err, "%s %s", tc.signature, tc.arg)
+			} else {
+				assert.ErrorContains(t, err, "overflows", "%s %s", tc.signature, tc.arg)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := simulator.EncodeCall("transfer(address,uint256)", json.RawMessage(`["0x71C7656EC7ab88b098defB751B7401B5f6d8976F"]`))
+		assert.ErrorContains(t, err, "expects 2 arguments")
+
+		_, err = simulator.EncodeCall("transfer(address,uint256", nil)
+		assert.ErrorContains(t, err, "invalid function signature")
+
+		_, err = simulator.EncodeCall("set(uint8)", json.RawMessage(`[256]`))
+		assert.ErrorContains(t, err, "overflows")
+
+		_, err = simulator.EncodeCall("set(uint256)", json.RawMessage(`["-1"]`))
+		assert.ErrorContains(t, err, "negative")
+
+		_, err = simulator.EncodeCall("set(address)", json.RawMessage(`["not-an-address"]`))
+		assert.ErrorContains(t, err, "hex address")
+	})
+}
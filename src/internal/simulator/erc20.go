@@ -0,0 +1,163 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// erc20ABIJSON covers the subset of the ERC-20 interface used by token actions, and the calls
+// a contract_call is decoded as
+const erc20ABIJSON = `[
+	{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"approve","stateMutability":"nonpayable","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"transferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"increaseAllowance","stateMutability":"nonpayable","inputs":[{"name":"spender","type":"address"},{"name":"addedValue","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"decimals","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]}
+]`
+
+var erc20ABI = mustParseABI(erc20ABIJSON)
+
+// MaxUint256 is type(uint256).max, the conventional "unlimited" allowance
+var MaxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+func mustParseABI(raw string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in ABI: %v", err))
+	}
+	return parsed
+}
+
+// decodeERC20Call fills in the recipient, token amount and allowance of a candidate whose
+// calldata is a well-known ERC-20 call, so a contract_call cannot move or approve tokens past
+// the checks erc20_transfer and erc20_approve get
+func decodeERC20Call(candidate *TxCandidate, allowUnlimited bool) error {
+	if len(candidate.Data) < 4 {
+		return nil
+	}
+	method, err := erc20ABI.MethodById(candidate.Data[:4])
+	if err != nil {
+		return nil // Not an ERC-20 call
+	}
+	switch method.Name {
+	case "transfer", "transferFrom", "approve", "increaseAllowance":
+	default:
+		return nil
+	}
+
+	args, err := method.Inputs.Unpack(candidate.Data[4:])
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", method.Sig, err)
+	}
+	// Every one of them ends in (address beneficiary, uint256 amount)
+	recipient := args[len(args)-2].(common.Address)
+	amount := args[len(args)-1].(*big.Int)
+	candidate.Recipient = &recipient
+	candidate.TokenAmount = amount
+	if method.Name == "approve" || method.Name == "increaseAllowance" {
+		candidate.UnlimitedApproval = amount.Cmp(MaxUint256) == 0
+		candidate.AllowUnlimited = allowUnlimited
+	}
+	return nil
+}
+
+// DecimalsResolver looks up the decimals() of an ERC-20 token so human amounts can be scaled
+type DecimalsResolver interface {
+	TokenDecimals(ctx context.Context, token common.Address) (uint8, error)
+}
+
+// parseTokenAmount converts an amount into base units. Integers are taken as base units;
+// amounts with a decimal point (e.g. "1.5") are scaled by the token's decimals. Zero is only
+// accepted with allowZero, as approving 0 revokes an allowance.
+func parseTokenAmount(ctx context.Context, params map[string]string, token common.Address, resolver DecimalsResolver, allowZero bool) (*big.Int, error) {
+	amountStr, ok := params["amount"]
+	if !ok || amountStr == "" {
+		return nil, errors.New("missing amount parameter")
+	}
+
+	if !strings.Contains(amountStr, ".") {
+		amount, success := new(big.Int).SetString(amountStr, 10)
+		if !success {
+			return nil, errors.New("invalid amount format (must be decimal number)")
+		}
+		return checkTokenAmount(amount, allowZero)
+	}
+
+	// Human-readable amount: resolve decimals from params or on-chain
+	var decimals uint8
+	if raw, ok := params["decimals"]; ok && raw != "" {
+		d, success := new(big.Int).SetString(raw, 10)
+		if !success || d.Sign() < 0 || d.Cmp(big.NewInt(77)) > 0 {
+			return nil, errors.New("invalid decimals parameter")
+		}
+		decimals = uint8(d.Uint64())
+	} else {
+		if resolver == nil {
+			return nil, errors.New("decimal amount requires a decimals parameter or on-chain lookup")
+		}
+		d, err := resolver.TokenDecimals(ctx, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve token decimals: %w", err)
+		}
+		decimals = d
+	}
+
+	amount, err := scaleDecimal(amountStr, decimals)
+	if err != nil {
+		return nil, err
+	}
+	return checkTokenAmount(amount, allowZero)
+}
+
+func checkTokenAmount(amount *big.Int, allowZero bool) (*big.Int, error) {
+	if allowZero && amount.Sign() < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+	if !allowZero && amount.Sign() <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	return amount, nil
+}
+
+// scaleDecimal converts "1.5" with 18 decimals into 1500000000000000000 without float rounding
+func scaleDecimal(s string, decimals uint8) (*big.Int, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > int(decimals) {
+		return nil, fmt.Errorf("amount %s has more than %d decimal places", s, decimals)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	digits := whole + frac + strings.Repeat("0", int(decimals)-len(frac))
+	amount, ok := new(big.Int).SetString(digits, 10)
+	if !ok || strings.ContainsAny(digits, "+-") {
+		return nil, errors.New("invalid amount format (must be decimal number)")
+	}
+	return amount, nil
+}
+
+// TokenDecimals calls decimals() on an ERC-20 token
+func (s *Simulator) TokenDecimals(ctx context.Context, token common.Address) (uint8, error) {
+	data, err := erc20ABI.Pack("decimals")
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := s.client.CallContract(ctx, token, data)
+	if err != nil {
+		return 0, fmt.Errorf("decimals() call failed: %w", err)
+	}
+
+	values, err := erc20ABI.Unpack("decimals", out)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode decimals(): %w", err)
+	}
+	return values[0].(uint8), nil
+}
@@ -1,9 +1,12 @@
 package simulator
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 	"trustflow/src/pkg/types"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -11,14 +14,63 @@ import (
 
 // ParseIntent converts a generic high-level Intent into a low-level TxCandidate
 func ParseIntent(intent types.Intent) (*TxCandidate, error) {
+	return ParseIntentContext(context.Background(), intent, nil)
+}
+
+// ParseIntentContext is ParseIntent with an optional resolver for on-chain lookups
+// (e.g. token decimals). A nil resolver restricts parsing to what the params provide.
+func ParseIntentContext(ctx context.Context, intent types.Intent, resolver DecimalsResolver) (*TxCandidate, error) {
+	candidate, err := parseAction(ctx, intent, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Optional ABI used to decode custom errors if the simulation reverts
+	errorABI := intent.TypedParams["error_abi"]
+	if errorABI == nil && intent.Params["error_abi"] != "" {
+		errorABI = json.RawMessage(intent.Params["error_abi"])
+	}
+	if errorABI != nil {
+		candidate.ErrorABI, err = parseErrorABI(errorABI)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return candidate, nil
+}
+
+func parseAction(ctx context.Context, intent types.Intent, resolver DecimalsResolver) (*TxCandidate, error) {
 	switch intent.Action {
 	case "payment":
 		return parsePayment(intent.Params)
+	case "erc20_transfer":
+		return parseERC20Transfer(ctx, intent.Params, resolver)
+	case "erc20_approve":
+		return parseERC20Approve(ctx, intent.Params, resolver)
+	case "contract_call":
+		return parseContractCall(intent.Params, intent.TypedParams)
 	default:
 		return nil, fmt.Errorf("unknown action type: %s", intent.Action)
 	}
 }
 
+// Parse converts an intent using the connected chain to resolve token metadata
+func (s *Simulator) Parse(ctx context.Context, intent types.Intent) (*TxCandidate, error) {
+	return ParseIntentContext(ctx, intent, s)
+}
+
+func parseAddressParam(params map[string]string, name string) (common.Address, error) {
+	value, ok := params[name]
+	if !ok || value == "" {
+		return common.Address{}, fmt.Errorf("missing %s parameter", name)
+	}
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("invalid %s address format", name)
+	}
+	return common.HexToAddress(value), nil
+}
+
 func parsePayment(params map[string]string) (*TxCandidate, error) {
 	// 1. Validate Recipient
 	recipientStr, ok := params["recipient"]
@@ -52,3 +104,125 @@ func parsePayment(params map[string]string) (*TxCandidate, error) {
 		Data:      nil, // Native transfer has no data
 	}, nil
 }
+
+func parseERC20Transfer(ctx context.Context, params map[string]string, resolver DecimalsResolver) (*TxCandidate, error) {
+	// 1. Validate Token & Recipient
+	token, err := parseAddressParam(params, "token")
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := parseAddressParam(params, "recipient")
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Validate Amount (base units, or human-readable scaled by decimals)
+	amount, err := parseTokenAmount(ctx, params, token, resolver, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Encode transfer(address,uint256)
+	data, err := erc20ABI.Pack("transfer", recipient, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer: %w", err)
+	}
+
+	return &TxCandidate{
+		ToAddress:   &token,
+		Value:       big.NewInt(0), // Tokens move via calldata, not native value
+		Data:        data,
+		Recipient:   &recipient,
+		TokenAmount: amount,
+	}, nil
+}
+
+func parseERC20Approve(ctx context.Context, params map[string]string, resolver DecimalsResolver) (*TxCandidate, error) {
+	// 1. Validate Token & Spender
+	token, err := parseAddressParam(params, "token")
+	if err != nil {
+		return nil, err
+	}
+	spender, err := parseAddressParam(params, "spender")
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Validate Amount ("max"/"unlimited" request type(uint256).max)
+	var amount *big.Int
+	switch strings.ToLower(params["amount"]) {
+	case "max", "unlimited":
+		amount = new(big.Int).Set(MaxUint256)
+	default:
+		amount, err = parseTokenAmount(ctx, params, token, resolver, true) // 0 revokes the allowance
+		if err != nil {
+			return nil, err
+		}
+		if amount.Cmp(MaxUint256) > 0 {
+			return nil, errors.New("amount exceeds uint256")
+		}
+	}
+
+	// 3. Encode approve(address,uint256)
+	data, err := erc20ABI.Pack("approve", spender, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode approve: %w", err)
+	}
+
+	return &TxCandidate{
+		ToAddress:         &token,
+		Value:             big.NewInt(0),
+		Data:              data,
+		Recipient:         &spender,
+		TokenAmount:       amount,
+		UnlimitedApproval: amount.Cmp(MaxUint256) == 0,
+		AllowUnlimited:    params["allow_unlimited"] == "true",
+	}, nil
+}
+
+func parseContractCall(params map[string]string, typed map[string]json.RawMessage) (*TxCandidate, error) {
+	// 1. Validate Contract
+	contract, err := parseAddressParam(params, "contract")
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Encode Function Call
+	signature, ok := params["function"]
+	if !ok || signature == "" {
+		return nil, errors.New("missing function parameter")
+	}
+
+	// Args may be nested JSON in typed_params, or a JSON-encoded string in params
+	args := typed["args"]
+	if args == nil && params["args"] != "" {
+		args = json.RawMessage(params["args"])
+	}
+
+	data, err := EncodeCall(signature, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", signature, err)
+	}
+
+	// 3. Optional Native Value (payable functions)
+	value := big.NewInt(0)
+	if valueStr := params["value"]; valueStr != "" {
+		var success bool
+		value, success = new(big.Int).SetString(valueStr, 10)
+		if !success || value.Sign() < 0 {
+			return nil, errors.New("invalid value format (must be decimal integer)")
+		}
+	}
+
+	candidate := &TxCandidate{
+		ToAddress: &contract,
+		Value:     value,
+		Data:      data,
+	}
+
+	// 4. ERC-20 transfers and approvals get the same recipient and allowance checks as the token actions
+	if err := decodeERC20Call(candidate, params["allow_unlimited"] == "true"); err != nil {
+		return nil, err
+	}
+	return candidate, nil
+}
@@ -1,12 +1,17 @@
 package simulator_test
 
 import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
 	"math/big"
 	"testing"
 	"trustflow/src/internal/simulator"
 	"trustflow/src/pkg/types"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseIntent(t *testing.T) {
@@ -65,3 +70,313 @@ func TestParseIntent(t *testing.T) {
 		assert.Contains(t, err.Error(), "invalid amount")
 	})
 }
+
+type fixedDecimals uint8
+
+func (d fixedDecimals) TokenDecimals(ctx context.Context, token common.Address) (uint8, error) {
+	return uint8(d), nil
+}
+
+func TestParseIntent_ERC20Transfer(t *testing.T) {
+	const token = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	const recipient = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+
+	t.Run("Base Units", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_transfer",
+			Params: map[string]string{
+				"token":     token,
+				"recipient": recipient,
+				"amount":    "1000",
+			},
+		}
+
+		candidate, err := simulator.ParseIntent(intent)
+		require.NoError(t, err)
+		assert.Equal(t, token, candidate.ToAddress.Hex())
+		assert.Equal(t, recipient, candidate.Recipient.Hex())
+		assert.Equal(t, big.NewInt(0), candidate.Value)
+
+		// transfer(address,uint256) selector followed by two 32-byte words
+		require.Len(t, candidate.Data, 4+64)
+		assert.Equal(t, "a9059cbb", hex.EncodeToString(candidate.Data[:4]))
+		assert.Equal(t, common.HexToAddress(recipient).Bytes(), candidate.Data[4+12:4+32])
+		assert.Equal(t, big.NewInt(1000), new(big.Int).SetBytes(candidate.Data[4+32:]))
+	})
+
+	t.Run("Human Amount With Decimals Param", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_transfer",
+			Params: map[string]string{
+				"token":     token,
+				"recipient": recipient,
+				"amount":    "1.5",
+				"decimals":  "6",
+			},
+		}
+
+		candidate, err := simulator.ParseIntent(intent)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(1500000), new(big.Int).SetBytes(candidate.Data[4+32:]))
+	})
+
+	t.Run("Human Amount Resolved On-Chain", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_transfer",
+			Params: map[string]string{
+				"token":     token,
+				"recipient": recipient,
+				"amount":    "0.25",
+			},
+		}
+
+		candidate, err := simulator.ParseIntentContext(context.Background(), intent, fixedDecimals(18))
+		require.NoError(t, err)
+		expected, _ := new(big.Int).SetString("250000000000000000", 10)
+		assert.Equal(t, expected, new(big.Int).SetBytes(candidate.Data[4+32:]))
+	})
+
+	t.Run("Human Amount Without Decimals", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_transfer",
+			Params: map[string]string{
+				"token":     token,
+				"recipient": recipient,
+				"amount":    "1.5",
+			},
+		}
+
+		_, err := simulator.ParseIntent(intent)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "decimals")
+	})
+
+	t.Run("Too Many Decimal Places", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_transfer",
+			Params: map[string]string{
+				"token":     token,
+				"recipient": recipient,
+				"amount":    "1.1234567",
+				"decimals":  "6",
+			},
+		}
+
+		_, err := simulator.ParseIntent(intent)
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing Token", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_transfer",
+			Params: map[string]string{
+				"recipient": recipient,
+				"amount":    "1",
+			},
+		}
+
+		_, err := simulator.ParseIntent(intent)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "missing token")
+	})
+}
+
+func TestParseIntent_ERC20Approve(t *testing.T) {
+	const token = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	const spender = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+
+	t.Run("Bounded Allowance", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_approve",
+			Params: map[string]string{
+				"token":   token,
+				"spender": spender,
+				"amount":  "5000",
+			},
+		}
+
+		candidate, err := simulator.ParseIntent(intent)
+		require.NoError(t, err)
+		assert.Equal(t, "095ea7b3", hex.EncodeToString(candidate.Data[:4]))
+		assert.Equal(t, spender, candidate.Recipient.Hex())
+		assert.False(t, candidate.UnlimitedApproval)
+		assert.Empty(t, candidate.Warnings())
+	})
+
+	t.Run("Unlimited Refused Without Opt-In", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_approve",
+			Params: map[string]string{
+				"token":   token,
+				"spender": spender,
+				"amount":  "max",
+			},
+		}
+
+		candidate, err := simulator.ParseIntent(intent)
+		require.NoError(t, err)
+		assert.True(t, candidate.UnlimitedApproval)
+		assert.False(t, candidate.AllowUnlimited)
+		assert.Equal(t, simulator.MaxUint256, new(big.Int).SetBytes(candidate.Data[4+32:]))
+
+		// The guard fires before any RPC call, so no client is needed
+		_, err = simulator.NewSimulator(nil).Simulate(context.Background(), candidate)
+		assert.ErrorIs(t, err, simulator.ErrUnlimitedApproval)
+	})
+
+	t.Run("Explicit MaxUint256 Detected", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_approve",
+			Params: map[string]string{
+				"token":           token,
+				"spender":         spender,
+				"amount":          simulator.MaxUint256.String(),
+				"allow_unlimited": "true",
+			},
+		}
+
+		candidate, err := simulator.ParseIntent(intent)
+		require.NoError(t, err)
+		assert.True(t, candidate.UnlimitedApproval)
+		assert.True(t, candidate.AllowUnlimited)
+		assert.Len(t, candidate.Warnings(), 1)
+	})
+
+	t.Run("Zero Revokes Allowance", func(t *testing.T) {
+		for _, amount := range []string{"0", "0.0"} {
+			intent := types.Intent{
+				Action: "erc20_approve",
+				Params: map[string]string{
+					"token":    token,
+					"spender":  spender,
+					"amount":   amount,
+					"decimals": "6",
+				},
+			}
+
+			candidate, err := simulator.ParseIntent(intent)
+			require.NoError(t, err, amount)
+			assert.Equal(t, 0, candidate.TokenAmount.Sign())
+			assert.Equal(t, make([]byte, 32), candidate.Data[4+32:], "approve(spender, 0)")
+			assert.False(t, candidate.UnlimitedApproval)
+		}
+
+		// A transfer of nothing is still refused
+		_, err := simulator.ParseIntent(types.Intent{
+			Action: "erc20_transfer",
+			Params: map[string]string{"token": token, "recipient": spender, "amount": "0"},
+		})
+		assert.ErrorContains(t, err, "amount must be positive")
+		_, err = simulator.ParseIntent(types.Intent{
+			Action: "erc20_approve",
+			Params: map[string]string{"token": token, "spender": spender, "amount": "-1"},
+		})
+		assert.ErrorContains(t, err, "amount must not be negative")
+	})
+
+	t.Run("Missing Spender", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "erc20_approve",
+			Params: map[string]string{
+				"token":  token,
+				"amount": "1",
+			},
+		}
+
+		_, err := simulator.ParseIntent(intent)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "missing spender")
+	})
+}
+
+func TestParseIntent_ContractCall(t *testing.T) {
+	const contract = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+
+	t.Run("Typed Args", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "contract_call",
+			Params: map[string]string{
+				"contract": contract,
+				"function": "swap(address,uint256,uint256)",
+				"value":    "10",
+			},
+			TypedParams: map[string]json.RawMessage{
+				"args": json.RawMessage(`["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "100", 95]`),
+			},
+		}
+
+		candidate, err := simulator.ParseIntent(intent)
+		require.NoError(t, err)
+		assert.Equal(t, contract, candidate.ToAddress.Hex())
+		assert.Equal(t, big.NewInt(10), candidate.Value)
+		assert.Len(t, candidate.Data, 4+3*32)
+	})
+
+	t.Run("JSON Encoded String Args", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "contract_call",
+			Params: map[string]string{
+				"contract": contract,
+				"function": "transfer(address,uint256)",
+				"args":     `["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "1000"]`,
+			},
+		}
+
+		candidate, err := simulator.ParseIntent(intent)
+		require.NoError(t, err)
+		assert.Equal(t, "a9059cbb", hex.EncodeToString(candidate.Data[:4]))
+		assert.Equal(t, big.NewInt(0), candidate.Value)
+	})
+
+	t.Run("ERC-20 Transfer Decoded", func(t *testing.T) {
+		// The policy recipient allow-list and approval gate must see the token recipient, not the contract
+		intent := types.Intent{
+			Action: "contract_call",
+			Params: map[string]string{
+				"contract": contract,
+				"function": "transferFrom(address,address,uint256)",
+				"args":     `["0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "1000"]`,
+			},
+		}
+
+		candidate, err := simulator.ParseIntent(intent)
+		require.NoError(t, err)
+		assert.Equal(t, "0x71C7656EC7ab88b098defB751B7401B5f6d8976F", candidate.Beneficiary().Hex())
+		assert.Equal(t, big.NewInt(1000), candidate.TokenAmount)
+		assert.False(t, candidate.UnlimitedApproval)
+	})
+
+	t.Run("Unlimited Approve Flagged", func(t *testing.T) {
+		for _, function := range []string{"approve(address,uint256)", "increaseAllowance(address,uint256)"} {
+			intent := types.Intent{
+				Action: "contract_call",
+				Params: map[string]string{
+					"contract": contract,
+					"function": function,
+					"args":     `["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "` + simulator.MaxUint256.String() + `"]`,
+				},
+			}
+
+			candidate, err := simulator.ParseIntent(intent)
+			require.NoError(t, err)
+			assert.True(t, candidate.UnlimitedApproval, function)
+			assert.False(t, candidate.AllowUnlimited, function)
+			assert.Equal(t, "0x71C7656EC7ab88b098defB751B7401B5f6d8976F", candidate.Recipient.Hex())
+
+			intent.Params["allow_unlimited"] = "true"
+			candidate, err = simulator.ParseIntent(intent)
+			require.NoError(t, err)
+			assert.True(t, candidate.AllowUnlimited, function)
+		}
+	})
+
+	t.Run("Missing Function", func(t *testing.T) {
+		intent := types.Intent{
+			Action: "contract_call",
+			Params: map[string]string{"contract": contract},
+		}
+
+		_, err := simulator.ParseIntent(intent)
+		assert.ErrorContains(t, err, "missing function")
+	})
+}
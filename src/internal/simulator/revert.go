@@ -0,0 +1,173 @@
+package simulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Revert codes reported in SimulationError.Code
+const (
+	RevertCodeError   = "error"        // require/revert with a reason string: Error(string)
+	RevertCodePanic   = "panic"        // assert failure, overflow, etc.: Panic(uint256)
+	RevertCodeCustom  = "custom_error" // Custom error matched against the intent's error ABI
+	RevertCodeUnknown = "unknown"      // No revert data, or data we could not decode
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// panicReasons mirrors the Solidity panic codes
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "pop on an empty array",
+	0x32: "out-of-bounds array access",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+// SimulationError is returned when a dry run reverts, with the revert reason decoded
+type SimulationError struct {
+	Code   string // One of the RevertCode constants
+	Reason string // Human-readable decoded reason
+	Data   []byte // Raw revert data returned by the node
+	Err    error  // Underlying RPC error
+}
+
+func (e *SimulationError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("simulation failed (transaction would revert): %v", e.Err)
+	}
+	return fmt.Sprintf("simulation failed (transaction would revert): %s: %s", e.Code, e.Reason)
+}
+
+func (e *SimulationError) Unwrap() error {
+	return e.Err
+}
+
+// Details converts the error into its API representation
+func (e *SimulationError) Details() *types.RevertDetails {
+	details := &types.RevertDetails{
+		Code:   e.Code,
+		Reason: e.Reason,
+	}
+	if len(e.Data) > 0 {
+		details.Data = hexutil.Encode(e.Data)
+	}
+	return details
+}
+
+// newSimulationError decodes the revert data carried by an RPC error, if any
+func newSimulationError(err error, errorABI *abi.ABI) *SimulationError {
+	simErr := &SimulationError{Code: RevertCodeUnknown, Err: err}
+
+	data := revertData(err)
+	if len(data) == 0 {
+		return simErr
+	}
+
+	simErr.Data = data
+	simErr.Code, simErr.Reason = DecodeRevert(data, errorABI)
+	return simErr
+}
+
+// revertData extracts the hex revert payload that geth-style nodes attach to JSON-RPC errors
+func revertData(err error) []byte {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil
+	}
+
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil
+	}
+
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil {
+		return nil
+	}
+	return data
+}
+
+// DecodeRevert turns raw revert data into a code and readable reason.
+// errorABI is optional and used to match custom errors.
+func DecodeRevert(data []byte, errorABI *abi.ABI) (string, string) {
+	if len(data) < 4 {
+		return RevertCodeUnknown, ""
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		reason, err := abi.UnpackRevert(data)
+		if err != nil {
+			return RevertCodeUnknown, ""
+		}
+		return RevertCodeError, reason
+
+	case bytes.Equal(data[:4], panicSelector):
+		if len(data) < 4+32 {
+			return RevertCodeUnknown, ""
+		}
+		code := new(big.Int).SetBytes(data[4 : 4+32])
+		reason := "unknown panic"
+		if code.IsUint64() {
+			if known, ok := panicReasons[code.Uint64()]; ok {
+				reason = known
+			}
+		}
+		return RevertCodePanic, fmt.Sprintf("%s (code %#x)", reason, code)
+	}
+
+	if errorABI != nil {
+		var selector [4]byte
+		copy(selector[:], data[:4])
+		if abiErr, err := errorABI.ErrorByID(selector); err == nil {
+			if reason, err := formatCustomError(abiErr, data); err == nil {
+				return RevertCodeCustom, reason
+			}
+		}
+	}
+
+	return RevertCodeUnknown, ""
+}
+
+// formatCustomError renders a custom error as Name(arg=value, ...)
+func formatCustomError(abiErr *abi.Error, data []byte) (string, error) {
+	unpacked, err := abiErr.Unpack(data)
+	if err != nil {
+		return "", err
+	}
+
+	values, _ := unpacked.([]interface{})
+	args := make([]string, len(values))
+	for i, v := range values {
+		args[i] = fmt.Sprintf("%s=%v", abiErr.Inputs[i].Name, v)
+	}
+	return fmt.Sprintf("%s(%s)", abiErr.Name, strings.Join(args, ", ")), nil
+}
+
+// parseErrorABI accepts a JSON ABI (array of entries) describing custom errors
+func parseErrorABI(raw []byte) (*abi.ABI, error) {
+	parsed, err := abi.JSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid error_abi: %w", err)
+	}
+	return &parsed, nil
+}
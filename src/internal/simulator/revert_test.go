@@ -0,0 +1,95 @@
+package simulator_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"trustflow/src/internal/simulator"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const customErrorsABI = `[{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}]`
+
+func TestDecodeRevert(t *testing.T) {
+	t.Run("Error String", func(t *testing.T) {
+		data, err := simulator.EncodeCall("Error(string)", json.RawMessage(`["ERC20: transfer amount exceeds balance"]`))
+		require.NoError(t, err)
+
+		code, reason := simulator.DecodeRevert(data, nil)
+		assert.Equal(t, simulator.RevertCodeError, code)
+		assert.Equal(t, "ERC20: transfer amount exceeds balance", reason)
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		data, err := simulator.EncodeCall("Panic(uint256)", json.RawMessage(`["0x11"]`))
+		require.NoError(t, err)
+
+		code, reason := simulator.DecodeRevert(data, nil)
+		assert.Equal(t, simulator.RevertCodePanic, code)
+		assert.Equal(t, "arithmetic underflow or overflow (code 0x11)", reason)
+	})
+
+	t.Run("Custom Error", func(t *testing.T) {
+		parsed, err := abi.JSON(strings.NewReader(customErrorsABI))
+		require.NoError(t, err)
+		data, err := simulator.EncodeCall("InsufficientBalance(uint256,uint256)", json.RawMessage(`[5, 10]`))
+		require.NoError(t, err)
+
+		code, reason := simulator.DecodeRevert(data, &parsed)
+		assert.Equal(t, simulator.RevertCodeCustom, code)
+		assert.Equal(t, "InsufficientBalance(available=5, required=10)", reason)
+
+		// Without the ABI the selector cannot be matched
+		code, reason = simulator.DecodeRevert(data, nil)
+		assert.Equal(t, simulator.RevertCodeUnknown, code)
+		assert.Empty(t, reason)
+	})
+
+	t.Run("Empty Data", func(t *testing.T) {
+		code, _ := simulator.DecodeRevert(nil, nil)
+		assert.Equal(t, simulator.RevertCodeUnknown, code)
+	})
+}
+
+func TestSimulationError(t *testing.T) {
+	rpcErr := errors.New("execution reverted")
+	simErr := &simulator.SimulationError{
+		Code:   simulator.RevertCodeError,
+		Reason: "not owner",
+		Data:   []byte{0x08, 0xc3, 0x79, 0xa0},
+		Err:    rpcErr,
+	}
+
+	assert.ErrorIs(t, simErr, rpcErr)
+	assert.Contains(t, simErr.Error(), "error: not owner")
+	assert.Equal(t, &types.RevertDetails{Code: "error", Reason: "not owner", Data: "0x08c379a0"}, simErr.Details())
+}
+
+func TestParseIntent_ErrorABI(t *testing.T) {
+	intent := types.Intent{
+		Action: "contract_call",
+		Params: map[string]string{
+			"contract": "0x5FbDB2315678afecb367f032d93F642f64180aa3",
+			"function": "withdraw(uint256)",
+			"args":     `["1"]`,
+		},
+		TypedParams: map[string]json.RawMessage{
+			"error_abi": json.RawMessage(customErrorsABI),
+		},
+	}
+
+	candidate, err := simulator.ParseIntent(intent)
+	require.NoError(t, err)
+	require.NotNil(t, candidate.ErrorABI)
+
+	assert.Contains(t, candidate.ErrorABI.Errors, "InsufficientBalance")
+
+	intent.TypedParams["error_abi"] = json.RawMessage(`{not json`)
+	_, err = simulator.ParseIntent(intent)
+	assert.ErrorContains(t, err, "invalid error_abi")
+}
@@ -2,13 +2,16 @@ package simulator
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"math/big"
 	"trustflow/src/internal/chain"
 
 	"github.com/ethereum/go-ethereum"
 )
 
+// ErrUnlimitedApproval is returned when an intent requests a type(uint256).max allowance without opting in
+var ErrUnlimitedApproval = errors.New("unlimited token approval refused (set allow_unlimited=true to opt in)")
+
 type Simulator struct {
 	client *chain.ChainClient
 }
@@ -19,6 +22,11 @@ func NewSimulator(client *chain.ChainClient) *Simulator {
 
 // Simulate runs a transaction candidate against the chain to check for validity and estimate gas
 func (s *Simulator) Simulate(ctx context.Context, candidate *TxCandidate) (uint64, error) {
+	// Unlimited allowances granted by an agent are the biggest drain risk: refuse unless opted in
+	if candidate.UnlimitedApproval && !candidate.AllowUnlimited {
+		return 0, ErrUnlimitedApproval
+	}
+
 	from := s.client.GetAddress()
 
 	callMsg := ethereum.CallMsg{
@@ -31,8 +39,8 @@ func (s *Simulator) Simulate(ctx context.Context, candidate *TxCandidate) (uint6
 	// If EstimateGas succeeds, it means the transaction didn't revert.
 	gasLimit, err := s.client.EstimateGas(ctx, callMsg)
 	if err != nil {
-		// We wrap the error to give context (e.g. "execution reverted")
-		return 0, fmt.Errorf("simulation failed (transaction would revert): %w", err)
+		// Decode the revert reason (Error(string), Panic(uint256) or a custom error)
+		return 0, newSimulationError(err, candidate.ErrorABI)
 	}
 
 	return gasLimit, nil
@@ -40,32 +48,20 @@ func (s *Simulator) Simulate(ctx context.Context, candidate *TxCandidate) (uint6
 
 // CheckSolvency ensures the wallet has enough funds for Value + GasCost
 func (s *Simulator) CheckSolvency(ctx context.Context, gasLimit uint64, value *big.Int) error {
-	// 1. Get Gas Price
-	gasPrice, err := s.client.SuggestGasPrice(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to fetch gas price: %w", err)
-	}
-
-	// 2. Calculate Total Cost: Value + (GasLimit * GasPrice)
-	cost := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasPrice)
-	totalReq := new(big.Int).Add(value, cost)
+	_, err := s.CheckWorkflowSolvency(ctx, []*TxCandidate{{Value: value}}, []uint64{gasLimit})
+	return err
+}
 
-	// 3. Get Balance
-	balance, err := s.client.GetBalance(ctx)
+// GetGasPrice retrieves the most a transaction sent now would pay per gas
+func (s *Simulator) GetGasPrice(ctx context.Context) (*big.Int, error) {
+	fees, err := s.client.SuggestFees(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch balance: %w", err)
-	}
-
-	// 4. Compare
-	if balance.Cmp(totalReq) < 0 {
-		return fmt.Errorf("insufficient funds: have %s wei, want %s wei (Gas Cost: %s, Value: %s)",
-			balance.String(), totalReq.String(), cost.String(), value.String())
+		return nil, err
 	}
-
-	return nil
+	return fees.MaxPricePerGas(), nil
 }
 
-// GetGasPrice retrieves the current gas price from the chain
-func (s *Simulator) GetGasPrice(ctx context.Context) (*big.Int, error) {
-	return s.client.SuggestGasPrice(ctx)
+// SuggestFees retrieves the fee parameters a transaction sent now would use
+func (s *Simulator) SuggestFees(ctx context.Context) (*chain.FeeParams, error) {
+	return s.client.SuggestFees(ctx)
 }
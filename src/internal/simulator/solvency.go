@@ -0,0 +1,91 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"trustflow/src/pkg/types"
+)
+
+// ErrInsufficientFunds is wrapped by solvency failures
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// CheckWorkflowSolvency ensures the wallet can pay for every step: the sum of all values
+// plus gasLimit * gasPrice for each. The report breaks the cost down per step and is
+// returned alongside the error when the wallet falls short.
+func (s *Simulator) CheckWorkflowSolvency(ctx context.Context, candidates []*TxCandidate, gasLimits []uint64) (*types.SolvencyReport, error) {
+	// 1. Get Gas Price (the EIP-1559 fee cap: the node checks funds against the worst case)
+	gasPrice, err := s.GetGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch gas price: %w", err)
+	}
+
+	// 2. Get Balance
+	balance, err := s.client.GetBalance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch balance: %w", err)
+	}
+
+	// 3. Accumulate Value + (GasLimit * GasPrice) step by step
+	report := BuildSolvencyReport(balance, gasPrice, candidates, gasLimits)
+	if report.Solvent {
+		return report, nil
+	}
+
+	totalGasCost := new(big.Int)
+	totalValue := new(big.Int)
+	for i, candidate := range candidates {
+		totalGasCost.Add(totalGasCost, new(big.Int).Mul(new(big.Int).SetUint64(gasLimits[i]), gasPrice))
+		if candidate.Value != nil {
+			totalValue.Add(totalValue, candidate.Value)
+		}
+	}
+	return report, fmt.Errorf("%w: have %s wei, want %s wei (Gas Cost: %s, Value: %s)",
+		ErrInsufficientFunds, balance.String(), report.TotalRequired, totalGasCost.String(), totalValue.String())
+}
+
+// BuildSolvencyReport computes the per-step cost breakdown against a known balance and gas price
+func BuildSolvencyReport(balance, gasPrice *big.Int, candidates []*TxCandidate, gasLimits []uint64) *types.SolvencyReport {
+	report := &types.SolvencyReport{
+		Solvent:  true,
+		Balance:  balance.String(),
+		GasPrice: gasPrice.String(),
+		Steps:    make([]types.StepCost, len(candidates)),
+	}
+
+	cumulative := new(big.Int)
+	for i, candidate := range candidates {
+		value := candidate.Value
+		if value == nil {
+			value = new(big.Int)
+		}
+		gasCost := new(big.Int).Mul(new(big.Int).SetUint64(gasLimits[i]), gasPrice)
+		cumulative.Add(cumulative, value)
+		cumulative.Add(cumulative, gasCost)
+
+		step := types.StepCost{
+			StepIndex:  i,
+			Value:      value.String(),
+			GasLimit:   gasLimits[i],
+			GasCost:    gasCost.String(),
+			Cumulative: cumulative.String(),
+		}
+		if cumulative.Cmp(balance) > 0 {
+			step.Shortfall = new(big.Int).Sub(cumulative, balance).String()
+			if report.Solvent {
+				report.Solvent = false
+				firstIdx := i
+				report.FirstShortfallAt = &firstIdx
+			}
+		}
+		report.Steps[i] = step
+	}
+
+	report.TotalRequired = cumulative.String()
+	if !report.Solvent {
+		report.Shortfall = new(big.Int).Sub(cumulative, balance).String()
+	}
+	return report
+}
@@ -0,0 +1,81 @@
+package simulator_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"trustflow/src/internal/simulator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSolvencyReport(t *testing.T) {
+	steps := []*simulator.TxCandidate{
+		payment("0x71C7656EC7ab88b098defB751B7401B5f6d8976F", 1000),
+		payment("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", 2000),
+	}
+
+	t.Run("Solvent", func(t *testing.T) {
+		report := simulator.BuildSolvencyReport(big.NewInt(10000), big.NewInt(2), steps, []uint64{100, 100})
+		assert.True(t, report.Solvent)
+		assert.Equal(t, "3400", report.TotalRequired)
+		assert.Nil(t, report.FirstShortfallAt)
+		assert.Equal(t, "1200", report.Steps[0].Cumulative)
+		assert.Equal(t, "200", report.Steps[1].GasCost)
+	})
+
+	t.Run("Second Step Short", func(t *testing.T) {
+		report := simulator.BuildSolvencyReport(big.NewInt(2000), big.NewInt(2), steps, []uint64{100, 100})
+		assert.False(t, report.Solvent)
+		require.NotNil(t, report.FirstShortfallAt)
+		assert.Equal(t, 1, *report.FirstShortfallAt)
+		assert.Empty(t, report.Steps[0].Shortfall)
+		assert.Equal(t, "1400", report.Steps[1].Shortfall)
+		assert.Equal(t, "1400", report.Shortfall)
+	})
+}
+
+func TestCheckWorkflowSolvency(t *testing.T) {
+	client := fakeNode(t, func(method string, params json.RawMessage) (interface{}, *rpcError) {
+		switch method {
+		case "eth_getBlockByNumber":
+			return legacyHeader(), nil // Pre-London: no base fee, legacy gas price
+		case "eth_gasPrice":
+			return "0x1", nil
+		case "eth_getBalance":
+			return "0xc350", nil // 50000 wei
+		}
+		t.Fatalf("unexpected method %s", method)
+		return nil, nil
+	})
+	sim := simulator.NewSimulator(client)
+
+	steps := []*simulator.TxCandidate{
+		payment("0x71C7656EC7ab88b098defB751B7401B5f6d8976F", 5000),
+		payment("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", 5000),
+	}
+
+	report, err := sim.CheckWorkflowSolvency(context.Background(), steps, []uint64{21000, 21000})
+	assert.ErrorIs(t, err, simulator.ErrInsufficientFunds)
+	require.NotNil(t, report)
+	assert.Equal(t, 1, *report.FirstShortfallAt)
+	assert.Equal(t, "2000", report.Shortfall)
+
+	// A single step fits within the balance on its own
+	err = sim.CheckSolvency(context.Background(), 21000, big.NewInt(5000))
+	assert.NoError(t, err)
+}
+
+// legacyHeader is a minimal pre-London block header
+func legacyHeader() map[string]interface{} {
+	zero := "0x" + strings.Repeat("0", 64)
+	return map[string]interface{}{
+		"parentHash": zero, "sha3Uncles": zero, "miner": "0x" + strings.Repeat("0", 40),
+		"stateRoot": zero, "transactionsRoot": zero, "receiptsRoot": zero,
+		"logsBloom": "0x" + strings.Repeat("0", 512), "difficulty": "0x0", "number": "0x10",
+		"gasLimit": "0x1c9c380", "gasUsed": "0x0", "timestamp": "0x1", "extraData": "0x",
+	}
+}
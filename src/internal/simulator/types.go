@@ -1,15 +1,41 @@
 package simulator
 
 import (
+	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 )
 
 // TxCandidate represents a transaction that has been parsed but not yet signed or broadcast.
 // It serves as the intermediate format for simulation.
 type TxCandidate struct {
-	ToAddress *common.Address // Pointer because it can be nil (contract creation)
-	Value     *big.Int        // Amount in Wei
-	Data      []byte          // Call data (for smart contracts) or empty (for payments)
+	ToAddress   *common.Address // Pointer because it can be nil (contract creation)
+	Value       *big.Int        // Amount in Wei
+	Data        []byte          // Call data (for smart contracts) or empty (for payments)
+	Recipient   *common.Address // Final beneficiary when it differs from ToAddress (e.g. token transfers)
+	TokenAmount *big.Int        // ERC-20 base units transferred or approved; nil for other calls
+
+	UnlimitedApproval bool // Grants a type(uint256).max allowance
+	AllowUnlimited    bool // The intent explicitly opted in to an unlimited allowance
+
+	ErrorABI *abi.ABI // Optional user-supplied ABI for decoding custom revert errors
+}
+
+// Warnings lists risky properties of the candidate that the caller accepted
+func (c *TxCandidate) Warnings() []string {
+	var warnings []string
+	if c.UnlimitedApproval && c.AllowUnlimited {
+		warnings = append(warnings, fmt.Sprintf("unlimited allowance granted to %s", c.Recipient.Hex()))
+	}
+	return warnings
+}
+
+// Beneficiary returns the address that ultimately receives funds
+func (c *TxCandidate) Beneficiary() *common.Address {
+	if c.Recipient != nil {
+		return c.Recipient
+	}
+	return c.ToAddress
 }
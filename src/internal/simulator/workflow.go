@@ -0,0 +1,141 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"trustflow/src/internal/chain"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// WorkflowError identifies the step that made a workflow simulation fail
+type WorkflowError struct {
+	StepIndex int
+	Err       error
+}
+
+func (e *WorkflowError) Error() string {
+	return fmt.Sprintf("step %d: %v", e.StepIndex+1, e.Err)
+}
+
+func (e *WorkflowError) Unwrap() error {
+	return e.Err
+}
+
+// ErrDependentSteps is returned when the node cannot simulate a workflow's steps together and
+// a later step relies on the state an earlier one leaves behind
+var ErrDependentSteps = errors.New("depends on an earlier step, which the node cannot simulate without eth_simulateV1")
+
+// SimulateWorkflow dry-runs every step in order against the state left behind by the
+// previous ones, so a workflow is rejected before its first broadcast if any later step
+// would revert. It returns a gas limit per step. On nodes without eth_simulateV1 the steps
+// are simulated one by one against the current state instead, and independent reports it;
+// workflows whose steps depend on each other are then refused with ErrDependentSteps.
+func (s *Simulator) SimulateWorkflow(ctx context.Context, candidates []*TxCandidate) (gasLimits []uint64, independent bool, err error) {
+	for i, candidate := range candidates {
+		if candidate.UnlimitedApproval && !candidate.AllowUnlimited {
+			return nil, false, &WorkflowError{StepIndex: i, Err: ErrUnlimitedApproval}
+		}
+	}
+
+	from := s.client.GetAddress()
+	calls := make([]ethereum.CallMsg, len(candidates))
+	for i, candidate := range candidates {
+		calls[i] = ethereum.CallMsg{
+			From:  from,
+			To:    candidate.ToAddress,
+			Value: candidate.Value,
+			Data:  candidate.Data,
+		}
+	}
+
+	results, err := s.client.SimulateCalls(ctx, calls)
+	if errors.Is(err, chain.ErrSimulateUnsupported) {
+		if i := dependentStep(candidates); i >= 0 {
+			return nil, true, &WorkflowError{StepIndex: i, Err: ErrDependentSteps}
+		}
+		independent = len(candidates) > 1 // A single step sees the same state either way
+		if independent {
+			log.Printf("⚠️ Node lacks eth_simulateV1, simulating %d steps independently", len(candidates))
+		}
+		gasLimits, err := s.simulateIndependently(ctx, candidates)
+		return gasLimits, independent, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("workflow simulation failed: %w", err)
+	}
+
+	gasLimits = make([]uint64, len(results))
+	for i, result := range results {
+		if result.Error != nil || result.Status != 1 {
+			return nil, false, &WorkflowError{StepIndex: i, Err: callResultError(result, candidates[i])}
+		}
+		gasLimits[i] = gasLimitFromUsed(result.GasUsed, candidates[i])
+	}
+	return gasLimits, false, nil
+}
+
+// simulateIndependently estimates each step against the current state only.
+// Used when the node cannot carry state across calls.
+func (s *Simulator) simulateIndependently(ctx context.Context, candidates []*TxCandidate) ([]uint64, error) {
+	gasLimits := make([]uint64, len(candidates))
+	for i, candidate := range candidates {
+		gasLimit, err := s.Simulate(ctx, candidate)
+		if err != nil {
+			return nil, &WorkflowError{StepIndex: i, Err: err}
+		}
+		gasLimits[i] = gasLimit
+	}
+	return gasLimits, nil
+}
+
+// dependentStep returns the first step that calls a contract an earlier step called or named
+// as its recipient or spender (e.g. approve then transferFrom or swap), or -1 if none does.
+// Plain transfers only share the wallet balance, which the solvency check covers.
+func dependentStep(candidates []*TxCandidate) int {
+	touched := make(map[common.Address]bool)
+	for i, candidate := range candidates {
+		if len(candidate.Data) > 0 && candidate.ToAddress != nil && touched[*candidate.ToAddress] {
+			return i
+		}
+		if len(candidate.Data) > 0 && candidate.ToAddress != nil {
+			touched[*candidate.ToAddress] = true
+		}
+		if candidate.Recipient != nil {
+			touched[*candidate.Recipient] = true
+		}
+	}
+	return -1
+}
+
+// callResultError converts a failed simulated call into a SimulationError
+func callResultError(result ethclient.SimulateCallResult, candidate *TxCandidate) error {
+	if result.Error == nil {
+		return &SimulationError{Code: RevertCodeUnknown, Err: errors.New("execution reverted")}
+	}
+
+	simErr := &SimulationError{
+		Code: RevertCodeUnknown,
+		Err:  errors.New(result.Error.Message),
+	}
+	if data, err := hexutil.Decode(result.Error.Data); err == nil && len(data) > 0 {
+		simErr.Data = data
+		simErr.Code, simErr.Reason = DecodeRevert(data, candidate.ErrorABI)
+	}
+	return simErr
+}
+
+// gasLimitFromUsed pads the gas a simulated call consumed, since refunds and the 63/64
+// rule mean contract calls need more gas than they end up using
+func gasLimitFromUsed(gasUsed uint64, candidate *TxCandidate) uint64 {
+	if len(candidate.Data) == 0 {
+		return gasUsed // Plain transfers use exactly what they need
+	}
+	return gasUsed * 13 / 10
+}
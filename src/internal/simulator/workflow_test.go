@@ -0,0 +1,197 @@
+package simulator_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"trustflow/src/internal/chain"
+	"trustflow/src/internal/config"
+	"trustflow/src/internal/simulator"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPrivateKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+// fakeNode answers the JSON-RPC methods the simulator relies on
+func fakeNode(t *testing.T, handle func(method string, params json.RawMessage) (interface{}, *rpcError)) *chain.ChainClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if req.Method == "eth_chainId" {
+			resp["result"] = "0x1"
+		} else if result, rpcErr := handle(req.Method, req.Params); rpcErr != nil {
+			resp["error"] = rpcErr
+		} else {
+			resp["result"] = result
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := chain.NewChainClient(&config.Config{RPCURL: server.URL, PrivateKey: testPrivateKey})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func payment(to string, wei int64) *simulator.TxCandidate {
+	addr := common.HexToAddress(to)
+	return &simulator.TxCandidate{ToAddress: &addr, Value: big.NewInt(wei)}
+}
+
+func TestSimulateWorkflow(t *testing.T) {
+	steps := []*simulator.TxCandidate{
+		payment("0x71C7656EC7ab88b098defB751B7401B5f6d8976F", 100),
+		payment("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", 200),
+	}
+
+	t.Run("Cumulative Success", func(t *testing.T) {
+		client := fakeNode(t, func(method string, params json.RawMessage) (interface{}, *rpcError) {
+			require.Equal(t, "eth_simulateV1", method)
+			return []map[string]interface{}{{
+				"number": "0x10", "timestamp": "0x1", "gasLimit": "0x1c9c380", "gasUsed": "0xa410",
+				"calls": []map[string]interface{}{
+					{"returnData": "0x", "logs": []interface{}{}, "gasUsed": "0x5208", "status": "0x1"},
+					{"returnData": "0x", "logs": []interface{}{}, "gasUsed": "0x5208", "status": "0x1"},
+				},
+			}}, nil
+		})
+
+		gasLimits, independent, err := simulator.NewSimulator(client).SimulateWorkflow(context.Background(), steps)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{21000, 21000}, gasLimits)
+		assert.False(t, independent)
+	})
+
+	t.Run("Later Step Reverts", func(t *testing.T) {
+		revert, err := simulator.EncodeCall("Error(string)", json.RawMessage(`["insufficient balance"]`))
+		require.NoError(t, err)
+
+		client := fakeNode(t, func(method string, params json.RawMessage) (interface{}, *rpcError) {
+			return []map[string]interface{}{{
+				"number": "0x10", "timestamp": "0x1", "gasLimit": "0x1c9c380", "gasUsed": "0x5208",
+				"calls": []map[string]interface{}{
+					{"returnData": "0x", "logs": []interface{}{}, "gasUsed": "0x5208", "status": "0x1"},
+					{"returnData": "0x", "logs": []interface{}{}, "gasUsed": "0x0", "status": "0x0",
+						"error": map[string]interface{}{"code": 3, "message": "execution reverted", "data": hexutil.Encode(revert)}},
+				},
+			}}, nil
+		})
+
+		_, _, err = simulator.NewSimulator(client).SimulateWorkflow(context.Background(), steps)
+		var wfErr *simulator.WorkflowError
+		require.True(t, errors.As(err, &wfErr))
+		assert.Equal(t, 1, wfErr.StepIndex)
+
+		var simErr *simulator.SimulationError
+		require.True(t, errors.As(err, &simErr))
+		assert.Equal(t, simulator.RevertCodeError, simErr.Code)
+		assert.Equal(t, "insufficient balance", simErr.Reason)
+	})
+
+	t.Run("Unlimited contract_call Approve Refused", func(t *testing.T) {
+		approve, err := simulator.ParseIntent(types.Intent{
+			Action: "contract_call",
+			Params: map[string]string{
+				"contract": "0x5FbDB2315678afecb367f032d93F642f64180aa3",
+				"function": "approve(address,uint256)",
+				"args":     `["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "` + simulator.MaxUint256.String() + `"]`,
+			},
+		})
+		require.NoError(t, err)
+
+		client := fakeNode(t, func(method string, params json.RawMessage) (interface{}, *rpcError) {
+			t.Fatalf("unexpected method %s", method)
+			return nil, nil
+		})
+		_, _, err = simulator.NewSimulator(client).SimulateWorkflow(context.Background(), []*simulator.TxCandidate{steps[0], approve})
+		var wfErr *simulator.WorkflowError
+		require.True(t, errors.As(err, &wfErr))
+		assert.Equal(t, 1, wfErr.StepIndex)
+		assert.ErrorIs(t, err, simulator.ErrUnlimitedApproval)
+	})
+
+	t.Run("Falls Back Without eth_simulateV1", func(t *testing.T) {
+		var estimates int
+		client := fakeNode(t, func(method string, params json.RawMessage) (interface{}, *rpcError) {
+			switch method {
+			case "eth_simulateV1":
+				return nil, &rpcError{Code: -32601, Message: "the method eth_simulateV1 does not exist/is not available"}
+			case "eth_estimateGas":
+				estimates++
+				return "0x5208", nil
+			}
+			t.Fatalf("unexpected method %s", method)
+			return nil, nil
+		})
+
+		gasLimits, independent, err := simulator.NewSimulator(client).SimulateWorkflow(context.Background(), steps)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{21000, 21000}, gasLimits)
+		assert.Equal(t, 2, estimates)
+		assert.True(t, independent)
+	})
+
+	t.Run("Other Unsupported Wordings", func(t *testing.T) {
+		client := fakeNode(t, func(method string, params json.RawMessage) (interface{}, *rpcError) {
+			if method == "eth_simulateV1" {
+				return nil, &rpcError{Code: -32000, Message: "Method not supported on this plan"}
+			}
+			return "0x5208", nil
+		})
+
+		_, independent, err := simulator.NewSimulator(client).SimulateWorkflow(context.Background(), steps)
+		require.NoError(t, err)
+		assert.True(t, independent)
+	})
+
+	t.Run("Dependent Steps Refused Without eth_simulateV1", func(t *testing.T) {
+		// approve then transferFrom on the same token only succeeds against cumulative state
+		const token = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+		var candidates []*simulator.TxCandidate
+		for _, call := range []struct{ function, args string }{
+			{"approve(address,uint256)", `["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "100"]`},
+			{"transferFrom(address,address,uint256)", `["0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "100"]`},
+		} {
+			candidate, err := simulator.ParseIntent(types.Intent{Action: "contract_call", Params: map[string]string{
+				"contract": token, "function": call.function, "args": call.args,
+			}})
+			require.NoError(t, err)
+			candidates = append(candidates, candidate)
+		}
+
+		client := fakeNode(t, func(method string, params json.RawMessage) (interface{}, *rpcError) {
+			require.Equal(t, "eth_simulateV1", method)
+			return nil, &rpcError{Code: -32601, Message: "the method eth_simulateV1 does not exist/is not available"}
+		})
+		_, independent, err := simulator.NewSimulator(client).SimulateWorkflow(context.Background(), candidates)
+		assert.True(t, independent)
+		assert.ErrorIs(t, err, simulator.ErrDependentSteps)
+		var wfErr *simulator.WorkflowError
+		require.True(t, errors.As(err, &wfErr))
+		assert.Equal(t, 1, wfErr.StepIndex)
+	})
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"trustflow/src/pkg/types"
+)
+
+// Audit anchor statuses
+const (
+	AnchorSubmitted = "submitted"
+	AnchorConfirmed = "confirmed"
+	AnchorFailed    = "failed"
+)
+
+const selectAnchor = `
+        SELECT id, from_seq, to_seq, root, tx_hash, status, COALESCE(block_number, 0),
+               COALESCE(error, ''), created_at, COALESCE(confirmed_at, 0)
+        FROM audit_anchors`
+
+// AnchoredThrough returns the last audit event up to which every event is covered by an anchor
+// that has not failed. Anchors are sent one after another, so a failed one can leave a gap
+// below later ones: coverage stops at the first gap, and its events are anchored again.
+func (s *Storage) AnchoredThrough() (int64, error) {
+	rows, err := s.db.Query("SELECT from_seq, to_seq FROM audit_anchors WHERE status != ? ORDER BY from_seq ASC", AnchorFailed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch anchored range: %w", err)
+	}
+	defer rows.Close()
+
+	var through int64
+	for rows.Next() {
+		var from, to int64
+		if err := rows.Scan(&from, &to); err != nil {
+			return 0, err
+		}
+		if from > through {
+			break
+		}
+		through = max(through, to)
+	}
+	return through, rows.Err()
+}
+
+// AnchorTx is the transaction an anchor was sent in, kept so it can be replaced at the same
+// nonce if it gets stuck
+type AnchorTx struct {
+	Nonce    uint64
+	To       string
+	GasLimit uint64
+	Fees     *types.FeeParams // Fees of the latest broadcast, original or replacement
+}
+
+// PendingAnchor is a submitted anchor with the transactions broadcast for it
+type PendingAnchor struct {
+	types.AuditAnchor
+	Tx           *AnchorTx // nil for anchors recorded before their transaction was kept
+	Replacements []string  // Hashes of the replacements at its nonce, oldest first
+	BroadcastAt  int64     // Unix time of the latest broadcast
+}
+
+// Hashes lists every transaction broadcast for the anchor: the original and its replacements
+func (p *PendingAnchor) Hashes() []string {
+	return append([]string{p.TxHash}, p.Replacements...)
+}
+
+// SaveAnchor records an anchor as submitted before its transaction is broadcast, setting its ID
+func (s *Storage) SaveAnchor(anchor *types.AuditAnchor, tx AnchorTx) error {
+	anchor.Status = AnchorSubmitted
+	rawFees, _ := json.Marshal(tx.Fees)
+	result, err := s.db.Exec(`
+        INSERT INTO audit_anchors (from_seq, to_seq, root, tx_hash, status, created_at, nonce, to_address, gas_limit, fees, broadcast_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		anchor.FromSeq, anchor.ToSeq, anchor.Root, anchor.TxHash, anchor.Status, anchor.CreatedAt,
+		tx.Nonce, tx.To, tx.GasLimit, string(rawFees), anchor.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save anchor: %w", err)
+	}
+	anchor.ID, err = result.LastInsertId()
+	return err
+}
+
+// AddAnchorReplacement records a transaction that replaced an anchor's pending one at the same
+// nonce. The anchor's fees and broadcast time move to the replacement so later bumps build on it.
+func (s *Storage) AddAnchorReplacement(id int64, txHash string, fees *types.FeeParams) error {
+	rawFees, _ := json.Marshal(fees)
+	if _, err := s.db.Exec(`
+        UPDATE audit_anchors
+        SET replacements = json_insert(COALESCE(replacements, '[]'), '$[#]', ?), fees = ?, broadcast_at = ?
+        WHERE id = ?`, txHash, string(rawFees), time.Now().Unix(), id); err != nil {
+		return fmt.Errorf("failed to save anchor replacement: %w", err)
+	}
+	return nil
+}
+
+// UpdateAnchor records an anchor's outcome: confirmed in a block by txHash, the original or a
+// replacement, or failed
+func (s *Storage) UpdateAnchor(id int64, status string, txHash string, blockNumber uint64, errMsg string, at int64) error {
+	var confirmedAt sql.NullInt64
+	if status == AnchorConfirmed {
+		confirmedAt = sql.NullInt64{Int64: at, Valid: true}
+	}
+	if _, err := s.db.Exec("UPDATE audit_anchors SET status = ?, tx_hash = ?, block_number = ?, error = ?, confirmed_at = ? WHERE id = ?",
+		status, txHash, blockNumber, errMsg, confirmedAt, id); err != nil {
+		return fmt.Errorf("failed to update anchor: %w", err)
+	}
+	return nil
+}
+
+// ListPendingAnchors returns the submitted anchors with their transactions, oldest first
+func (s *Storage) ListPendingAnchors() ([]PendingAnchor, error) {
+	rows, err := s.db.Query(`
+        SELECT id, from_seq, to_seq, root, tx_hash, status, created_at,
+               nonce, COALESCE(to_address, ''), COALESCE(gas_limit, 0), fees, replacements, COALESCE(broadcast_at, created_at)
+        FROM audit_anchors
+        WHERE status = ?
+        ORDER BY id ASC`, AnchorSubmitted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch anchors: %w", err)
+	}
+	defer rows.Close()
+
+	var anchors []PendingAnchor
+	for rows.Next() {
+		var anchor PendingAnchor
+		var tx AnchorTx
+		var nonce sql.NullInt64
+		var fees, replacements sql.NullString
+		if err := rows.Scan(&anchor.ID, &anchor.FromSeq, &anchor.ToSeq, &anchor.Root, &anchor.TxHash, &anchor.Status, &anchor.CreatedAt,
+			&nonce, &tx.To, &tx.GasLimit, &fees, &replacements, &anchor.BroadcastAt); err != nil {
+			return nil, err
+		}
+		if nonce.Valid && fees.Valid && fees.String != "null" {
+			tx.Nonce = uint64(nonce.Int64)
+			tx.Fees = &types.FeeParams{}
+			if err := json.Unmarshal([]byte(fees.String), tx.Fees); err != nil {
+				return nil, fmt.Errorf("corrupt anchor fees: %w", err)
+			}
+			anchor.Tx = &tx
+		}
+		if replacements.Valid {
+			if err := json.Unmarshal([]byte(replacements.String), &anchor.Replacements); err != nil {
+				return nil, fmt.Errorf("corrupt anchor replacements: %w", err)
+			}
+		}
+		anchors = append(anchors, anchor)
+	}
+	return anchors, rows.Err()
+}
+
+// FindAnchor returns the anchor covering an audit event that has not failed, or nil if the
+// event is not anchored yet
+func (s *Storage) FindAnchor(seq int64) (*types.AuditAnchor, error) {
+	anchor, err := scanAnchor(s.db.QueryRow(selectAnchor+`
+        WHERE from_seq < ? AND to_seq >= ? AND status != ?
+        ORDER BY id DESC LIMIT 1`, seq, seq, AnchorFailed))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch anchor: %w", err)
+	}
+	return anchor, nil
+}
+
+// ListIntentAuditEvents returns an intent's audit events, oldest first
+func (s *Storage) ListIntentAuditEvents(intentID string) ([]types.AuditEvent, error) {
+	rows, err := s.db.Query(`
+        SELECT seq, intent_id, payload, prev_hash, hash, created_at
+        FROM audit_events
+        WHERE intent_id = ?
+        ORDER BY seq ASC`, intentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []types.AuditEvent
+	for rows.Next() {
+		var event types.AuditEvent
+		if err := rows.Scan(&event.Seq, &event.IntentID, &event.Payload, &event.PrevHash, &event.Hash, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func scanAnchor(row interface{ Scan(...any) error }) (*types.AuditAnchor, error) {
+	var anchor types.AuditAnchor
+	if err := row.Scan(&anchor.ID, &anchor.FromSeq, &anchor.ToSeq, &anchor.Root, &anchor.TxHash, &anchor.Status,
+		&anchor.BlockNumber, &anchor.Error, &anchor.CreatedAt, &anchor.ConfirmedAt); err != nil {
+		return nil, err
+	}
+	return &anchor, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"trustflow/src/pkg/types"
+)
+
+const selectAPIKey = `
+        SELECT id, prefix, name, address, scopes, created_by, created_at,
+               expires_at, COALESCE(last_used_at, 0), COALESCE(revoked_at, 0)
+        FROM api_keys`
+
+// SaveAPIKey stores an API key, keyed by the hash of the key itself (which is not stored)
+func (s *Storage) SaveAPIKey(key types.APIKey, keyHash string) error {
+	scopes, _ := json.Marshal(key.Scopes)
+	_, err := s.db.Exec(`
+        INSERT INTO api_keys (id, key_hash, prefix, name, address, scopes, created_by, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, keyHash, key.Prefix, key.Name, key.Address, string(scopes), key.CreatedBy, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	return nil
+}
+
+// UseAPIKey looks up the live key with the given hash and records that it was used at now.
+// It returns nil if there is no such key, or it was revoked or expired.
+func (s *Storage) UseAPIKey(keyHash string, now int64) (*types.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(`
+        UPDATE api_keys SET last_used_at = ?
+        WHERE key_hash = ? AND revoked_at IS NULL AND (expires_at = 0 OR expires_at > ?)
+        RETURNING id, prefix, name, address, scopes, created_by, created_at,
+                  expires_at, COALESCE(last_used_at, 0), COALESCE(revoked_at, 0)`,
+		now, keyHash, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns the API keys bound to an address, or all keys if address is empty,
+// newest first and including revoked ones
+func (s *Storage) ListAPIKeys(address string) ([]types.APIKey, error) {
+	rows, err := s.db.Query(selectAPIKey+`
+        WHERE ? = '' OR address = ?
+        ORDER BY created_at DESC, id ASC`, address, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []types.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes a key at now, reporting whether a live key with that ID existed
+func (s *Storage) RevokeAPIKey(id string, now int64) (bool, error) {
+	result, err := s.db.Exec("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", now, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*types.APIKey, error) {
+	var key types.APIKey
+	var scopes string
+	if err := row.Scan(&key.ID, &key.Prefix, &key.Name, &key.Address, &scopes, &key.CreatedBy, &key.CreatedAt,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("corrupt API key scopes: %w", err)
+	}
+	return &key, nil
+}
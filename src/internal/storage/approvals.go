@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"trustflow/src/pkg/types"
+)
+
+// Approval decisions
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+var (
+	// ErrApprovalClosed is returned when an intent is no longer awaiting approval, or its approval expired
+	ErrApprovalClosed = errors.New("intent is not awaiting approval")
+	// ErrAlreadyDecided is returned when an approver has already approved or rejected an intent
+	ErrAlreadyDecided = errors.New("approver has already decided on this intent")
+)
+
+// RequestApproval records the approval an intent needs and parks it as awaiting_approval.
+// An intent re-processed while still awaiting keeps its original request and deadline.
+func (s *Storage) RequestApproval(intentID string, userID string, approval *types.ApprovalState) error {
+	log.Printf("✋ Requesting Approval: IntentID=%s, Required=%d of %d", intentID, approval.Required, len(approval.Approvers))
+	approvers, _ := json.Marshal(approval.Approvers)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+        INSERT INTO intent_approvals (intent_id, user_id, reason, risk_score, total_value, required, approvers, requested_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(intent_id) DO NOTHING`,
+		intentID, userID, approval.Reason, approval.RiskScore, approval.TotalValue, approval.Required, string(approvers),
+		approval.RequestedAt, approval.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to save approval request: %w", err)
+	}
+	message := fmt.Sprintf("Awaiting %d of %d approvals: %s", approval.Required, len(approval.Approvers), approval.Reason)
+	if _, err := tx.Exec("UPDATE intents SET status = 'awaiting_approval', message = ? WHERE id = ? AND user_id = ?",
+		message, intentID, userID); err != nil {
+		return fmt.Errorf("failed to update intent status: %w", err)
+	}
+	event := intentEvent(intentID, userID, "awaiting_approval", message)
+	if err := appendEvent(tx, &event); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.publish(event)
+	return nil
+}
+
+// GetApproval returns the approval an intent needed, its decisions and the intent's owner,
+// or nil if the intent never needed approval
+func (s *Storage) GetApproval(intentID string) (*types.ApprovalState, string, error) {
+	var state types.ApprovalState
+	var userID, approvers string
+	var approvedAt sql.NullInt64
+	err := s.db.QueryRow(`
+        SELECT user_id, reason, risk_score, total_value, required, approvers, requested_at, expires_at, approved_at
+        FROM intent_approvals
+        WHERE intent_id = ?`, intentID).
+		Scan(&userID, &state.Reason, &state.RiskScore, &state.TotalValue, &state.Required, &approvers, &state.RequestedAt, &state.ExpiresAt, &approvedAt)
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch approval: %w", err)
+	}
+	state.ApprovedAt = approvedAt.Int64
+	if err := json.Unmarshal([]byte(approvers), &state.Approvers); err != nil {
+		return nil, "", fmt.Errorf("corrupt approvers: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+        SELECT approver, decision, comment, created_at
+        FROM approval_decisions
+        WHERE intent_id = ?
+        ORDER BY id ASC`, intentID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch approval decisions: %w", err)
+	}
+	defer rows.Close()
+
+	state.Decisions = []types.ApprovalDecision{}
+	for rows.Next() {
+		var d types.ApprovalDecision
+		var comment sql.NullString
+		if err := rows.Scan(&d.Approver, &d.Decision, &comment, &d.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		d.Comment = comment.String
+		state.Decisions = append(state.Decisions, d)
+	}
+	return &state, userID, rows.Err()
+}
+
+// DecideApproval records an approver's decision and applies its outcome in the same transaction,
+// so a decision is always on record before the intent moves on. A rejection ends the intent;
+// the approval that reaches the required count puts it back in the queue. It returns the
+// intent's resulting status: rejected, pending or awaiting_approval.
+func (s *Storage) DecideApproval(intentID string, approver string, decision string, comment string, now int64) (string, error) {
+	log.Printf("✍️ Recording Decision: IntentID=%s, Approver=%s, Decision=%s", intentID, approver, decision)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var status, userID string
+	var required int
+	var expiresAt int64
+	err = tx.QueryRow(`
+        SELECT i.status, i.user_id, a.required, a.expires_at
+        FROM intents i JOIN intent_approvals a ON a.intent_id = i.id
+        WHERE i.id = ?`, intentID).Scan(&status, &userID, &required, &expiresAt)
+	if err == sql.ErrNoRows {
+		return "", ErrApprovalClosed
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch approval: %w", err)
+	}
+	if status != "awaiting_approval" || expiresAt <= now {
+		return "", fmt.Errorf("%w (status %s)", ErrApprovalClosed, status)
+	}
+
+	var decided int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM approval_decisions WHERE intent_id = ? AND approver = ?",
+		intentID, approver).Scan(&decided); err != nil {
+		return "", err
+	}
+	if decided > 0 {
+		return "", ErrAlreadyDecided
+	}
+	if _, err := tx.Exec(`
+        INSERT INTO approval_decisions (intent_id, approver, decision, comment, created_at)
+        VALUES (?, ?, ?, ?, ?)`, intentID, approver, decision, comment, now); err != nil {
+		return "", fmt.Errorf("failed to save decision: %w", err)
+	}
+	if err := appendAudit(tx, auditRecord{
+		UserID: userID, IntentID: intentID, Kind: AuditKindApproval, Status: decision, Message: comment, Actor: approver, CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+
+	var events []types.StatusEvent
+	switch decision {
+	case DecisionReject:
+		status = "rejected"
+		message := fmt.Sprintf("Rejected by %s", approver)
+		if _, err := tx.Exec("UPDATE intents SET status = ?, message = ? WHERE id = ?",
+			status, message, intentID); err != nil {
+			return "", err
+		}
+		skipped, err := skipPendingSteps(tx, intentID, userID, -1)
+		if err != nil {
+			return "", err
+		}
+		events = append([]types.StatusEvent{intentEvent(intentID, userID, status, message)}, skipped...)
+	case DecisionApprove:
+		var approvals int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM approval_decisions WHERE intent_id = ? AND decision = ?",
+			intentID, DecisionApprove).Scan(&approvals); err != nil {
+			return "", err
+		}
+		if approvals >= required {
+			status = "pending"
+			if _, err := tx.Exec("UPDATE intent_approvals SET approved_at = ? WHERE intent_id = ?", now, intentID); err != nil {
+				return "", err
+			}
+			message := fmt.Sprintf("Approved by %d of %d required approvers", approvals, required)
+			if _, err := tx.Exec("UPDATE intents SET status = ?, message = ? WHERE id = ?",
+				status, message, intentID); err != nil {
+				return "", err
+			}
+			events = append(events, intentEvent(intentID, userID, status, message))
+		}
+	default:
+		return "", fmt.Errorf("unknown decision %q", decision)
+	}
+
+	for i := range events {
+		if err := appendEvent(tx, &events[i]); err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	s.publish(events...)
+	return status, nil
+}
+
+// ExpireApprovals ends every intent still awaiting approval past its deadline and returns their IDs
+func (s *Storage) ExpireApprovals(now int64) ([]string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const message = "Approval expired before enough approvers agreed"
+	rows, err := tx.Query(`
+        UPDATE intents
+        SET status = 'expired', message = ?
+        WHERE status = 'awaiting_approval' AND id IN (SELECT intent_id FROM intent_approvals WHERE expires_at <= ?)
+        RETURNING id, user_id`, message, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	var expired []string
+	var events []types.StatusEvent
+	for rows.Next() {
+		var id, userID string
+		if err := rows.Scan(&id, &userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		expired = append(expired, id)
+		events = append(events, intentEvent(id, userID, "expired", message))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, event := range events[:len(expired)] {
+		skipped, err := skipPendingSteps(tx, event.IntentID, event.UserID, -1)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, skipped...)
+	}
+	for i := range events {
+		if err := appendEvent(tx, &events[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.publish(events...)
+	return expired, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"trustflow/src/pkg/types"
+)
+
+// AuditKindApproval is the audit kind of an approver's decision; other audit events share
+// the status event kinds
+const AuditKindApproval = "approval"
+
+// AuditGenesisHash is the prev_hash of the first audit event
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+// auditRecord is the content of an audit event, hashed as its JSON payload
+type auditRecord struct {
+	UserID     string `json:"user_id"`
+	IntentID   string `json:"intent_id"`
+	Kind       string `json:"kind"`
+	StepIndex  *int   `json:"step_index,omitempty"`
+	Status     string `json:"status"`
+	TxHash     string `json:"tx_hash,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Actor      string `json:"actor,omitempty"`       // Approver, for approval decisions
+	IntentHash string `json:"intent_hash,omitempty"` // SHA-256 of raw_intent when the intent is queued
+	Signature  string `json:"signature,omitempty"`   // The intent's EIP-712 signature when it is queued
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// AuditHash links an audit event to its predecessor: the SHA-256 of the previous hash,
+// the event's sequence number and its payload
+func AuditHash(prevHash string, seq int64, payload string) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\n%d\n%s", prevHash, seq, payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// auditStatusEvent appends the audit event for a persisted status event. Queueing an intent
+// also fixes the hash of its raw_intent and its signature in the chain.
+func auditStatusEvent(q execer, event types.StatusEvent) error {
+	record := auditRecord{
+		UserID:    event.UserID,
+		IntentID:  event.IntentID,
+		Kind:      event.Kind,
+		StepIndex: event.StepIndex,
+		Status:    event.Status,
+		TxHash:    event.TxHash,
+		Message:   event.Message,
+		CreatedAt: event.CreatedAt,
+	}
+	if event.Kind == EventKindIntent && event.Status == "pending" {
+		var rawIntent, signature sql.NullString
+		err := q.QueryRow("SELECT raw_intent, signature FROM intents WHERE id = ?", event.IntentID).Scan(&rawIntent, &signature)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to fetch intent for audit: %w", err)
+		}
+		if rawIntent.Valid {
+			sum := sha256.Sum256([]byte(rawIntent.String))
+			record.IntentHash = hex.EncodeToString(sum[:])
+		}
+		record.Signature = signature.String
+	}
+	return appendAudit(q, record)
+}
+
+// appendAudit adds a record to the end of the audit chain. q must serialize appends (a
+// transaction on the single connection): the next sequence number is the primary key, so
+// a racing append fails instead of forking the chain.
+func appendAudit(q execer, record auditRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	var seq int64
+	prevHash := AuditGenesisHash
+	err = q.QueryRow("SELECT seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1").Scan(&seq, &prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to fetch audit head: %w", err)
+	}
+	seq++
+
+	if _, err := q.Exec(`
+        INSERT INTO audit_events (seq, intent_id, payload, prev_hash, hash, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)`,
+		seq, record.IntentID, string(payload), prevHash, AuditHash(prevHash, seq, string(payload)), record.CreatedAt); err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEvents returns up to limit audit events after the given sequence number, oldest first
+func (s *Storage) ListAuditEvents(afterSeq int64, limit int) ([]types.AuditEvent, error) {
+	rows, err := s.db.Query(`
+        SELECT seq, intent_id, payload, prev_hash, hash, created_at
+        FROM audit_events
+        WHERE seq > ?
+        ORDER BY seq ASC
+        LIMIT ?`, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []types.AuditEvent
+	for rows.Next() {
+		var event types.AuditEvent
+		if err := rows.Scan(&event.Seq, &event.IntentID, &event.Payload, &event.PrevHash, &event.Hash, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
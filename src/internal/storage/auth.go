@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SaveAuthNonce records a sign-in nonce that can be used once before expiresAt, clearing out
+// nonces that expired unused. It saves nothing and returns false when limit nonces are
+// already outstanding.
+func (s *Storage) SaveAuthNonce(nonce string, createdAt, expiresAt int64, limit int) (bool, error) {
+	if _, err := s.db.Exec("DELETE FROM auth_nonces WHERE expires_at <= ?", createdAt); err != nil {
+		return false, fmt.Errorf("failed to purge nonces: %w", err)
+	}
+	result, err := s.db.Exec(`
+        INSERT INTO auth_nonces (nonce, created_at, expires_at)
+        SELECT ?, ?, ? WHERE (SELECT COUNT(*) FROM auth_nonces) < ?`,
+		nonce, createdAt, expiresAt, limit)
+	if err != nil {
+		return false, fmt.Errorf("failed to save nonce: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// ConsumeAuthNonce deletes a sign-in nonce, reporting whether it was issued and still valid at now
+func (s *Storage) ConsumeAuthNonce(nonce string, now int64) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM auth_nonces WHERE nonce = ? AND expires_at > ?", nonce, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume nonce: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// SaveSession records a session for an authenticated address, keyed by the hash of its token,
+// clearing out sessions that have expired
+func (s *Storage) SaveSession(tokenHash string, address string, createdAt, expiresAt int64) error {
+	if _, err := s.db.Exec("DELETE FROM auth_sessions WHERE expires_at <= ?", createdAt); err != nil {
+		return fmt.Errorf("failed to purge sessions: %w", err)
+	}
+	if _, err := s.db.Exec("INSERT INTO auth_sessions (token_hash, address, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		tokenHash, address, createdAt, expiresAt); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// GetSession returns the address of the session with the given token hash; found is false
+// if there is no such session or it expired by now
+func (s *Storage) GetSession(tokenHash string, now int64) (address string, found bool, err error) {
+	err = s.db.QueryRow("SELECT address FROM auth_sessions WHERE token_hash = ? AND expires_at > ?", tokenHash, now).
+		Scan(&address)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to fetch session: %w", err)
+	}
+	return address, true, nil
+}
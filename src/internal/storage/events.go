@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"trustflow/src/pkg/types"
+)
+
+// Kinds of status event
+const (
+	EventKindIntent = "intent"
+	EventKindStep   = "step"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so events can join the write they record
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// SetEventListener registers fn to receive every status event once it is persisted.
+// Set it before the storage is shared; fn must not block.
+func (s *Storage) SetEventListener(fn func(types.StatusEvent)) {
+	s.listener = fn
+}
+
+// intentEvent builds the event for an intent's status transition
+func intentEvent(intentID, userID, status, message string) types.StatusEvent {
+	return types.StatusEvent{UserID: userID, IntentID: intentID, Kind: EventKindIntent, Status: status, Message: message}
+}
+
+// stepEvent builds the event for a step's status transition
+func stepEvent(intentID, userID string, stepIndex int, status, txHash, message string) types.StatusEvent {
+	return types.StatusEvent{UserID: userID, IntentID: intentID, Kind: EventKindStep, StepIndex: &stepIndex, Status: status, TxHash: txHash, Message: message}
+}
+
+// appendEvent persists an event, assigns it the next sequence number and adds it to the
+// audit chain
+func appendEvent(q execer, event *types.StatusEvent) error {
+	event.CreatedAt = time.Now().Unix()
+	var stepIndex sql.NullInt64
+	if event.StepIndex != nil {
+		stepIndex = sql.NullInt64{Int64: int64(*event.StepIndex), Valid: true}
+	}
+	result, err := q.Exec(`
+        INSERT INTO intent_events (user_id, intent_id, kind, step_index, status, tx_hash, message, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.UserID, event.IntentID, event.Kind, stepIndex, event.Status, event.TxHash, event.Message, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+	if event.Seq, err = result.LastInsertId(); err != nil {
+		return err
+	}
+	return auditStatusEvent(q, *event)
+}
+
+// publish hands persisted events to the listener
+func (s *Storage) publish(events ...types.StatusEvent) {
+	if s.listener == nil {
+		return
+	}
+	for _, event := range events {
+		s.listener(event)
+	}
+}
+
+// writeWithEvents runs write in a transaction together with the events it returns, so a
+// status is never saved without its event and audit entry. The events are published once the
+// transaction commits.
+func (s *Storage) writeWithEvents(write func(tx *sql.Tx) ([]types.StatusEvent, error)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	events, err := write(tx)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		if err := appendEvent(tx, &events[i]); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.publish(events...)
+	return nil
+}
+
+// ListEvents returns up to limit of the user's events after the given sequence number, oldest
+// first. An empty intentID lists events for all of the user's intents.
+func (s *Storage) ListEvents(userID string, intentID string, afterSeq int64, limit int) ([]types.StatusEvent, error) {
+	rows, err := s.db.Query(`
+        SELECT seq, intent_id, kind, step_index, status, tx_hash, message, created_at
+        FROM intent_events
+        WHERE user_id = ? AND (? = '' OR intent_id = ?) AND seq > ?
+        ORDER BY seq ASC
+        LIMIT ?`, userID, intentID, intentID, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []types.StatusEvent
+	for rows.Next() {
+		event := types.StatusEvent{UserID: userID}
+		var stepIndex sql.NullInt64
+		var txHash, message sql.NullString
+		if err := rows.Scan(&event.Seq, &event.IntentID, &event.Kind, &stepIndex, &event.Status, &txHash, &message, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if stepIndex.Valid {
+			index := int(stepIndex.Int64)
+			event.StepIndex = &index
+		}
+		event.TxHash = txHash.String
+		event.Message = message.String
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// RecentEventsStart returns the sequence number after which only the user's latest limit
+// events follow, or 0 if there are no more than limit. An empty intentID counts events for
+// all of the user's intents.
+func (s *Storage) RecentEventsStart(userID string, intentID string, limit int) (int64, error) {
+	var seq int64
+	err := s.db.QueryRow(`
+        SELECT seq FROM intent_events
+        WHERE user_id = ? AND (? = '' OR intent_id = ?)
+        ORDER BY seq DESC
+        LIMIT 1 OFFSET ?`, userID, intentID, intentID, limit).Scan(&seq)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch events: %w", err)
+	}
+	return seq, nil
+}
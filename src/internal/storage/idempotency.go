@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"trustflow/src/pkg/types"
+)
+
+// CreateIntent saves an intent and every workflow step as pending in one transaction.
+// It saves nothing and returns false when the intent ID, or the user's idempotency key,
+// is already taken. requestHash fingerprints the request so replays can be told from reuse.
+func (s *Storage) CreateIntent(intent types.Intent, userID string, idempotencyKey string, requestHash string) (bool, error) {
+	log.Printf("💾 Creating Intent: ID=%s, IdempotencyKey=%q", intent.ID, idempotencyKey)
+	rawBytes, signature := rawIntent(intent)
+	key := sql.NullString{String: idempotencyKey, Valid: idempotencyKey != ""}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        INSERT INTO intents (id, user_id, status, created_at, raw_intent, signature, idempotency_key, request_hash) 
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?) 
+        ON CONFLICT DO NOTHING`,
+		intent.ID, userID, "pending", time.Now().Unix(), string(rawBytes), signature, key, requestHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to save intent: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	for i, step := range intent.WorkflowSteps() {
+		if _, err := tx.Exec("INSERT INTO intent_steps (intent_id, user_id, step_index, action, status) VALUES (?, ?, ?, ?, ?)",
+			intent.ID, userID, i, step.Action, "pending"); err != nil {
+			return false, fmt.Errorf("failed to save step: %w", err)
+		}
+	}
+
+	event := intentEvent(intent.ID, userID, "pending", "")
+	if err := appendEvent(tx, &event); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	log.Printf("✅ Created Intent %s", intent.ID)
+	s.publish(event)
+	return true, nil
+}
+
+// FindIntentByKey returns the ID and request hash of the intent a user submitted under an
+// idempotency key; found is false if the key is unused
+func (s *Storage) FindIntentByKey(userID string, idempotencyKey string) (id string, requestHash string, found bool, err error) {
+	var hash sql.NullString
+	err = s.db.QueryRow("SELECT id, request_hash FROM intents WHERE user_id = ? AND idempotency_key = ?", userID, idempotencyKey).
+		Scan(&id, &hash)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	return id, hash.String, true, nil
+}
+
+// FindIntentByID returns the owner and request hash of an intent; found is false if the ID is unused
+func (s *Storage) FindIntentByID(id string) (userID string, requestHash string, found bool, err error) {
+	var owner, hash sql.NullString
+	err = s.db.QueryRow("SELECT user_id, request_hash FROM intents WHERE id = ?", id).Scan(&owner, &hash)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to look up intent: %w", err)
+	}
+	return owner.String, hash.String, true, nil
+}
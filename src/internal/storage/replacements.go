@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"trustflow/src/pkg/types"
+)
+
+// StepTx is the transaction a step broadcast, kept so it can be replaced at the same nonce
+type StepTx struct {
+	IntentID    string
+	UserID      string
+	StepIndex   int
+	Status      string
+	TxHash      string // First broadcast
+	Nonce       uint64
+	To          string
+	Value       string // Wei
+	Data        string // Hex calldata
+	GasLimit    uint64
+	RawTx       string           // Hex signed latest broadcast, original or replacement, for rebroadcast
+	Fees        *types.FeeParams // Fees of the latest broadcast, original or replacement
+	BroadcastAt int64            // Unix time of the latest broadcast
+	Speedups    int
+}
+
+// SaveStepTx records the nonce, call and signed transaction of a step so it can be replaced,
+// or rebroadcast after a restart
+func (s *Storage) SaveStepTx(intentID string, userID string, stepIndex int, nonce uint64, to string, data string, gasLimit uint64, rawTx string) error {
+	_, err := s.db.Exec(`
+        UPDATE intent_steps
+        SET nonce = ?, to_address = ?, data = ?, gas_limit = ?, raw_tx = ?, broadcast_at = ?
+        WHERE intent_id = ? AND user_id = ? AND step_index = ?`,
+		nonce, to, data, gasLimit, rawTx, time.Now().Unix(), intentID, userID, stepIndex)
+	if err != nil {
+		log.Printf("❌ Failed to save step tx for intent %s: %v", intentID, err)
+	}
+	return err
+}
+
+const stepTxColumns = `
+        SELECT s.intent_id, s.user_id, s.step_index, s.status, s.tx_hash, s.nonce, s.to_address, s.value, s.data, s.gas_limit, s.raw_tx, s.fees, s.broadcast_at,
+            (SELECT COUNT(*) FROM step_replacements r
+             WHERE r.intent_id = s.intent_id AND r.user_id = s.user_id AND r.step_index = s.step_index AND r.kind = 'speedup')
+        FROM intent_steps s `
+
+// GetStepTx returns the broadcast transaction of a step, or nil if it was never broadcast
+func (s *Storage) GetStepTx(intentID string, userID string, stepIndex int) (*StepTx, error) {
+	rows, err := s.db.Query(stepTxColumns+`
+        WHERE s.intent_id = ? AND s.user_id = ? AND s.step_index = ? AND s.nonce IS NOT NULL`,
+		intentID, userID, stepIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch step tx: %w", err)
+	}
+	txs, err := scanStepTxs(rows)
+	if err != nil || len(txs) == 0 {
+		return nil, err
+	}
+	return &txs[0], nil
+}
+
+// ListBroadcastSteps returns steps in the given status whose latest broadcast is older than before
+func (s *Storage) ListBroadcastSteps(status string, before int64) ([]StepTx, error) {
+	rows, err := s.db.Query(stepTxColumns+`
+        WHERE s.status = ? AND s.nonce IS NOT NULL AND s.broadcast_at < ?
+        ORDER BY s.broadcast_at ASC`, status, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch broadcast steps: %w", err)
+	}
+	return scanStepTxs(rows)
+}
+
+func scanStepTxs(rows *sql.Rows) ([]StepTx, error) {
+	defer rows.Close()
+
+	var txs []StepTx
+	for rows.Next() {
+		var tx StepTx
+		var txHash, to, value, data, rawTx, fees sql.NullString
+		var gasLimit, broadcastAt sql.NullInt64
+		if err := rows.Scan(&tx.IntentID, &tx.UserID, &tx.StepIndex, &tx.Status, &txHash, &tx.Nonce, &to, &value, &data, &gasLimit, &rawTx, &fees, &broadcastAt, &tx.Speedups); err != nil {
+			return nil, err
+		}
+		tx.TxHash, tx.To, tx.Value, tx.Data, tx.RawTx = txHash.String, to.String, value.String, data.String, rawTx.String
+		tx.GasLimit, tx.BroadcastAt = uint64(gasLimit.Int64), broadcastAt.Int64
+		if fees.Valid && fees.String != "" {
+			tx.Fees = &types.FeeParams{}
+			if err := json.Unmarshal([]byte(fees.String), tx.Fees); err != nil {
+				return nil, fmt.Errorf("corrupt step fees: %w", err)
+			}
+		}
+		txs = append(txs, tx)
+	}
+	return txs, rows.Err()
+}
+
+// AddStepReplacement records a transaction that replaced a step's pending one at the same nonce.
+// The step's fees, broadcast time and signed transaction move to the replacement so later
+// bumps build on it and a restart rebroadcasts it rather than the outbid original.
+func (s *Storage) AddStepReplacement(intentID string, userID string, stepIndex int, kind string, txHash string, fees *types.FeeParams, rawTx string) error {
+	log.Printf("🔁 Recording %s: IntentID=%s, Index=%d, TxHash=%s", kind, intentID, stepIndex, txHash)
+	rawFees, _ := json.Marshal(fees)
+	now := time.Now().Unix()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+        INSERT INTO step_replacements (intent_id, user_id, step_index, kind, tx_hash, fees, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		intentID, userID, stepIndex, kind, txHash, string(rawFees), now); err != nil {
+		return fmt.Errorf("failed to save replacement: %w", err)
+	}
+	if _, err := tx.Exec(`
+        UPDATE intent_steps
+        SET fees = ?, broadcast_at = ?, raw_tx = ?
+        WHERE intent_id = ? AND user_id = ? AND step_index = ?`,
+		string(rawFees), now, rawTx, intentID, userID, stepIndex); err != nil {
+		return fmt.Errorf("failed to update step fees: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetStepReplacements lists the replacement transactions of a step, oldest first
+func (s *Storage) GetStepReplacements(intentID string, userID string, stepIndex int) ([]types.TxReplacement, error) {
+	rows, err := s.db.Query(`
+        SELECT kind, tx_hash, fees, created_at
+        FROM step_replacements
+        WHERE intent_id = ? AND user_id = ? AND step_index = ?
+        ORDER BY id ASC`, intentID, userID, stepIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch replacements: %w", err)
+	}
+	defer rows.Close()
+
+	var replacements []types.TxReplacement
+	for rows.Next() {
+		var r types.TxReplacement
+		var fees sql.NullString
+		if err := rows.Scan(&r.Kind, &r.TxHash, &fees, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		if fees.Valid && fees.String != "" {
+			r.Fees = &types.FeeParams{}
+			if err := json.Unmarshal([]byte(fees.String), r.Fees); err != nil {
+				return nil, fmt.Errorf("corrupt replacement fees: %w", err)
+			}
+		}
+		replacements = append(replacements, r)
+	}
+	return replacements, rows.Err()
+}
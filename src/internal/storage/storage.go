@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/big"
 	"time"
 
 	"trustflow/src/pkg/types"
@@ -13,7 +14,8 @@ import (
 )
 
 type Storage struct {
-	db *sql.DB
+	db       *sql.DB
+	listener func(types.StatusEvent) // Optional: receives status events once persisted
 }
 
 func NewStorage(dbPath string) (*Storage, error) {
@@ -26,6 +28,9 @@ func NewStorage(dbPath string) (*Storage, error) {
 		return nil, fmt.Errorf("failed to ping db: %w", err)
 	}
 
+	// SQLite allows a single writer; serialize access so concurrent workers never hit SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
 	s := &Storage{db: db}
 	if err := s.initSchema(); err != nil {
 		return nil, fmt.Errorf("failed to init schema: %w", err)
@@ -58,52 +63,303 @@ func (s *Storage) initSchema() error {
         FOREIGN KEY(intent_id) REFERENCES intents(id)
     );`
 
+	createNoncesTable := `
+    CREATE TABLE IF NOT EXISTS nonces (
+        address TEXT PRIMARY KEY,
+        nonce INTEGER,
+        updated_at INTEGER
+    );`
+
+	createReplacementsTable := `
+    CREATE TABLE IF NOT EXISTS step_replacements (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        intent_id TEXT,
+        user_id TEXT,
+        step_index INTEGER,
+        kind TEXT,
+        tx_hash TEXT,
+        fees TEXT,
+        created_at INTEGER
+    );`
+
+	createWalletsTable := `
+    CREATE TABLE IF NOT EXISTS wallets (
+        user_id TEXT PRIMARY KEY,
+        account_index INTEGER UNIQUE,
+        created_at INTEGER
+    );`
+
+	createApprovalsTable := `
+    CREATE TABLE IF NOT EXISTS intent_approvals (
+        intent_id TEXT PRIMARY KEY,
+        user_id TEXT,
+        reason TEXT,
+        risk_score INTEGER,
+        total_value TEXT,
+        required INTEGER,
+        approvers TEXT,
+        requested_at INTEGER,
+        expires_at INTEGER,
+        approved_at INTEGER,
+        FOREIGN KEY(intent_id) REFERENCES intents(id)
+    );`
+
+	createDecisionsTable := `
+    CREATE TABLE IF NOT EXISTS approval_decisions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        intent_id TEXT,
+        approver TEXT,
+        decision TEXT,
+        comment TEXT,
+        created_at INTEGER,
+        UNIQUE(intent_id, approver)
+    );`
+
+	createWebhooksTable := `
+    CREATE TABLE IF NOT EXISTS webhooks (
+        id TEXT PRIMARY KEY,
+        user_id TEXT,
+        url TEXT,
+        secret TEXT,
+        events TEXT,
+        created_at INTEGER
+    );`
+
+	createDeliveriesTable := `
+    CREATE TABLE IF NOT EXISTS webhook_deliveries (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        webhook_id TEXT,
+        user_id TEXT,
+        event_id TEXT,
+        event_type TEXT,
+        intent_id TEXT,
+        payload TEXT,
+        status TEXT,
+        attempts INTEGER DEFAULT 0,
+        next_attempt_at INTEGER,
+        response_code INTEGER,
+        error TEXT,
+        created_at INTEGER,
+        delivered_at INTEGER
+    );`
+
+	createEventsTable := `
+    CREATE TABLE IF NOT EXISTS intent_events (
+        seq INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id TEXT,
+        intent_id TEXT,
+        kind TEXT,
+        step_index INTEGER,
+        status TEXT,
+        tx_hash TEXT,
+        message TEXT,
+        created_at INTEGER
+    );`
+
+	createAuditEventsTable := `
+    CREATE TABLE IF NOT EXISTS audit_events (
+        seq INTEGER PRIMARY KEY,
+        intent_id TEXT,
+        payload TEXT,
+        prev_hash TEXT,
+        hash TEXT,
+        created_at INTEGER
+    );`
+
+	createAuditAnchorsTable := `
+    CREATE TABLE IF NOT EXISTS audit_anchors (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        from_seq INTEGER,
+        to_seq INTEGER,
+        root TEXT,
+        tx_hash TEXT,
+        status TEXT,
+        block_number INTEGER,
+        error TEXT,
+        created_at INTEGER,
+        confirmed_at INTEGER
+    );`
+
+	createAuthNoncesTable := `
+    CREATE TABLE IF NOT EXISTS auth_nonces (
+        nonce TEXT PRIMARY KEY,
+        created_at INTEGER,
+        expires_at INTEGER
+    );`
+
+	createSessionsTable := `
+    CREATE TABLE IF NOT EXISTS auth_sessions (
+        token_hash TEXT PRIMARY KEY,
+        address TEXT,
+        created_at INTEGER,
+        expires_at INTEGER
+    );`
+
+	createAPIKeysTable := `
+    CREATE TABLE IF NOT EXISTS api_keys (
+        id TEXT PRIMARY KEY,
+        key_hash TEXT UNIQUE,
+        prefix TEXT,
+        name TEXT,
+        address TEXT,
+        scopes TEXT,
+        created_by TEXT,
+        created_at INTEGER,
+        expires_at INTEGER,
+        last_used_at INTEGER,
+        revoked_at INTEGER
+    );`
+
 	if _, err := s.db.Exec(createIntentsTable); err != nil {
 		return err
 	}
 	if _, err := s.db.Exec(createStepsTable); err != nil {
 		return err
 	}
+	if _, err := s.db.Exec(createNoncesTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createReplacementsTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createWalletsTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createApprovalsTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createDecisionsTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createWebhooksTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createDeliveriesTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createEventsTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createAuditEventsTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createAuditAnchorsTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createAuthNoncesTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createSessionsTable); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(createAPIKeysTable); err != nil {
+		return err
+	}
 
     s.db.Exec("ALTER TABLE intents ADD COLUMN raw_intent TEXT")
+    s.db.Exec("ALTER TABLE intents ADD COLUMN signature TEXT")
     s.db.Exec("ALTER TABLE intents ADD COLUMN user_id TEXT")
     s.db.Exec("ALTER TABLE intent_steps ADD COLUMN user_id TEXT")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN value TEXT")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN executed_at INTEGER")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN block_number INTEGER")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN gas_used INTEGER")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN receipt_status INTEGER")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN fees TEXT")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN nonce INTEGER")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN to_address TEXT")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN data TEXT")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN gas_limit INTEGER")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN broadcast_at INTEGER")
+	s.db.Exec("ALTER TABLE intent_steps ADD COLUMN raw_tx TEXT")
+	s.db.Exec("ALTER TABLE intents ADD COLUMN idempotency_key TEXT")
+	s.db.Exec("ALTER TABLE intents ADD COLUMN request_hash TEXT")
+	s.db.Exec("ALTER TABLE audit_anchors ADD COLUMN nonce INTEGER")
+	s.db.Exec("ALTER TABLE audit_anchors ADD COLUMN to_address TEXT")
+	s.db.Exec("ALTER TABLE audit_anchors ADD COLUMN gas_limit INTEGER")
+	s.db.Exec("ALTER TABLE audit_anchors ADD COLUMN fees TEXT")
+	s.db.Exec("ALTER TABLE audit_anchors ADD COLUMN replacements TEXT")
+	s.db.Exec("ALTER TABLE audit_anchors ADD COLUMN broadcast_at INTEGER")
+
+	// A user's idempotency key names exactly one intent
+	if _, err := s.db.Exec(`
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_intents_idempotency_key
+    ON intents (user_id, idempotency_key) WHERE idempotency_key IS NOT NULL`); err != nil {
+		return err
+	}
+
+	// The dispatcher polls for deliveries that are due
+	if _, err := s.db.Exec(`
+    CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (status, next_attempt_at)`); err != nil {
+		return err
+	}
+
+	// Event streams replay a user's events after the last one they saw
+	if _, err := s.db.Exec(`
+    CREATE INDEX IF NOT EXISTS idx_intent_events_user
+    ON intent_events (user_id, seq)`); err != nil {
+		return err
+	}
+
+	// The audit log is append-only: the database refuses to rewrite or drop its history
+	if _, err := s.db.Exec(`
+    CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+    BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END`); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`
+    CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+    BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END`); err != nil {
+		return err
+	}
+	// Inclusion proofs look up an intent's audit events
+	if _, err := s.db.Exec(`
+    CREATE INDEX IF NOT EXISTS idx_audit_events_intent
+    ON audit_events (intent_id, seq)`); err != nil {
+		return err
+	}
+
+	// Users are keyed by checksummed address; fix up rows keyed before that was enforced
+	if err := s.normalizeUserIDs(); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func (s *Storage) SaveIntent(intent types.Intent, userID string) error {
-    log.Printf("💾 Saving Intent: ID=%s", intent.ID)
-    rawBytes, _ := json.Marshal(intent)
-    _, err := s.db.Exec("INSERT INTO intents (id, user_id, status, created_at, raw_intent) VALUES (?, ?, ?, ?, ?)",
-        intent.ID, userID, "pending", time.Now().Unix(), string(rawBytes))
-    if err != nil {
-        log.Printf("❌ Failed to save intent %s: %v", intent.ID, err)
-    } else {
-        log.Printf("✅ Saved Intent %s", intent.ID)
-    }
-    return err
+// rawIntent serializes an intent for the raw_intent column and splits off its signature,
+// which is stored in its own column. raw_intent is the intent as accepted, including the
+// server-set created_at; the signature covers the EIP-712 typed data built from its id and
+// steps (see auth.IntentTypedData), not this JSON.
+func rawIntent(intent types.Intent) ([]byte, sql.NullString) {
+	signature := sql.NullString{String: intent.Signature, Valid: intent.Signature != ""}
+	intent.Signature = ""
+	rawBytes, _ := json.Marshal(intent)
+	return rawBytes, signature
 }
 
 func (s *Storage) GetIntent(id string, userID string) (*types.IntentState, error) {
 	// 1. Get Intent Details
 	var state types.IntentState
-	var rawIntent sql.NullString
-    err := s.db.QueryRow("SELECT id, status, created_at, message, raw_intent FROM intents WHERE id = ? AND user_id = ?", id, userID).
-        Scan(&state.IntentID, &state.Status, &state.CreatedAt, &state.Message, &rawIntent)
+	var message, rawIntent, signature sql.NullString // Message stays NULL until the intent is processed
+    err := s.db.QueryRow("SELECT id, status, created_at, message, raw_intent, signature FROM intents WHERE id = ? AND user_id = ?", id, userID).
+        Scan(&state.IntentID, &state.Status, &state.CreatedAt, &message, &rawIntent, &signature)
 	if err == sql.ErrNoRows {
 		return nil, nil // Not found
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch intent: %w", err)
 	}
+	state.Message = message.String
 	if rawIntent.Valid {
 		state.RawIntent = rawIntent.String
 	}
+	state.Signature = signature.String
 
 	// 2. Get Steps
     rows, err := s.db.Query(`
-        SELECT step_index, action, status, tx_hash, error_msg 
+        SELECT step_index, action, status, tx_hash, error_msg, block_number, gas_used, receipt_status, fees 
         FROM intent_steps 
         WHERE intent_id = ? AND user_id = ?
         ORDER BY step_index ASC`, id, userID)
@@ -114,15 +370,47 @@ func (s *Storage) GetIntent(id string, userID string) (*types.IntentState, error
 
 	for rows.Next() {
 		var step types.StepState
-		var txHash, errorMsg sql.NullString // Handle nullable fields
+		var txHash, errorMsg, fees sql.NullString // Handle nullable fields
+		var blockNumber, gasUsed, receiptStatus sql.NullInt64
 
-		if err := rows.Scan(&step.StepIndex, &step.Action, &step.Status, &txHash, &errorMsg); err != nil {
+		if err := rows.Scan(&step.StepIndex, &step.Action, &step.Status, &txHash, &errorMsg, &blockNumber, &gasUsed, &receiptStatus, &fees); err != nil {
 			return nil, err
 		}
 		step.TxHash = txHash.String
 		step.Error = errorMsg.String
+		step.BlockNumber = uint64(blockNumber.Int64)
+		step.GasUsed = uint64(gasUsed.Int64)
+		if receiptStatus.Valid {
+			status := uint64(receiptStatus.Int64)
+			step.ReceiptStatus = &status
+		}
+		if fees.Valid && fees.String != "" {
+			step.Fees = &types.FeeParams{}
+			if err := json.Unmarshal([]byte(fees.String), step.Fees); err != nil {
+				return nil, fmt.Errorf("corrupt step fees: %w", err)
+			}
+		}
 		state.Steps = append(state.Steps, step)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 3. Attach replacement transactions (speed-ups and cancellations)
+	for i := range state.Steps {
+		replacements, err := s.GetStepReplacements(id, userID, state.Steps[i].StepIndex)
+		if err != nil {
+			return nil, err
+		}
+		state.Steps[i].Replacements = replacements
+	}
+
+	// 4. Attach the human approval, if the intent needed one
+	approval, _, err := s.GetApproval(id)
+	if err != nil {
+		return nil, err
+	}
+	state.Approval = approval
 
 	return &state, nil
 }
@@ -137,9 +425,11 @@ func (s *Storage) GetRecentIntents(userID string, limit int) ([]types.IntentStat
 	var intents []types.IntentState
 	for rows.Next() {
 		var i types.IntentState
-		if err := rows.Scan(&i.IntentID, &i.Status, &i.CreatedAt, &i.Message); err != nil {
+		var message sql.NullString
+		if err := rows.Scan(&i.IntentID, &i.Status, &i.CreatedAt, &message); err != nil {
 			return nil, err
 		}
+		i.Message = message.String
 		// We don't fetch steps here to keep listing lightweight
 		intents = append(intents, i)
 	}
@@ -148,32 +438,250 @@ func (s *Storage) GetRecentIntents(userID string, limit int) ([]types.IntentStat
 
 func (s *Storage) UpdateIntentStatus(id, userID, status, message string) error {
     log.Printf("🔄 Updating Intent Status: ID=%s, Status=%s", id, status)
-    _, err := s.db.Exec("UPDATE intents SET status = ?, message = ? WHERE id = ? AND user_id = ?", status, message, id, userID)
+	err := s.writeWithEvents(func(tx *sql.Tx) ([]types.StatusEvent, error) {
+		if _, err := tx.Exec("UPDATE intents SET status = ?, message = ? WHERE id = ? AND user_id = ?", status, message, id, userID); err != nil {
+			return nil, err
+		}
+		return []types.StatusEvent{intentEvent(id, userID, status, message)}, nil
+	})
 	if err != nil {
 		log.Printf("❌ Failed to update intent status %s: %v", id, err)
+		return err
 	}
-	return err
+	return nil
 }
 
-func (s *Storage) SaveStep(intentID string, userID string, stepIndex int, action string) error {
-    log.Printf("💾 Saving Step: IntentID=%s, Index=%d, Action=%s", intentID, stepIndex, action)
-    _, err := s.db.Exec("INSERT INTO intent_steps (intent_id, user_id, step_index, action, status) VALUES (?, ?, ?, ?, ?)",
-        intentID, userID, stepIndex, action, "pending")
+func (s *Storage) UpdateStepStatus(intentID string, userID string, stepIndex int, status, txHash, errorMsg string) error {
+    log.Printf("🔄 Updating Step Status: IntentID=%s, Index=%d, Status=%s, TxHash=%s", intentID, stepIndex, status, txHash)
+	err := s.writeWithEvents(func(tx *sql.Tx) ([]types.StatusEvent, error) {
+		if _, err := tx.Exec(`
+            UPDATE intent_steps 
+            SET status = ?, tx_hash = ?, error_msg = ? 
+            WHERE intent_id = ? AND user_id = ? AND step_index = ?`,
+			status, txHash, errorMsg, intentID, userID, stepIndex); err != nil {
+			return nil, err
+		}
+		return []types.StatusEvent{stepEvent(intentID, userID, stepIndex, status, txHash, errorMsg)}, nil
+	})
 	if err != nil {
-		log.Printf("❌ Failed to save step for intent %s: %v", intentID, err)
+		log.Printf("❌ Failed to update step status for intent %s: %v", intentID, err)
+		return err
 	}
-	return err
+	return nil
 }
 
-func (s *Storage) UpdateStepStatus(intentID string, userID string, stepIndex int, status, txHash, errorMsg string) error {
-    log.Printf("🔄 Updating Step Status: IntentID=%s, Index=%d, Status=%s, TxHash=%s", intentID, stepIndex, status, txHash)
-    _, err := s.db.Exec(`
+// MarkStepExecuted records a broadcast (submitted, not yet confirmed) step together with the
+// value it moved, for budget accounting, and the fees it was sent with
+func (s *Storage) MarkStepExecuted(intentID string, userID string, stepIndex int, txHash string, value *big.Int, fees *types.FeeParams) error {
+	log.Printf("🔄 Marking Step Executed: IntentID=%s, Index=%d, TxHash=%s, Value=%s", intentID, stepIndex, txHash, value)
+	var feesJSON sql.NullString
+	if fees != nil {
+		raw, _ := json.Marshal(fees)
+		feesJSON = sql.NullString{String: string(raw), Valid: true}
+	}
+	err := s.writeWithEvents(func(tx *sql.Tx) ([]types.StatusEvent, error) {
+		if _, err := tx.Exec(`
+            UPDATE intent_steps 
+            SET status = ?, tx_hash = ?, error_msg = ?, value = ?, executed_at = ?, fees = ? 
+            WHERE intent_id = ? AND user_id = ? AND step_index = ?`,
+			"submitted", txHash, "", value.String(), time.Now().Unix(), feesJSON, intentID, userID, stepIndex); err != nil {
+			return nil, err
+		}
+		return []types.StatusEvent{stepEvent(intentID, userID, stepIndex, "submitted", txHash, "")}, nil
+	})
+	if err != nil {
+		log.Printf("❌ Failed to mark step executed for intent %s: %v", intentID, err)
+		return err
+	}
+	return nil
+}
+
+// SumExecutedValue totals the value of every step the user executed since the given unix time
+func (s *Storage) SumExecutedValue(userID string, since int64) (*big.Int, error) {
+	rows, err := s.db.Query(`
+        SELECT value 
+        FROM intent_steps 
+        WHERE user_id = ? AND executed_at >= ? AND status NOT IN ('failed', 'cancelled') AND value IS NOT NULL`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch executed steps: %w", err)
+	}
+	defer rows.Close()
+
+	total := new(big.Int)
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		value, ok := new(big.Int).SetString(raw, 10)
+		if !ok {
+			return nil, fmt.Errorf("corrupt step value %q", raw)
+		}
+		total.Add(total, value)
+	}
+	return total, rows.Err()
+}
+
+// SkipRemainingSteps marks every pending step after stepIndex as skipped once a workflow halts
+func (s *Storage) SkipRemainingSteps(intentID string, userID string, stepIndex int) error {
+	err := s.writeWithEvents(func(tx *sql.Tx) ([]types.StatusEvent, error) {
+		return skipPendingSteps(tx, intentID, userID, stepIndex)
+	})
+	if err != nil {
+		log.Printf("❌ Failed to skip remaining steps for intent %s: %v", intentID, err)
+		return err
+	}
+	return nil
+}
+
+// skipPendingSteps marks an intent's pending steps after stepIndex as skipped and returns
+// the events for them, not yet recorded
+func skipPendingSteps(q querier, intentID string, userID string, stepIndex int) ([]types.StatusEvent, error) {
+	rows, err := q.Query(`
         UPDATE intent_steps 
-        SET status = ?, tx_hash = ?, error_msg = ? 
-        WHERE intent_id = ? AND user_id = ? AND step_index = ?`,
-        status, txHash, errorMsg, intentID, userID, stepIndex)
+        SET status = 'skipped' 
+        WHERE intent_id = ? AND user_id = ? AND step_index > ? AND status = 'pending'
+        RETURNING step_index`,
+		intentID, userID, stepIndex)
 	if err != nil {
-		log.Printf("❌ Failed to update step status for intent %s: %v", intentID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []types.StatusEvent
+	for rows.Next() {
+		var index int
+		if err := rows.Scan(&index); err != nil {
+			return nil, err
+		}
+		events = append(events, stepEvent(intentID, userID, index, "skipped", "", ""))
+	}
+	return events, rows.Err()
+}
+
+// IntentRef identifies an intent and its owner
+type IntentRef struct {
+	ID     string
+	UserID string
+}
+
+// ListIntentsByStatus returns every intent in the given status, oldest first
+func (s *Storage) ListIntentsByStatus(status string) ([]IntentRef, error) {
+	rows, err := s.db.Query("SELECT id, user_id FROM intents WHERE status = ? ORDER BY created_at ASC, rowid ASC", status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch intents: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []IntentRef
+	for rows.Next() {
+		var ref IntentRef
+		if err := rows.Scan(&ref.ID, &ref.UserID); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// ClaimPendingIntent atomically moves the oldest pending intent to processing and returns it.
+// Intents of a user who already has one processing wait their turn, so budget and solvency
+// checks never race against the same user's concurrent spend. It returns nil when there is
+// nothing to do.
+func (s *Storage) ClaimPendingIntent() (*types.Intent, string, error) {
+	var id, userID, rawIntent string
+	err := s.writeWithEvents(func(tx *sql.Tx) ([]types.StatusEvent, error) {
+		err := tx.QueryRow(`
+            UPDATE intents 
+            SET status = 'processing' 
+            WHERE id = (
+                SELECT id FROM intents
+                WHERE status = 'pending' AND user_id NOT IN (SELECT user_id FROM intents WHERE status = 'processing')
+                ORDER BY created_at ASC, rowid ASC LIMIT 1) 
+            RETURNING id, user_id, raw_intent`).Scan(&id, &userID, &rawIntent)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []types.StatusEvent{intentEvent(id, userID, "processing", "")}, nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to claim intent: %w", err)
+	}
+	if id == "" {
+		return nil, "", nil
+	}
+
+	var intent types.Intent
+	if err := json.Unmarshal([]byte(rawIntent), &intent); err != nil {
+		return nil, "", fmt.Errorf("corrupt raw intent: %w", err)
+	}
+	log.Printf("📥 Claimed Intent %s", intent.ID)
+	return &intent, userID, nil
+}
+
+// RecordStepReceipt stores the mined receipt of a step and its resulting status. txHash is the
+// transaction that was mined, which may be a replacement of the one first broadcast.
+func (s *Storage) RecordStepReceipt(intentID string, userID string, stepIndex int, txHash string, status string, blockNumber, gasUsed, receiptStatus uint64, errorMsg string) error {
+	log.Printf("🧾 Recording Receipt: IntentID=%s, Index=%d, TxHash=%s, Block=%d, GasUsed=%d, ReceiptStatus=%d", intentID, stepIndex, txHash, blockNumber, gasUsed, receiptStatus)
+	err := s.writeWithEvents(func(tx *sql.Tx) ([]types.StatusEvent, error) {
+		if _, err := tx.Exec(`
+            UPDATE intent_steps 
+            SET status = ?, tx_hash = ?, block_number = ?, gas_used = ?, receipt_status = ?, error_msg = ? 
+            WHERE intent_id = ? AND user_id = ? AND step_index = ?`,
+			status, txHash, blockNumber, gasUsed, receiptStatus, errorMsg, intentID, userID, stepIndex); err != nil {
+			return nil, err
+		}
+		return []types.StatusEvent{stepEvent(intentID, userID, stepIndex, status, txHash, errorMsg)}, nil
+	})
+	if err != nil {
+		log.Printf("❌ Failed to record receipt for intent %s: %v", intentID, err)
+		return err
+	}
+	return nil
+}
+
+// LoadNonce returns the last nonce used by a signer address
+func (s *Storage) LoadNonce(address string) (uint64, bool, error) {
+	var nonce uint64
+	err := s.db.QueryRow("SELECT nonce FROM nonces WHERE address = ?", address).Scan(&nonce)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return nonce, true, nil
+}
+
+// SaveNonce records the last nonce used by a signer address
+func (s *Storage) SaveNonce(address string, nonce uint64) error {
+	_, err := s.db.Exec(`
+        INSERT INTO nonces (address, nonce, updated_at) VALUES (?, ?, ?) 
+        ON CONFLICT(address) DO UPDATE SET nonce = excluded.nonce, updated_at = excluded.updated_at`,
+		address, nonce, time.Now().Unix())
+	if err != nil {
+		log.Printf("❌ Failed to save nonce for %s: %v", address, err)
 	}
 	return err
 }
+
+// AssignWallet returns the HD account index of a user, allocating the next free one on first use
+func (s *Storage) AssignWallet(userID string) (uint32, error) {
+	_, err := s.db.Exec(`
+        INSERT INTO wallets (user_id, account_index, created_at) 
+        SELECT ?, COALESCE(MAX(account_index) + 1, 0), ? FROM wallets 
+        WHERE true 
+        ON CONFLICT(user_id) DO NOTHING`, userID, time.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to assign wallet: %w", err)
+	}
+
+	var index uint32
+	if err := s.db.QueryRow("SELECT account_index FROM wallets WHERE user_id = ?", userID).Scan(&index); err != nil {
+		return 0, fmt.Errorf("failed to fetch wallet: %w", err)
+	}
+	return index, nil
+}
@@ -0,0 +1,406 @@
+package storage_test
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const user = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+
+func newStore(t *testing.T) *storage.Storage {
+	t.Helper()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	return store
+}
+
+// createIntent saves an intent and its steps as pending
+func createIntent(t *testing.T, store *storage.Storage, intent types.Intent, userID string) {
+	t.Helper()
+	created, err := store.CreateIntent(intent, userID, "", "hash-"+intent.ID)
+	require.NoError(t, err)
+	require.True(t, created)
+}
+
+func TestClaimPendingIntent(t *testing.T) {
+	store := newStore(t)
+
+	first := types.Intent{ID: "intent-1", Action: "payment", Params: map[string]string{"amount": "1"}}
+	second := types.Intent{ID: "intent-2", Action: "payment", Params: map[string]string{"amount": "2"}}
+	createIntent(t, store, first, user)
+	createIntent(t, store, second, user)
+
+	claimed, userID, err := store.ClaimPendingIntent()
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, "intent-1", claimed.ID)
+	assert.Equal(t, user, userID)
+	assert.Equal(t, "1", claimed.Params["amount"])
+
+	state, err := store.GetIntent("intent-1", user)
+	require.NoError(t, err)
+	assert.Equal(t, "processing", state.Status)
+
+	// The same user's next intent waits until the first settles, another user's does not
+	other := types.Intent{ID: "intent-3", Action: "payment", Params: map[string]string{"amount": "3"}}
+	createIntent(t, store, other, "0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+	claimed, _, err = store.ClaimPendingIntent()
+	require.NoError(t, err)
+	assert.Equal(t, "intent-3", claimed.ID)
+	claimed, _, err = store.ClaimPendingIntent()
+	require.NoError(t, err)
+	assert.Nil(t, claimed)
+
+	require.NoError(t, store.UpdateIntentStatus("intent-1", user, "success", ""))
+	claimed, _, err = store.ClaimPendingIntent()
+	require.NoError(t, err)
+	assert.Equal(t, "intent-2", claimed.ID)
+	require.NoError(t, store.UpdateIntentStatus("intent-2", user, "success", ""))
+	require.NoError(t, store.UpdateIntentStatus("intent-3", "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "success", ""))
+
+	// Queue drained
+	claimed, _, err = store.ClaimPendingIntent()
+	require.NoError(t, err)
+	assert.Nil(t, claimed)
+}
+
+func TestSumExecutedValue(t *testing.T) {
+	store := newStore(t)
+
+	createIntent(t, store, types.Intent{ID: "intent-1", Steps: []types.IntentStep{{Action: "payment"}, {Action: "payment"}, {Action: "payment"}}}, user)
+	require.NoError(t, store.MarkStepExecuted("intent-1", user, 0, "0xaa", big.NewInt(100), nil))
+	require.NoError(t, store.MarkStepExecuted("intent-1", user, 1, "0xbb", big.NewInt(250), nil))
+	require.NoError(t, store.MarkStepExecuted("intent-1", user, 2, "0xcc", big.NewInt(1000), nil))
+	require.NoError(t, store.UpdateStepStatus("intent-1", user, 2, "failed", "0xcc", "reverted"))
+
+	total, err := store.SumExecutedValue(user, 0)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(350), total) // Failed steps do not count
+
+	other, err := store.SumExecutedValue("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, other.Sign())
+}
+
+func TestNonceStore(t *testing.T) {
+	store := newStore(t)
+
+	_, found, err := store.LoadNonce(user)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, store.SaveNonce(user, 41))
+	require.NoError(t, store.SaveNonce(user, 42))
+
+	nonce, found, err := store.LoadNonce(user)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(42), nonce)
+}
+
+func TestStepReplacements(t *testing.T) {
+	store := newStore(t)
+	createIntent(t, store, types.Intent{ID: "intent-1", Action: "payment"}, user)
+
+	stepTx, err := store.GetStepTx("intent-1", user, 0)
+	require.NoError(t, err)
+	assert.Nil(t, stepTx, "a step that was never broadcast cannot be replaced")
+
+	legacy := &types.FeeParams{Type: "legacy", GasPrice: "100"}
+	require.NoError(t, store.MarkStepExecuted("intent-1", user, 0, "0xaa", big.NewInt(5), legacy))
+	require.NoError(t, store.SaveStepTx("intent-1", user, 0, 7, user, "0x", 21000, "0x02f8"))
+
+	bumped := &types.FeeParams{Type: "legacy", GasPrice: "125"}
+	require.NoError(t, store.AddStepReplacement("intent-1", user, 0, "speedup", "0xbb", bumped, "0x02f9"))
+
+	stepTx, err = store.GetStepTx("intent-1", user, 0)
+	require.NoError(t, err)
+	require.NotNil(t, stepTx)
+	assert.Equal(t, "0xaa", stepTx.TxHash)
+	assert.Equal(t, uint64(7), stepTx.Nonce)
+	assert.Equal(t, "5", stepTx.Value)
+	assert.Equal(t, uint64(21000), stepTx.GasLimit)
+	assert.Equal(t, "0x02f9", stepTx.RawTx, "a restart rebroadcasts the replacement, not the outbid original")
+	assert.Equal(t, bumped, stepTx.Fees, "later bumps build on the latest broadcast")
+	assert.Equal(t, 1, stepTx.Speedups)
+
+	stuck, err := store.ListBroadcastSteps("submitted", stepTx.BroadcastAt+1)
+	require.NoError(t, err)
+	assert.Len(t, stuck, 1)
+
+	state, err := store.GetIntent("intent-1", user)
+	require.NoError(t, err)
+	require.Len(t, state.Steps[0].Replacements, 1)
+	assert.Equal(t, "0xbb", state.Steps[0].Replacements[0].TxHash)
+	assert.Equal(t, "speedup", state.Steps[0].Replacements[0].Kind)
+}
+
+func TestListIntentsByStatus(t *testing.T) {
+	store := newStore(t)
+	createIntent(t, store, types.Intent{ID: "intent-1", Action: "payment"}, user)
+	createIntent(t, store, types.Intent{ID: "intent-2", Action: "payment"}, user)
+
+	_, _, err := store.ClaimPendingIntent()
+	require.NoError(t, err)
+
+	processing, err := store.ListIntentsByStatus("processing")
+	require.NoError(t, err)
+	require.Len(t, processing, 1)
+	assert.Equal(t, "intent-1", processing[0].ID)
+	assert.Equal(t, user, processing[0].UserID)
+}
+
+func TestAssignWallet(t *testing.T) {
+	store := newStore(t)
+
+	first, err := store.AssignWallet("0xaaaa")
+	require.NoError(t, err)
+	second, err := store.AssignWallet("0xbbbb")
+	require.NoError(t, err)
+	again, err := store.AssignWallet("0xaaaa")
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(0), first)
+	assert.Equal(t, uint32(1), second)
+	assert.Equal(t, first, again, "a user keeps the index assigned on first use")
+}
+
+func TestApprovalDecisions(t *testing.T) {
+	store := newStore(t)
+	const approverA, approverB = "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", "0x4B20993Bc481177ec7E8f571ceCaE8A9e22C02db"
+
+	request := func(id string, expiresAt int64) {
+		createIntent(t, store, types.Intent{ID: id, Action: "payment"}, user)
+		require.NoError(t, store.RequestApproval(id, user, &types.ApprovalState{
+			Reason:     "total value exceeds threshold",
+			TotalValue: "5000",
+			Required:   2,
+			Approvers:  []string{approverA, approverB},
+			ExpiresAt:  expiresAt,
+		}))
+	}
+
+	t.Run("Quorum Requeues", func(t *testing.T) {
+		request("approved", 100)
+
+		status, err := store.DecideApproval("approved", approverA, storage.DecisionApprove, "looks fine", 10)
+		require.NoError(t, err)
+		assert.Equal(t, "awaiting_approval", status)
+
+		_, err = store.DecideApproval("approved", approverA, storage.DecisionApprove, "", 11)
+		assert.ErrorIs(t, err, storage.ErrAlreadyDecided)
+
+		status, err = store.DecideApproval("approved", approverB, storage.DecisionApprove, "", 12)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", status)
+
+		state, err := store.GetIntent("approved", user)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", state.Status)
+		require.NotNil(t, state.Approval)
+		assert.Equal(t, int64(12), state.Approval.ApprovedAt)
+		require.Len(t, state.Approval.Decisions, 2)
+		assert.Equal(t, approverA, state.Approval.Decisions[0].Approver)
+		assert.Equal(t, "looks fine", state.Approval.Decisions[0].Comment)
+
+		_, err = store.DecideApproval("approved", approverB, storage.DecisionReject, "", 13)
+		assert.ErrorIs(t, err, storage.ErrApprovalClosed)
+	})
+
+	t.Run("Rejection Ends Intent", func(t *testing.T) {
+		request("rejected", 100)
+
+		status, err := store.DecideApproval("rejected", approverB, storage.DecisionReject, "too much", 10)
+		require.NoError(t, err)
+		assert.Equal(t, "rejected", status)
+
+		state, err := store.GetIntent("rejected", user)
+		require.NoError(t, err)
+		assert.Equal(t, "rejected", state.Status)
+		assert.Equal(t, "skipped", state.Steps[0].Status)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		request("expired", 100)
+
+		_, err := store.DecideApproval("expired", approverA, storage.DecisionApprove, "", 100)
+		assert.ErrorIs(t, err, storage.ErrApprovalClosed, "no decisions at or after the deadline")
+
+		expired, err := store.ExpireApprovals(100)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"expired"}, expired)
+
+		state, err := store.GetIntent("expired", user)
+		require.NoError(t, err)
+		assert.Equal(t, "expired", state.Status)
+		assert.Equal(t, "skipped", state.Steps[0].Status)
+		assert.Empty(t, state.Approval.Decisions)
+	})
+}
+
+func TestCreateIntent(t *testing.T) {
+	store := newStore(t)
+	intent := types.Intent{ID: "intent-1", Steps: []types.IntentStep{
+		{Action: "payment", Params: map[string]string{"amount": "1"}},
+		{Action: "payment", Params: map[string]string{"amount": "2"}},
+	}}
+
+	created, err := store.CreateIntent(intent, user, "retry-key", "hash-1")
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	state, err := store.GetIntent("intent-1", user)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", state.Status)
+	assert.Len(t, state.Steps, 2)
+
+	id, hash, found, err := store.FindIntentByKey(user, "retry-key")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "intent-1", id)
+	assert.Equal(t, "hash-1", hash)
+
+	// Same key under a new ID, and same ID under a new key, are both refused
+	created, err = store.CreateIntent(types.Intent{ID: "intent-2", Action: "payment"}, user, "retry-key", "hash-1")
+	require.NoError(t, err)
+	assert.False(t, created)
+	created, err = store.CreateIntent(types.Intent{ID: "intent-1", Action: "payment"}, user, "other-key", "hash-1")
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	// Keys are scoped per user, and intents without a key never collide on it
+	created, err = store.CreateIntent(types.Intent{ID: "intent-3", Action: "payment"}, "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "retry-key", "hash-3")
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, err = store.CreateIntent(types.Intent{ID: "intent-4", Action: "payment"}, user, "", "hash-4")
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, err = store.CreateIntent(types.Intent{ID: "intent-5", Action: "payment"}, user, "", "hash-5")
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	owner, _, found, err := store.FindIntentByID("intent-3")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", owner)
+
+	_, _, found, err = store.FindIntentByID("missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// A signature is kept beside raw_intent, which stays exactly the signed payload
+	created, err = store.CreateIntent(types.Intent{ID: "intent-6", Action: "payment", Signature: "0xsig"}, user, "", "hash-6")
+	require.NoError(t, err)
+	assert.True(t, created)
+	state, err = store.GetIntent("intent-6", user)
+	require.NoError(t, err)
+	assert.Equal(t, "0xsig", state.Signature)
+	assert.NotContains(t, state.RawIntent, "0xsig")
+}
+
+func TestStatusEvents(t *testing.T) {
+	store := newStore(t)
+	var published []types.StatusEvent
+	store.SetEventListener(func(event types.StatusEvent) { published = append(published, event) })
+
+	intent := types.Intent{ID: "intent-1", Steps: []types.IntentStep{{Action: "payment"}, {Action: "payment"}, {Action: "payment"}}}
+	_, err := store.CreateIntent(intent, user, "", "hash-1")
+	require.NoError(t, err)
+	_, _, err = store.ClaimPendingIntent()
+	require.NoError(t, err)
+	require.NoError(t, store.MarkStepExecuted("intent-1", user, 0, "0xaa", big.NewInt(1), nil))
+	require.NoError(t, store.RecordStepReceipt("intent-1", user, 0, "0xaa", "failed", 10, 21000, 0, "reverted"))
+	require.NoError(t, store.SkipRemainingSteps("intent-1", user, 0))
+	require.NoError(t, store.UpdateIntentStatus("intent-1", user, "failed", "reverted"))
+
+	events, err := store.ListEvents(user, "intent-1", 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, published, events, "every persisted event is published")
+
+	var transitions []string
+	for _, event := range events {
+		transition := event.Kind + ":" + event.Status
+		if event.StepIndex != nil {
+			transition += fmt.Sprintf("@%d", *event.StepIndex)
+		}
+		transitions = append(transitions, transition)
+	}
+	assert.Equal(t, []string{
+		"intent:pending", "intent:processing", "step:submitted@0", "step:failed@0",
+		"step:skipped@1", "step:skipped@2", "intent:failed",
+	}, transitions)
+
+	resumed, err := store.ListEvents(user, "", events[4].Seq, 100)
+	require.NoError(t, err)
+	assert.Len(t, resumed, 2, "only events after the given sequence number")
+
+	others, err := store.ListEvents("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "", 0, 100)
+	require.NoError(t, err)
+	assert.Empty(t, others)
+}
+
+func TestStatusWithoutEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trustflow.db")
+	store, err := storage.NewStorage(path)
+	require.NoError(t, err)
+	createIntent(t, store, types.Intent{ID: "intent-1", Action: "payment"}, user)
+
+	// Make every event append fail, as a full disk or a broken audit chain would
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TRIGGER no_events BEFORE INSERT ON intent_events BEGIN SELECT RAISE(ABORT, 'disk full'); END`)
+	require.NoError(t, err)
+
+	assert.Error(t, store.UpdateIntentStatus("intent-1", user, "failed", "reverted"))
+	assert.Error(t, store.UpdateStepStatus("intent-1", user, 0, "failed", "", "reverted"))
+	state, err := store.GetIntent("intent-1", user)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", state.Status, "the status is not saved without its event")
+	assert.Equal(t, "pending", state.Steps[0].Status)
+}
+
+func TestNormalizeUserIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trustflow.db")
+	store, err := storage.NewStorage(path)
+	require.NoError(t, err)
+
+	// Rows keyed by a lowercase header address, before sign-in checksummed every user
+	legacy := strings.ToLower(user)
+	createIntent(t, store, types.Intent{ID: "intent-1", Action: "payment"}, legacy)
+	legacyIndex, err := store.AssignWallet(legacy)
+	require.NoError(t, err)
+	const other = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+	_, err = store.AssignWallet(strings.ToLower(other))
+	require.NoError(t, err)
+	_, err = store.AssignWallet(other) // Both spellings got a wallet: cannot be merged automatically
+	require.NoError(t, err)
+
+	store, err = storage.NewStorage(path)
+	require.NoError(t, err)
+
+	state, err := store.GetIntent("intent-1", user)
+	require.NoError(t, err)
+	require.NotNil(t, state, "the intent is found under the checksummed address")
+	require.Len(t, state.Steps, 1)
+	events, err := store.ListEvents(user, "intent-1", 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+	index, err := store.AssignWallet(user)
+	require.NoError(t, err)
+	assert.Equal(t, legacyIndex, index, "the user keeps their wallet")
+
+	// The conflicting row is left for an operator instead of failing startup
+	index, err = store.AssignWallet(strings.ToLower(other))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), index)
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// userColumns lists every column keyed by a user's address
+var userColumns = []struct{ table, column string }{
+	{"intents", "user_id"},
+	{"intent_steps", "user_id"},
+	{"step_replacements", "user_id"},
+	{"intent_approvals", "user_id"},
+	{"intent_events", "user_id"},
+	{"wallets", "user_id"},
+	{"webhooks", "user_id"},
+	{"webhook_deliveries", "user_id"},
+}
+
+// normalizeUserIDs rewrites user addresses stored in another case to the checksummed form
+// requests are authenticated as. Rows written before sign-in were keyed by whatever
+// X-User-Address held and would otherwise be unreachable. A row whose checksummed twin already
+// exists (e.g. a second derived wallet) is kept as is and logged for an operator to merge.
+func (s *Storage) normalizeUserIDs() error {
+	for _, c := range userColumns {
+		rows, err := s.db.Query(fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IS NOT NULL", c.column, c.table, c.column))
+		if err != nil {
+			return fmt.Errorf("failed to list %s.%s: %w", c.table, c.column, err)
+		}
+		var stale []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			if common.IsHexAddress(id) && common.HexToAddress(id).Hex() != id {
+				stale = append(stale, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range stale {
+			checksummed := common.HexToAddress(id).Hex()
+			result, err := s.db.Exec(fmt.Sprintf("UPDATE OR IGNORE %s SET %s = ? WHERE %s = ?", c.table, c.column, c.column), checksummed, id)
+			if err != nil {
+				return fmt.Errorf("failed to normalize %s.%s: %w", c.table, c.column, err)
+			}
+			moved, _ := result.RowsAffected()
+			var kept int
+			if err := s.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", c.table, c.column), id).Scan(&kept); err != nil {
+				return err
+			}
+			log.Printf("🔠 Normalized %d %s rows of %s to %s", moved, c.table, id, checksummed)
+			if kept > 0 {
+				log.Printf("⚠️ %d %s rows of %s conflict with rows of %s and were left as they are", kept, c.table, id, checksummed)
+			}
+		}
+	}
+	return nil
+}
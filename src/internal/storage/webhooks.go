@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+
+	"trustflow/src/pkg/types"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// DueDelivery is a pending delivery with everything needed to send it
+type DueDelivery struct {
+	types.WebhookDelivery
+	URL     string
+	Secret  string
+	Payload []byte
+}
+
+// SaveWebhook registers a webhook for a user
+func (s *Storage) SaveWebhook(userID string, hook types.Webhook) error {
+	events, _ := json.Marshal(hook.Events)
+	_, err := s.db.Exec(`
+        INSERT INTO webhooks (id, user_id, url, secret, events, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)`,
+		hook.ID, userID, hook.URL, hook.Secret, string(events), hook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook: %w", err)
+	}
+	return nil
+}
+
+// ListWebhooks returns a user's webhooks, oldest first, without their secrets
+func (s *Storage) ListWebhooks(userID string) ([]types.Webhook, error) {
+	rows, err := s.db.Query(`
+        SELECT id, url, events, created_at
+        FROM webhooks
+        WHERE user_id = ?
+        ORDER BY created_at ASC, id ASC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []types.Webhook{}
+	for rows.Next() {
+		var hook types.Webhook
+		var events string
+		if err := rows.Scan(&hook.ID, &hook.URL, &events, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(events), &hook.Events); err != nil {
+			return nil, fmt.Errorf("corrupt webhook events: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhook removes a user's webhook and abandons its pending deliveries,
+// reporting whether the webhook existed
+func (s *Storage) DeleteWebhook(userID string, id string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM webhooks WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(`
+        UPDATE webhook_deliveries SET status = ?, next_attempt_at = NULL, error = 'webhook deleted'
+        WHERE webhook_id = ? AND status = ?`, DeliveryFailed, id, DeliveryPending); err != nil {
+		return false, fmt.Errorf("failed to abandon deliveries: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// EnqueueWebhookEvent queues a delivery of the event to each of the user's webhooks
+// subscribed to its type, returning how many were queued
+func (s *Storage) EnqueueWebhookEvent(userID string, event types.WebhookEvent, payload []byte) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Collect the subscribers before inserting: the connection cannot interleave the two
+	rows, err := tx.Query("SELECT id, events FROM webhooks WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+	var subscribers []string
+	for rows.Next() {
+		var id, raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		var events []string
+		if err := json.Unmarshal([]byte(raw), &events); err != nil {
+			log.Printf("⚠️ Webhook %s has corrupt events: %v", id, err)
+			continue
+		}
+		if len(events) == 0 || slices.Contains(events, event.Type) {
+			subscribers = append(subscribers, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range subscribers {
+		if _, err := tx.Exec(`
+            INSERT INTO webhook_deliveries (webhook_id, user_id, event_id, event_type, intent_id, payload, status, attempts, next_attempt_at, created_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?, ?)`,
+			id, userID, event.ID, event.Type, event.IntentID, string(payload), DeliveryPending, event.CreatedAt, event.CreatedAt); err != nil {
+			return 0, fmt.Errorf("failed to queue delivery: %w", err)
+		}
+	}
+	return len(subscribers), tx.Commit()
+}
+
+// ListDueDeliveries returns up to limit pending deliveries whose next attempt is due by now, oldest
+// first, taking at most perWebhook from any one webhook
+func (s *Storage) ListDueDeliveries(now int64, perWebhook int, limit int) ([]DueDelivery, error) {
+	rows, err := s.db.Query(`
+        SELECT id, webhook_id, event_id, event_type, intent_id, status, attempts, created_at, payload, url, secret
+        FROM (
+            SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.intent_id, d.status, d.attempts, d.created_at,
+                   d.payload, w.url, w.secret, d.next_attempt_at,
+                   ROW_NUMBER() OVER (PARTITION BY d.webhook_id ORDER BY d.next_attempt_at ASC, d.id ASC) AS position
+            FROM webhook_deliveries d
+            JOIN webhooks w ON w.id = d.webhook_id
+            WHERE d.status = ? AND d.next_attempt_at <= ?
+        )
+        WHERE position <= ?
+        ORDER BY next_attempt_at ASC, id ASC
+        LIMIT ?`, DeliveryPending, now, perWebhook, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch due deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var due []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		var payload string
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.IntentID, &d.Status, &d.Attempts, &d.CreatedAt,
+			&payload, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// RecordDeliveryAttempt records the outcome of an attempt made at attemptedAt to send a delivery.
+// A pending delivery is retried at nextAttemptAt; delivered and failed deliveries are final.
+func (s *Storage) RecordDeliveryAttempt(id int64, status string, responseCode int, errorMsg string, attemptedAt, nextAttemptAt int64) error {
+	var next, deliveredAt sql.NullInt64
+	if status == DeliveryPending {
+		next = sql.NullInt64{Int64: nextAttemptAt, Valid: true}
+	}
+	if status == DeliveryDelivered {
+		deliveredAt = sql.NullInt64{Int64: attemptedAt, Valid: true}
+	}
+	_, err := s.db.Exec(`
+        UPDATE webhook_deliveries
+        SET status = ?, attempts = attempts + 1, response_code = ?, error = ?, next_attempt_at = ?, delivered_at = ?
+        WHERE id = ?`, status, responseCode, errorMsg, next, deliveredAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries to one of a user's webhooks, newest first
+func (s *Storage) ListDeliveries(userID string, webhookID string, limit int) ([]types.WebhookDelivery, error) {
+	rows, err := s.db.Query(`
+        SELECT id, webhook_id, event_id, event_type, intent_id, status, attempts, next_attempt_at,
+               response_code, error, created_at, delivered_at
+        FROM webhook_deliveries
+        WHERE user_id = ? AND webhook_id = ?
+        ORDER BY id DESC
+        LIMIT ?`, userID, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	for rows.Next() {
+		var d types.WebhookDelivery
+		var nextAttemptAt, responseCode, deliveredAt sql.NullInt64
+		var errorMsg sql.NullString
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.IntentID, &d.Status, &d.Attempts, &nextAttemptAt,
+			&responseCode, &errorMsg, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		d.NextAttemptAt = nextAttemptAt.Int64
+		d.ResponseCode = int(responseCode.Int64)
+		d.Error = errorMsg.String
+		d.DeliveredAt = deliveredAt.Int64
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
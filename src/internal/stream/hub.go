@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"log"
+	"sync"
+
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+)
+
+// replayPage bounds each read of events from storage
+const replayPage = 500
+
+// historyLimit is how many past events a stream opened without a resume point starts with
+const historyLimit = 100
+
+// FromRecent passed as afterSeq starts a stream with the latest events instead of after a
+// given one
+const FromRecent int64 = -1
+
+// Hub streams persisted status events to subscribers. Events are always read from storage
+// in sequence order; publishing only wakes the subscribers an event concerns, so events
+// published out of order are still streamed in order and a slow subscriber is never dropped.
+type Hub struct {
+	store *storage.Storage
+
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]*subscription
+}
+
+type subscription struct {
+	userID   string
+	intentID string        // Empty: every intent of the user
+	wake     chan struct{} // Holds one pending wake-up; further ones coalesce into it
+}
+
+// NewHub creates a hub and registers it to receive every event the storage persists
+func NewHub(store *storage.Storage) *Hub {
+	h := &Hub{store: store, subs: make(map[int]*subscription)}
+	store.SetEventListener(h.Publish)
+	return h
+}
+
+// Publish wakes every subscriber the event concerns; it never blocks the writer
+func (h *Hub) Publish(event types.StatusEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.subs {
+		if sub.userID != event.UserID || (sub.intentID != "" && sub.intentID != event.IntentID) {
+			continue
+		}
+		select {
+		case sub.wake <- struct{}{}:
+		default: // A wake-up is already pending
+		}
+	}
+}
+
+// Subscribe streams the user's events after afterSeq, or the latest ones for FromRecent: first
+// those already persisted, a page at a time, then new ones as they are published. An empty
+// intentID follows every intent of the user. The channel is closed if storage fails, after
+// which the client can resume from the last event it got; cancel releases the subscription.
+func (h *Hub) Subscribe(userID string, intentID string, afterSeq int64) (<-chan types.StatusEvent, func(), error) {
+	if afterSeq < 0 {
+		start, err := h.store.RecentEventsStart(userID, intentID, historyLimit)
+		if err != nil {
+			return nil, nil, err
+		}
+		afterSeq = start
+	}
+
+	// Subscribe before reading the backlog so nothing published in between is missed
+	sub := &subscription{userID: userID, intentID: intentID, wake: make(chan struct{}, 1)}
+	h.mu.Lock()
+	id := h.nextID
+	h.nextID++
+	h.subs[id] = sub
+	h.mu.Unlock()
+	release := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs, id)
+	}
+
+	page, err := h.store.ListEvents(userID, intentID, afterSeq, replayPage)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	out := make(chan types.StatusEvent)
+	done := make(chan struct{})
+	go func() {
+		defer close(out)
+		for {
+			for _, event := range page {
+				select {
+				case out <- event:
+					afterSeq = event.Seq
+				case <-done:
+					return
+				}
+			}
+			if len(page) < replayPage {
+				// Caught up: wait until something new is persisted
+				select {
+				case <-sub.wake:
+				case <-done:
+					return
+				}
+			}
+			if page, err = h.store.ListEvents(userID, intentID, afterSeq, replayPage); err != nil {
+				log.Printf("❌ Event stream for %s stopped: %v", userID, err)
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(done)
+			release()
+		})
+	}, nil
+}
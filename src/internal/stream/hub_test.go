@@ -0,0 +1,137 @@
+package stream_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"trustflow/src/internal/storage"
+	"trustflow/src/internal/stream"
+	"trustflow/src/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const user = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+
+func next(t *testing.T, events <-chan types.StatusEvent) types.StatusEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "stream closed")
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return types.StatusEvent{}
+	}
+}
+
+func TestHub_ReplayThenLive(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	hub := stream.NewHub(store)
+
+	_, err = store.CreateIntent(types.Intent{ID: "intent-1", Action: "payment"}, user, "", "hash-intent-1")
+	require.NoError(t, err)
+	_, err = store.CreateIntent(types.Intent{ID: "intent-2", Action: "payment"}, user, "", "hash-intent-2")
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateIntentStatus("intent-1", user, "processing", ""))
+
+	history, err := store.ListEvents(user, "intent-1", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	// Resume after the first event: the second is replayed, then live events follow
+	events, cancel, err := hub.Subscribe(user, "intent-1", history[0].Seq)
+	require.NoError(t, err)
+	defer cancel()
+
+	replayed := next(t, events)
+	assert.Equal(t, history[1].Seq, replayed.Seq)
+	assert.Equal(t, "processing", replayed.Status)
+
+	require.NoError(t, store.UpdateIntentStatus("intent-2", user, "failed", "other intent")) // Filtered out
+	require.NoError(t, store.UpdateStepStatus("intent-1", user, 0, "failed", "0xaa", "reverted"))
+
+	live := next(t, events)
+	assert.Equal(t, "intent-1", live.IntentID)
+	assert.Equal(t, storage.EventKindStep, live.Kind)
+	require.NotNil(t, live.StepIndex)
+	assert.Equal(t, 0, *live.StepIndex)
+	assert.Equal(t, "0xaa", live.TxHash)
+	assert.Greater(t, live.Seq, replayed.Seq)
+}
+
+func TestHub_UserStream(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	hub := stream.NewHub(store)
+
+	events, cancel, err := hub.Subscribe(user, "", 0)
+	require.NoError(t, err)
+
+	_, err = store.CreateIntent(types.Intent{ID: "intent-1", Action: "payment"}, "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "", "hash-intent-1")
+	require.NoError(t, err)
+	_, err = store.CreateIntent(types.Intent{ID: "intent-2", Action: "payment"}, user, "", "hash-intent-2")
+	require.NoError(t, err)
+
+	event := next(t, events)
+	assert.Equal(t, "intent-2", event.IntentID, "other users' events are not streamed")
+	assert.Equal(t, "pending", event.Status)
+
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok, "cancel closes the stream")
+}
+
+func TestHub_SlowSubscriberKeepsUp(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	hub := stream.NewHub(store)
+	_, err = store.CreateIntent(types.Intent{ID: "intent-1", Action: "payment"}, user, "", "hash-intent-1")
+	require.NoError(t, err)
+
+	events, cancel, err := hub.Subscribe(user, "intent-1", 0)
+	require.NoError(t, err)
+	defer cancel()
+
+	// Far more events than a live buffer would hold are published before the subscriber reads
+	for i := 0; i < 300; i++ {
+		require.NoError(t, store.UpdateIntentStatus("intent-1", user, "processing", ""))
+	}
+
+	var last int64
+	for i := 0; i < 301; i++ {
+		event := next(t, events)
+		assert.Greater(t, event.Seq, last, "events arrive in sequence order")
+		last = event.Seq
+	}
+}
+
+func TestHub_RecentHistory(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	hub := stream.NewHub(store)
+	_, err = store.CreateIntent(types.Intent{ID: "intent-1", Action: "payment"}, user, "", "hash-intent-1")
+	require.NoError(t, err)
+	for i := 0; i < 150; i++ {
+		require.NoError(t, store.UpdateIntentStatus("intent-1", user, "processing", ""))
+	}
+	history, err := store.ListEvents(user, "", 0, 200)
+	require.NoError(t, err)
+	require.Len(t, history, 151)
+
+	// Without a resume point the stream starts with the latest 100 events
+	events, cancel, err := hub.Subscribe(user, "", stream.FromRecent)
+	require.NoError(t, err)
+	defer cancel()
+	assert.Equal(t, history[51].Seq, next(t, events).Seq)
+
+	// A user with less history gets all of it
+	other, cancelOther, err := hub.Subscribe("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "", stream.FromRecent)
+	require.NoError(t, err)
+	defer cancelOther()
+	_, err = store.CreateIntent(types.Intent{ID: "intent-2", Action: "payment"}, "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "", "hash-intent-2")
+	require.NoError(t, err)
+	assert.Equal(t, "intent-2", next(t, other).IntentID)
+}
@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultBasePath is the BIP-44 Ethereum account path; user wallets are its children m/44'/60'/0'/0/<index>
+var DefaultBasePath = accounts.DefaultRootDerivationPath
+
+// HDWallet derives per-user accounts (BIP-32) from a BIP-39 mnemonic
+type HDWallet struct {
+	master *extendedKey
+	base   accounts.DerivationPath
+}
+
+// extendedKey is a BIP-32 private key with its chain code
+type extendedKey struct {
+	key       *big.Int
+	chainCode []byte
+}
+
+// NewHDWallet derives the master key from a BIP-39 mnemonic and optional passphrase.
+// The mnemonic must use the English wordlist and carry a valid checksum.
+func NewHDWallet(mnemonic, passphrase string) (*HDWallet, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return nil, fmt.Errorf("mnemonic must have 12, 15, 18, 21 or 24 words, got %d", len(words))
+	}
+	if err := checkMnemonic(words); err != nil {
+		return nil, err
+	}
+
+	// BIP-39 seed: PBKDF2-HMAC-SHA512 over the normalized sentence, salted with "mnemonic" + passphrase
+	seed, err := pbkdf2.Key(sha512.New, strings.Join(words, " "), []byte("mnemonic"+norm.NFKD.String(passphrase)), 2048, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive seed: %w", err)
+	}
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Sign() == 0 || key.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errors.New("mnemonic yields an invalid master key")
+	}
+	return &HDWallet{
+		master: &extendedKey{key: key, chainCode: sum[32:]},
+		base:   DefaultBasePath,
+	}, nil
+}
+
+// LoadHDWallet reads a mnemonic, and optionally its passphrase, from files.
+// Surrounding whitespace in either file is ignored.
+func LoadHDWallet(mnemonicFile, passphraseFile string) (*HDWallet, error) {
+	mnemonic, err := os.ReadFile(mnemonicFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mnemonic: %w", err)
+	}
+	var passphrase []byte
+	if passphraseFile != "" {
+		if passphrase, err = os.ReadFile(passphraseFile); err != nil {
+			return nil, fmt.Errorf("failed to read mnemonic passphrase: %w", err)
+		}
+	}
+	return NewHDWallet(string(mnemonic), strings.TrimSpace(string(passphrase)))
+}
+
+// Path returns the derivation path of the account at index
+func (w *HDWallet) Path(index uint32) accounts.DerivationPath {
+	path := make(accounts.DerivationPath, len(w.base), len(w.base)+1)
+	copy(path, w.base)
+	return append(path, index)
+}
+
+// Derive returns the private key of the account at index
+func (w *HDWallet) Derive(index uint32) (*ecdsa.PrivateKey, error) {
+	if index >= 0x80000000 {
+		return nil, fmt.Errorf("account index %d out of range", index)
+	}
+
+	key := w.master
+	for _, child := range w.Path(index) {
+		var err error
+		if key, err = key.child(child); err != nil {
+			return nil, fmt.Errorf("failed to derive %s: %w", w.Path(index), err)
+		}
+	}
+	return crypto.ToECDSA(key.keyBytes())
+}
+
+// child implements BIP-32 private child key derivation (CKDpriv)
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		// Hardened: 0x00 || ser256(k) || ser32(i)
+		data = append([]byte{0}, k.keyBytes()...)
+	} else {
+		// Normal: serP(point(k)) || ser32(i)
+		priv, err := crypto.ToECDSA(k.keyBytes())
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(n) >= 0 {
+		return nil, errors.New("invalid child key")
+	}
+	childKey := tweak.Add(tweak, k.key)
+	childKey.Mod(childKey, n)
+	if childKey.Sign() == 0 {
+		return nil, errors.New("invalid child key")
+	}
+	return &extendedKey{key: childKey, chainCode: sum[32:]}, nil
+}
+
+// keyBytes serializes the key as 32 big-endian bytes
+func (k *extendedKey) keyBytes() []byte {
+	return k.key.FillBytes(make([]byte, 32))
+}
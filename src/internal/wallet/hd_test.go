@@ -0,0 +1,71 @@
+package wallet_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"trustflow/src/internal/wallet"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Well-known BIP-39 test mnemonic; its first Ethereum account is 0x9858...
+const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestHDWallet(t *testing.T) {
+	hd, err := wallet.NewHDWallet(mnemonic, "")
+	require.NoError(t, err)
+
+	key, err := hd.Derive(0)
+	require.NoError(t, err)
+	assert.Equal(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", crypto.PubkeyToAddress(key.PublicKey).Hex())
+	assert.Equal(t, "m/44'/60'/0'/0/0", hd.Path(0).String())
+
+	key, err = hd.Derive(1)
+	require.NoError(t, err)
+	assert.Equal(t, "0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0", crypto.PubkeyToAddress(key.PublicKey).Hex())
+
+	t.Run("passphrase changes every account", func(t *testing.T) {
+		other, err := wallet.NewHDWallet(mnemonic, "TREZOR")
+		require.NoError(t, err)
+		key, err := other.Derive(0)
+		require.NoError(t, err)
+		assert.NotEqual(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", crypto.PubkeyToAddress(key.PublicKey).Hex())
+	})
+
+	t.Run("rejects bad word counts", func(t *testing.T) {
+		_, err := wallet.NewHDWallet("abandon abandon about", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects unknown words", func(t *testing.T) {
+		_, err := wallet.NewHDWallet(strings.Replace(mnemonic, "about", "abouts", 1), "")
+		assert.ErrorContains(t, err, "wordlist")
+	})
+
+	t.Run("rejects bad checksums", func(t *testing.T) {
+		_, err := wallet.NewHDWallet(strings.Repeat("abandon ", 12), "")
+		assert.ErrorContains(t, err, "checksum")
+	})
+
+	t.Run("accepts longer mnemonics", func(t *testing.T) {
+		_, err := wallet.NewHDWallet(strings.Repeat("zoo ", 23)+"vote", "")
+		assert.NoError(t, err)
+		_, err = wallet.NewHDWallet("legal winner thank year wave sausage worth useful legal winner thank yellow", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("loads from file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "mnemonic")
+		require.NoError(t, os.WriteFile(file, []byte(mnemonic+"\n"), 0o600))
+
+		loaded, err := wallet.LoadHDWallet(file, "")
+		require.NoError(t, err)
+		key, err := loaded.Derive(0)
+		require.NoError(t, err)
+		assert.Equal(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", crypto.PubkeyToAddress(key.PublicKey).Hex())
+	})
+}
@@ -0,0 +1,104 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"trustflow/src/internal/chain"
+	"trustflow/src/pkg/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// IndexStore assigns each user a stable, unique HD account index
+type IndexStore interface {
+	AssignWallet(userID string) (uint32, error)
+}
+
+// Manager maps users to their own derived agent wallets. Without an HD wallet
+// configured, every user shares the server wallet of the base client.
+type Manager struct {
+	base  *chain.ChainClient
+	hd    *HDWallet // Optional
+	store IndexStore
+
+	mu       sync.Mutex
+	accounts map[string]*account
+}
+
+type account struct {
+	index  uint32
+	client *chain.ChainClient
+}
+
+// NewManager creates a wallet manager; hd may be nil to keep the single server wallet
+func NewManager(base *chain.ChainClient, hd *HDWallet, store IndexStore) *Manager {
+	return &Manager{
+		base:     base,
+		hd:       hd,
+		store:    store,
+		accounts: make(map[string]*account),
+	}
+}
+
+// ClientFor returns a chain client that sends from and simulates as the user's wallet
+func (m *Manager) ClientFor(userID string) (*chain.ChainClient, error) {
+	acct, err := m.account(userID)
+	if err != nil {
+		return nil, err
+	}
+	return acct.client, nil
+}
+
+// Info describes the user's wallet and its current balance
+func (m *Manager) Info(ctx context.Context, userID string) (*types.WalletInfo, error) {
+	acct, err := m.account(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := acct.client.GetBalance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch balance: %w", err)
+	}
+
+	info := &types.WalletInfo{
+		UserAddress:   userID,
+		WalletAddress: acct.client.GetAddress().Hex(),
+		Balance:       balance.String(),
+		Shared:        m.hd == nil,
+	}
+	if m.hd != nil {
+		info.DerivationPath = m.hd.Path(acct.index).String()
+	}
+	return info, nil
+}
+
+func (m *Manager) account(userID string) (*account, error) {
+	if m.hd == nil {
+		return &account{client: m.base}, nil
+	}
+
+	// Keyed like every other store: one wallet per address, checksummed however it was sent
+	userID = common.HexToAddress(userID).Hex()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if acct, ok := m.accounts[userID]; ok {
+		return acct, nil
+	}
+
+	index, err := m.store.AssignWallet(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign wallet: %w", err)
+	}
+	key, err := m.hd.Derive(index)
+	if err != nil {
+		return nil, err
+	}
+
+	acct := &account{index: index, client: m.base.WithSigner(chain.NewKeySignerFromKey(key))}
+	m.accounts[userID] = acct
+	return acct, nil
+}
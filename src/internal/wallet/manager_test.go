@@ -0,0 +1,50 @@
+package wallet_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"trustflow/src/internal/chain"
+	"trustflow/src/internal/storage"
+	"trustflow/src/internal/wallet"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_KeepsMigratedWallets(t *testing.T) {
+	const user = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+	const other = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+	path := filepath.Join(t.TempDir(), "trustflow.db")
+	store, err := storage.NewStorage(path)
+	require.NoError(t, err)
+
+	// Wallets assigned under lowercase header addresses, before user IDs were checksummed
+	_, err = store.AssignWallet(strings.ToLower(other))
+	require.NoError(t, err)
+	legacyIndex, err := store.AssignWallet(strings.ToLower(user))
+	require.NoError(t, err)
+
+	// Restarting migrates them to the checksummed address
+	store, err = storage.NewStorage(path)
+	require.NoError(t, err)
+	hd, err := wallet.NewHDWallet(mnemonic, "")
+	require.NoError(t, err)
+	manager := wallet.NewManager(&chain.ChainClient{}, hd, store)
+
+	key, err := hd.Derive(legacyIndex)
+	require.NoError(t, err)
+	for _, id := range []string{user, strings.ToLower(user)} {
+		client, err := manager.ClientFor(id)
+		require.NoError(t, err)
+		assert.Equal(t, chain.NewKeySignerFromKey(key).Address(), client.GetAddress(), "%s keeps the wallet holding their funds", id)
+	}
+
+	// A new user still gets the next free account
+	index, err := store.AssignWallet(user)
+	require.NoError(t, err)
+	assert.Equal(t, legacyIndex, index)
+	index, err = store.AssignWallet("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), index)
+}
@@ -0,0 +1,48 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// bip39English is the BIP-39 English wordlist, one word per line in index order
+//
+//go:embed bip39_english.txt
+var bip39English string
+
+var bip39Index = func() map[string]int64 {
+	words := strings.Fields(bip39English)
+	index := make(map[string]int64, len(words))
+	for i, word := range words {
+		index[word] = int64(i)
+	}
+	return index
+}()
+
+// checkMnemonic verifies that every word is on the English wordlist and that the
+// trailing checksum bits match the SHA-256 of the entropy the words encode
+func checkMnemonic(words []string) error {
+	bits := new(big.Int)
+	for i, word := range words {
+		n, ok := bip39Index[word]
+		if !ok {
+			return fmt.Errorf("mnemonic word %d is not in the BIP-39 English wordlist", i+1)
+		}
+		bits.Lsh(bits, 11).Or(bits, big.NewInt(n))
+	}
+
+	// 11 bits per word hold the entropy plus one checksum bit for every 32 entropy bits
+	checksumBits := uint(len(words) * 11 / 33)
+	checksum := new(big.Int).And(bits, big.NewInt(1<<checksumBits-1))
+	entropy := new(big.Int).Rsh(bits, checksumBits).FillBytes(make([]byte, checksumBits*4))
+
+	sum := sha256.Sum256(entropy)
+	if uint64(sum[0]>>(8-checksumBits)) != checksum.Uint64() {
+		return errors.New("mnemonic checksum is invalid")
+	}
+	return nil
+}
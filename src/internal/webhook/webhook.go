@@ -0,0 +1,379 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"trustflow/src/internal/storage"
+	"trustflow/src/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+// Intent lifecycle events a webhook can subscribe to
+const (
+	EventIntentCreated   = "intent.created"
+	EventStepBroadcast   = "step.broadcast"
+	EventStepConfirmed   = "step.confirmed"
+	EventIntentSucceeded = "intent.succeeded"
+	EventIntentFailed    = "intent.failed"
+	EventIntentBlocked   = "intent.blocked"
+)
+
+// EventTypes lists every event type, in lifecycle order
+var EventTypes = []string{
+	EventIntentCreated,
+	EventStepBroadcast,
+	EventStepConfirmed,
+	EventIntentSucceeded,
+	EventIntentFailed,
+	EventIntentBlocked,
+}
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-TrustFlow-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	EventHeader     = "X-TrustFlow-Event"     // Event type
+	DeliveryHeader  = "X-TrustFlow-Delivery"  // Event ID, the same on every retry
+)
+
+const (
+	batchSize      = 20               // Deliveries sent per poll
+	perEndpoint    = 5                // Deliveries per webhook per poll, so one backlog cannot fill a batch
+	requestTimeout = 10 * time.Second // Per attempt
+	resolveTimeout = 5 * time.Second  // Looking up a webhook's host on registration
+	maxBackoff     = time.Hour        // Cap on the delay between attempts
+	listLimit      = 100              // Deliveries returned by the delivery log
+)
+
+var (
+	// ErrInvalidWebhook is returned when a webhook's URL or events are not acceptable
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrWebhookNotFound is returned when a user has no webhook with the given ID
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrBlockedAddress is returned when a webhook host is, or resolves to, an internal address
+	ErrBlockedAddress = errors.New("webhook address not allowed")
+)
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret.
+// Receivers recompute it from the t= value of the signature header and the raw body.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher registers webhooks, queues lifecycle events for them and delivers the queue
+// with exponential backoff. The queue lives in storage, so deliveries survive a restart.
+type Dispatcher struct {
+	store        *storage.Storage
+	client       *http.Client
+	maxAttempts  int           // Attempts before a delivery is given up as failed
+	retryBase    time.Duration // Delay after the first failed attempt; doubles after each one
+	allowPrivate bool          // Deliver to loopback, private and link-local addresses too (local development)
+	wake         chan struct{} // Nudges the delivery loop when an event is queued
+}
+
+// NewDispatcher creates a dispatcher. Unless allowPrivate is set, webhooks may only reach public
+// addresses: the check runs on registration and again on every connection, so a host that later
+// resolves to an internal address is refused too. Redirects are never followed.
+func NewDispatcher(store *storage.Storage, maxAttempts int, retryBase time.Duration, allowPrivate bool) *Dispatcher {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		Proxy:               nil, // A proxy would be dialed instead of the webhook, bypassing the check
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: requestTimeout,
+		MaxIdleConnsPerHost: 2,
+	}
+
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse // A 3xx is a failed delivery, not a hop to another host
+			},
+		},
+		maxAttempts:  max(maxAttempts, 1),
+		retryBase:    retryBase,
+		allowPrivate: allowPrivate,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// blockedIP reports whether ip is internal: loopback, private, link-local (including cloud
+// metadata endpoints), multicast or unspecified
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// checkHost refuses a webhook host that is, or currently resolves to, an internal address.
+// A name that does not resolve yet is accepted: every delivery is checked again when it dials.
+func (d *Dispatcher) checkHost(host string) error {
+	if d.allowPrivate {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if blockedIP(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// Register creates a webhook for the user and returns it with its signing secret,
+// which is not shown again. No events subscribes to all of them.
+func (d *Dispatcher) Register(userID string, rawURL string, events []string) (*types.Webhook, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	if err := d.checkHost(parsed.Hostname()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+	}
+	for _, event := range events {
+		if !slices.Contains(EventTypes, event) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	hook := types.Webhook{
+		ID:        uuid.New().String(),
+		URL:       rawURL,
+		Events:    slices.Compact(slices.Sorted(slices.Values(events))),
+		Secret:    "whsec_" + hex.EncodeToString(secret),
+		CreatedAt: time.Now().Unix(),
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	if err := d.store.SaveWebhook(userID, hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// List returns the user's webhooks without their secrets
+func (d *Dispatcher) List(userID string) ([]types.Webhook, error) {
+	return d.store.ListWebhooks(userID)
+}
+
+// Delete removes one of the user's webhooks; its pending deliveries are abandoned
+func (d *Dispatcher) Delete(userID string, id string) error {
+	found, err := d.store.DeleteWebhook(userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Deliveries returns the most recent deliveries to one of the user's webhooks, newest first
+func (d *Dispatcher) Deliveries(userID string, id string) ([]types.WebhookDelivery, error) {
+	deliveries, err := d.store.ListDeliveries(userID, id, listLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		// Tell an unknown webhook apart from one nothing was sent to yet
+		hooks, err := d.store.ListWebhooks(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(hooks, func(h types.Webhook) bool { return h.ID == id }) {
+			return nil, ErrWebhookNotFound
+		}
+	}
+	return deliveries, nil
+}
+
+// Notify queues the event for every webhook of the user subscribed to it. Failures are
+// logged, never returned: a webhook must not hold up the intent it reports on.
+func (d *Dispatcher) Notify(userID string, event types.WebhookEvent) {
+	if d == nil {
+		return // Webhooks not configured
+	}
+
+	event.ID = uuid.New().String()
+	event.CreatedAt = time.Now().Unix()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("❌ Failed to encode %s event for %s: %v", event.Type, event.IntentID, err)
+		return
+	}
+
+	queued, err := d.store.EnqueueWebhookEvent(userID, event, payload)
+	if err != nil {
+		log.Printf("❌ Failed to queue %s event for %s: %v", event.Type, event.IntentID, err)
+		return
+	}
+	if queued > 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start delivers queued events in the background until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			for d.DeliverDue(ctx) == batchSize && ctx.Err() == nil {
+				// A full batch: there may be more due
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-d.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+	log.Printf("🪝 Webhook dispatcher started (%d attempts, first retry after %s)", d.maxAttempts, d.retryBase)
+}
+
+// DeliverDue loads a batch of due deliveries and returns how many it loaded. Each webhook's
+// deliveries are sent in order, concurrently with other webhooks'; a webhook's first failure
+// ends its turn, so a slow or dead endpoint costs one attempt per batch and delays no one else.
+func (d *Dispatcher) DeliverDue(ctx context.Context) int {
+	due, err := d.store.ListDueDeliveries(time.Now().Unix(), perEndpoint, batchSize)
+	if err != nil {
+		log.Printf("❌ Failed to load webhook deliveries: %v", err)
+		return 0
+	}
+
+	var order []string
+	byWebhook := make(map[string][]storage.DueDelivery)
+	for _, delivery := range due {
+		if byWebhook[delivery.WebhookID] == nil {
+			order = append(order, delivery.WebhookID)
+		}
+		byWebhook[delivery.WebhookID] = append(byWebhook[delivery.WebhookID], delivery)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range order {
+		wg.Add(1)
+		go func(deliveries []storage.DueDelivery) {
+			defer wg.Done()
+			for _, delivery := range deliveries {
+				if !d.attempt(ctx, delivery) {
+					return
+				}
+			}
+		}(byWebhook[id])
+	}
+	wg.Wait()
+	return len(due)
+}
+
+// attempt sends a delivery once and records the outcome, scheduling a retry if it failed.
+// It reports whether the delivery succeeded.
+func (d *Dispatcher) attempt(ctx context.Context, delivery storage.DueDelivery) bool {
+	now := time.Now()
+	code, err := d.send(ctx, delivery, now.Unix())
+	if err == nil {
+		if err := d.store.RecordDeliveryAttempt(delivery.ID, storage.DeliveryDelivered, code, "", now.Unix(), 0); err != nil {
+			log.Printf("❌ %v", err)
+		}
+		return true
+	}
+
+	status, next := storage.DeliveryPending, now.Add(d.backoff(delivery.Attempts+1))
+	if delivery.Attempts+1 >= d.maxAttempts {
+		status = storage.DeliveryFailed
+		log.Printf("🪝 Giving up on %s delivery %d to %s after %d attempts: %v", delivery.EventType, delivery.ID, delivery.URL, delivery.Attempts+1, err)
+	}
+	if err := d.store.RecordDeliveryAttempt(delivery.ID, status, code, err.Error(), now.Unix(), next.Unix()); err != nil {
+		log.Printf("❌ %v", err)
+	}
+	return false
+}
+
+// send POSTs the signed payload and returns the response status, failing on anything but 2xx
+func (d *Dispatcher) send(ctx context.Context, delivery storage.DueDelivery, timestamp int64) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TrustFlow-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.EventID)
+	req.Header.Set(SignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(delivery.Secret, timestamp, delivery.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Drain so the connection is reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the delay before the next attempt once attempts have failed: retryBase, doubling each time
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.retryBase
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
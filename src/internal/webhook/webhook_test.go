@@ -0,0 +1,260 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trustflow/src/internal/storage"
+	"trustflow/src/internal/webhook"
+	"trustflow/src/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const user = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+
+func newDispatcher(t *testing.T, maxAttempts int) *webhook.Dispatcher {
+	t.Helper()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	return webhook.NewDispatcher(store, maxAttempts, 0, true) // Retries are due immediately; test servers are on loopback
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"intent.created"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(body)))
+
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), webhook.Sign("whsec_test", 1700000000, body))
+	assert.NotEqual(t, webhook.Sign("whsec_test", 1700000000, body), webhook.Sign("whsec_test", 1700000001, body),
+		"the timestamp is covered by the signature")
+}
+
+func TestDispatcher_Register(t *testing.T) {
+	hooks := newDispatcher(t, 3)
+
+	for _, bad := range []string{"", "ftp://example.com/hook", "/relative", "https://"} {
+		_, err := hooks.Register(user, bad, nil)
+		assert.ErrorIs(t, err, webhook.ErrInvalidWebhook, bad)
+	}
+	_, err := hooks.Register(user, "https://example.com/hook", []string{"intent.exploded"})
+	assert.ErrorIs(t, err, webhook.ErrInvalidWebhook)
+
+	hook, err := hooks.Register(user, "https://example.com/hook", []string{webhook.EventIntentFailed, webhook.EventIntentFailed})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hook.Secret, "whsec_"))
+	assert.Equal(t, []string{webhook.EventIntentFailed}, hook.Events)
+
+	listed, err := hooks.List(user)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Secret, "the secret is only shown on creation")
+
+	require.NoError(t, hooks.Delete(user, hook.ID))
+	assert.ErrorIs(t, hooks.Delete(user, hook.ID), webhook.ErrWebhookNotFound)
+}
+
+func TestDispatcher_DeliverWithRetry(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	var received []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		received = append(received, r)
+		bodies = append(bodies, body)
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // First attempt fails
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	hooks := newDispatcher(t, 3)
+	hook, err := hooks.Register(user, server.URL, []string{webhook.EventIntentSucceeded})
+	require.NoError(t, err)
+
+	hooks.Notify(user, types.WebhookEvent{Type: webhook.EventIntentCreated, IntentID: "intent-1", Status: "pending"}) // Not subscribed
+	hooks.Notify(user, types.WebhookEvent{Type: webhook.EventIntentSucceeded, IntentID: "intent-1", Status: "success"})
+
+	assert.Equal(t, 1, hooks.DeliverDue(context.Background()))
+	assert.Equal(t, 1, hooks.DeliverDue(context.Background()), "a failed attempt is retried")
+	assert.Equal(t, 0, hooks.DeliverDue(context.Background()), "a delivered event is not sent again")
+
+	require.Len(t, received, 2)
+	req, body := received[1], bodies[1]
+	assert.Equal(t, webhook.EventIntentSucceeded, req.Header.Get(webhook.EventHeader))
+	assert.Equal(t, received[0].Header.Get(webhook.DeliveryHeader), req.Header.Get(webhook.DeliveryHeader), "retries keep the event ID")
+
+	// The signature verifies against the raw body and the timestamp in the header
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(req.Header.Get(webhook.SignatureHeader), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signature = value
+		}
+	}
+	assert.Equal(t, webhook.Sign(hook.Secret, timestamp, body), signature)
+
+	var event types.WebhookEvent
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "intent-1", event.IntentID)
+	assert.Equal(t, "success", event.Status)
+
+	deliveries, err := hooks.Deliveries(user, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, storage.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseCode)
+}
+
+func TestDispatcher_GiveUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hooks := newDispatcher(t, 2)
+	hook, err := hooks.Register(user, server.URL, nil)
+	require.NoError(t, err)
+	hooks.Notify(user, types.WebhookEvent{Type: webhook.EventIntentFailed, IntentID: "intent-1", Status: "failed"})
+
+	for hooks.DeliverDue(context.Background()) > 0 {
+	}
+
+	deliveries, err := hooks.Deliveries(user, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, storage.DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseCode)
+	assert.Contains(t, deliveries[0].Error, "500")
+
+	_, err = hooks.Deliveries(user, "missing")
+	assert.ErrorIs(t, err, webhook.ErrWebhookNotFound)
+}
+
+func TestDispatcher_BlocksInternalAddresses(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "trustflow.db"))
+	require.NoError(t, err)
+	hooks := webhook.NewDispatcher(store, 1, 0, false)
+
+	for _, internal := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+	} {
+		_, err := hooks.Register(user, internal, nil)
+		assert.ErrorIs(t, err, webhook.ErrInvalidWebhook, internal)
+		assert.ErrorIs(t, err, webhook.ErrBlockedAddress, internal)
+	}
+	_, err = hooks.Register(user, "https://93.184.215.14/hook", nil)
+	assert.NoError(t, err, "public addresses are accepted")
+
+	// A webhook that reaches an internal address anyway, e.g. through DNS changing after
+	// registration, is refused when the delivery connects
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+	require.NoError(t, store.SaveWebhook(user, types.Webhook{ID: "internal", URL: server.URL, Events: []string{webhook.EventIntentFailed}, Secret: "whsec_test"}))
+	hooks.Notify(user, types.WebhookEvent{Type: webhook.EventIntentFailed, IntentID: "intent-1", Status: "failed"})
+	hooks.DeliverDue(context.Background())
+
+	deliveries, err := hooks.Deliveries(user, "internal")
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, storage.DeliveryFailed, deliveries[0].Status)
+	assert.Contains(t, deliveries[0].Error, "not allowed")
+	assert.Zero(t, calls)
+}
+
+func TestDispatcher_NoRedirects(t *testing.T) {
+	var redirected bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	hooks := newDispatcher(t, 1)
+	hook, err := hooks.Register(user, server.URL, nil)
+	require.NoError(t, err)
+	hooks.Notify(user, types.WebhookEvent{Type: webhook.EventIntentFailed, IntentID: "intent-1", Status: "failed"})
+	hooks.DeliverDue(context.Background())
+
+	deliveries, err := hooks.Deliveries(user, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, storage.DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, http.StatusFound, deliveries[0].ResponseCode)
+	assert.False(t, redirected, "the redirect is not followed")
+}
+
+func TestDispatcher_SlowEndpointIsolated(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	fastHit := make(chan struct{}, 10)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fastHit <- struct{}{}
+	}))
+	defer fast.Close()
+
+	hooks := newDispatcher(t, 3)
+	_, err := hooks.Register(user, slow.URL, nil)
+	require.NoError(t, err)
+	_, err = hooks.Register(user, fast.URL, nil)
+	require.NoError(t, err)
+	hooks.Notify(user, types.WebhookEvent{Type: webhook.EventIntentCreated, IntentID: "intent-1", Status: "pending"})
+	hooks.Notify(user, types.WebhookEvent{Type: webhook.EventIntentSucceeded, IntentID: "intent-1", Status: "success"})
+
+	done := make(chan struct{})
+	go func() {
+		hooks.DeliverDue(context.Background())
+		close(done)
+	}()
+
+	// Both of the fast endpoint's deliveries arrive while the slow one still holds its first
+	for range 2 {
+		select {
+		case <-fastHit:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the slow endpoint held up the fast one")
+		}
+	}
+	close(release)
+	<-done
+}
@@ -1,19 +1,39 @@
 package types
 
+import "encoding/json"
+
 // Intent represents the high-level user request
 type Intent struct {
-	ID        string            `json:"id,omitempty"`
-	Action    string            `json:"action"` // Deprecated in favor of Steps, but kept for backward compat if needed
-	Params    map[string]string `json:"params,omitempty"`
-	Steps     []IntentStep      `json:"steps,omitempty"` // For multi-step workflows
-	CreatedAt int64             `json:"created_at,omitempty"`
+	ID          string                     `json:"id,omitempty"`
+	Action      string                     `json:"action"` // Deprecated in favor of Steps, but kept for backward compat if needed
+	Params      map[string]string          `json:"params,omitempty"`
+	TypedParams map[string]json.RawMessage `json:"typed_params,omitempty"` // Nested/typed values (arrays, objects, numbers)
+	Steps       []IntentStep               `json:"steps,omitempty"`        // For multi-step workflows
+	CreatedAt   int64                      `json:"created_at,omitempty"`
+	Signature   string                     `json:"signature,omitempty"` // Optional EIP-712 signature by the submitting address; stored apart from raw_intent
 }
 
 // IntentStep represents a single atomic action within a workflow
 type IntentStep struct {
-	ID     string            `json:"id,omitempty"`
-	Action string            `json:"action" binding:"required"`
-	Params map[string]string `json:"params" binding:"required"`
+	ID          string                     `json:"id,omitempty"`
+	Action      string                     `json:"action" binding:"required"`
+	Params      map[string]string          `json:"params" binding:"required"`
+	TypedParams map[string]json.RawMessage `json:"typed_params,omitempty"` // e.g. contract_call "args" as a JSON array
+}
+
+// WorkflowSteps normalizes the intent into a list of steps, converting a single action into a 1-step workflow
+func (i Intent) WorkflowSteps() []IntentStep {
+	if len(i.Steps) == 0 && i.Action != "" {
+		return []IntentStep{
+			{Action: i.Action, Params: i.Params, TypedParams: i.TypedParams},
+		}
+	}
+	return i.Steps
+}
+
+// AsIntent wraps a single step as a standalone intent for parsing
+func (s IntentStep) AsIntent() Intent {
+	return Intent{Action: s.Action, Params: s.Params, TypedParams: s.TypedParams}
 }
 
 // IntentResponse is the standard API response for intent submission
@@ -25,33 +45,284 @@ type IntentResponse struct {
 	TxHashes        []string `json:"tx_hashes,omitempty"`         // For multi-step
 	FailedStepIndex *int     `json:"failed_step_index,omitempty"` // If failed, which step (0-based)
 	Error           string   `json:"error,omitempty"`             // Error details
+	BlockedRule     string   `json:"blocked_rule,omitempty"`      // If blocked, the policy rule that fired
+
+	Solvency *SolvencyReport `json:"solvency,omitempty"` // Per-step shortfall when the wallet cannot afford the workflow
+	Approval *ApprovalState  `json:"approval,omitempty"` // If awaiting approval, who must approve and by when
+	Replayed bool            `json:"replayed,omitempty"` // True when a retried submission returned the intent already accepted
 }
 
 // StepState represents the status of a specific step in the workflow
 type StepState struct {
-	StepIndex int    `json:"step_index"`
-	Action    string `json:"action"`
-	Status    string `json:"status"`
-	TxHash    string `json:"tx_hash,omitempty"`
-	Error     string `json:"error,omitempty"`
+	StepIndex     int        `json:"step_index"`
+	Action        string     `json:"action"`
+	Status        string     `json:"status"` // pending, submitted, success, failed, blocked, skipped, unconfirmed, cancelled
+	TxHash        string     `json:"tx_hash,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	BlockNumber   uint64     `json:"block_number,omitempty"`
+	GasUsed       uint64     `json:"gas_used,omitempty"`
+	ReceiptStatus *uint64    `json:"receipt_status,omitempty"` // 1 = success, 0 = reverted on-chain
+	Fees          *FeeParams `json:"fees,omitempty"`           // Gas pricing the transaction was sent with
+
+	Replacements []TxReplacement `json:"replacements,omitempty"` // Speed-ups and cancellations at the step's nonce
+}
+
+// TxReplacement is a transaction broadcast at the nonce of a stuck step to replace it
+type TxReplacement struct {
+	Kind      string     `json:"kind"` // speedup or cancel
+	TxHash    string     `json:"tx_hash"`
+	Fees      *FeeParams `json:"fees,omitempty"`
+	CreatedAt int64      `json:"created_at"`
+}
+
+// FeeParams describes how a transaction is priced (Wei per gas)
+type FeeParams struct {
+	Type                 string `json:"type"`                               // legacy or dynamic_fee (EIP-1559)
+	GasPrice             string `json:"gas_price,omitempty"`                // Legacy only
+	MaxFeePerGas         string `json:"max_fee_per_gas,omitempty"`          // EIP-1559 fee cap
+	MaxPriorityFeePerGas string `json:"max_priority_fee_per_gas,omitempty"` // EIP-1559 tip
+	BaseFee              string `json:"base_fee,omitempty"`                 // EIP-1559 expected next base fee
 }
 
 // IntentState represents the full current state of an intent for polling
 type IntentState struct {
 	IntentID  string      `json:"intent_id"`
-	Status    string      `json:"status"`
+	Status    string      `json:"status"` // pending, processing, awaiting_approval, success, failed, blocked, rejected, expired
 	CreatedAt int64       `json:"created_at"`
 	Message   string      `json:"message,omitempty"`
 	RawIntent string      `json:"raw_intent,omitempty"`
+	Signature string      `json:"signature,omitempty"` // EIP-712 signature authorizing raw_intent, if it was signed
 	Steps     []StepState `json:"steps"`
+
+	Approval *ApprovalState `json:"approval,omitempty"` // Human approval the intent needed, with every decision
 }
 
 // SimulationResponse provides details about a dry-run execution
 type SimulationResponse struct {
-	Valid     bool   `json:"valid"`
-	GasLimit  uint64 `json:"gas_limit"`
-	GasPrice  string `json:"gas_price"`
-	TotalCost string `json:"total_cost"`
+	Valid     bool           `json:"valid"`
+	GasLimit  uint64         `json:"gas_limit"`
+	GasPrice  string         `json:"gas_price"`
+	TotalCost string         `json:"total_cost"`
+	Message   string         `json:"message,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Warnings  []string       `json:"warnings,omitempty"` // Risks the intent explicitly accepted
+	Revert    *RevertDetails `json:"revert,omitempty"`   // Decoded revert reason when the dry run fails
+	Fees      *FeeParams     `json:"fees,omitempty"`     // Pricing the transactions would be sent with
+
+	FailedStepIndex *int             `json:"failed_step_index,omitempty"` // For workflows, the step that failed
+	Steps           []StepSimulation `json:"steps,omitempty"`             // Per-step breakdown for workflows
+	Solvency        *SolvencyReport  `json:"solvency,omitempty"`          // Balance vs. value plus gas for all steps
+
+	SimulatedIndependently bool `json:"simulated_independently,omitempty"` // The node lacks eth_simulateV1: each step saw only the current state
+}
+
+// SolvencyReport compares the wallet balance with what a whole workflow needs
+type SolvencyReport struct {
+	Solvent          bool       `json:"solvent"`
+	Balance          string     `json:"balance"`                      // Wei
+	GasPrice         string     `json:"gas_price"`                    // Wei per gas used for the estimate
+	TotalRequired    string     `json:"total_required"`               // Wei: sum of values plus gas for every step
+	Shortfall        string     `json:"shortfall,omitempty"`          // Wei missing for the whole workflow
+	FirstShortfallAt *int       `json:"first_shortfall_at,omitempty"` // First step the wallet cannot afford
+	Steps            []StepCost `json:"steps"`
+}
+
+// StepCost is the cost of a single step within a solvency report
+type StepCost struct {
+	StepIndex  int    `json:"step_index"`
+	Value      string `json:"value"` // Wei
+	GasLimit   uint64 `json:"gas_limit"`
+	GasCost    string `json:"gas_cost"`            // Wei
+	Cumulative string `json:"cumulative"`          // Wei required up to and including this step
+	Shortfall  string `json:"shortfall,omitempty"` // Wei missing by the end of this step
+}
+
+// StepSimulation is the dry-run result of a single workflow step
+type StepSimulation struct {
+	StepIndex int      `json:"step_index"`
+	Action    string   `json:"action"`
+	GasLimit  uint64   `json:"gas_limit"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// RevertDetails is the decoded reason a simulated transaction reverted
+type RevertDetails struct {
+	Code   string `json:"code"`             // error, panic, custom_error or unknown
+	Reason string `json:"reason,omitempty"` // Decoded human-readable reason
+	Data   string `json:"data,omitempty"`   // Raw revert data (hex)
+}
+
+// BudgetStatus reports a user's rolling spend against their cap
+type BudgetStatus struct {
+	UserAddress   string `json:"user_address"`
+	Limit         string `json:"limit,omitempty"`     // Wei, empty when unlimited
+	Spent         string `json:"spent"`               // Wei executed within the window
+	Remaining     string `json:"remaining,omitempty"` // Wei, empty when unlimited
+	WindowSeconds int64  `json:"window_seconds"`
+	Unlimited     bool   `json:"unlimited"`
+}
+
+// WalletInfo describes the agent wallet a user's intents spend from
+type WalletInfo struct {
+	UserAddress    string `json:"user_address"`
+	WalletAddress  string `json:"wallet_address"`
+	DerivationPath string `json:"derivation_path,omitempty"` // BIP-44 path, for HD-derived wallets
+	Balance        string `json:"balance"`                   // Wei
+	Shared         bool   `json:"shared"`                    // True when all users share the server wallet
+}
+
+// ApprovalState is the M-of-N human approval a high-value or risky intent must collect
+type ApprovalState struct {
+	Reason      string             `json:"reason"` // Threshold the workflow exceeded
+	RiskScore   int                `json:"risk_score"`
+	TotalValue  string             `json:"total_value"` // Wei
+	Required    int                `json:"required"`
+	Approvers   []string           `json:"approvers"`
+	RequestedAt int64              `json:"requested_at"`
+	ExpiresAt   int64              `json:"expires_at"`
+	ApprovedAt  int64              `json:"approved_at,omitempty"` // Set once Required approvals are in
+	Decisions   []ApprovalDecision `json:"decisions"`
+}
+
+// ApprovalDecision is one approver's approve or reject of an intent
+type ApprovalDecision struct {
+	Approver  string `json:"approver"`
+	Decision  string `json:"decision"` // approve or reject
+	Comment   string `json:"comment,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Webhook is a URL that receives HMAC-signed callbacks for a user's intent lifecycle events
+type Webhook struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`           // Subscribed event types; empty subscribes to all
+	Secret    string   `json:"secret,omitempty"` // HMAC-SHA256 key, only returned when the webhook is created
+	CreatedAt int64    `json:"created_at"`
+}
+
+// WebhookEvent is the JSON body POSTed to a webhook
+type WebhookEvent struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"` // intent.created, step.broadcast, step.confirmed, intent.succeeded, intent.failed or intent.blocked
+	IntentID  string `json:"intent_id"`
+	Status    string `json:"status"`               // Intent status, or step status for step events
+	StepIndex *int   `json:"step_index,omitempty"` // Step events, and the step a failed or blocked intent stopped at
+	TxHash    string `json:"tx_hash,omitempty"`
+	Message   string `json:"message,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// WebhookDelivery is one event queued for a webhook, with the outcome of its latest attempt
+type WebhookDelivery struct {
+	ID            int64  `json:"id"`
+	WebhookID     string `json:"webhook_id"`
+	EventID       string `json:"event_id"`
+	EventType     string `json:"event_type"`
+	IntentID      string `json:"intent_id"`
+	Status        string `json:"status"` // pending, delivered or failed
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"next_attempt_at,omitempty"` // While pending
+	ResponseCode  int    `json:"response_code,omitempty"`   // HTTP status of the latest attempt
+	Error         string `json:"error,omitempty"`
+	CreatedAt     int64  `json:"created_at"`
+	DeliveredAt   int64  `json:"delivered_at,omitempty"`
+}
+
+// StatusEvent is a persisted intent or step status transition, streamed over SSE
+type StatusEvent struct {
+	Seq       int64  `json:"seq"` // Position in the event sequence; the SSE event ID
+	UserID    string `json:"-"`
+	IntentID  string `json:"intent_id"`
+	Kind      string `json:"kind"`                 // intent or step
+	StepIndex *int   `json:"step_index,omitempty"` // Step events only
+	Status    string `json:"status"`
+	TxHash    string `json:"tx_hash,omitempty"`
 	Message   string `json:"message,omitempty"`
-	Error     string `json:"error,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// AuthNonce is a single-use nonce to embed in a Sign-In with Ethereum (EIP-4361) message
+type AuthNonce struct {
+	Nonce      string `json:"nonce"`
+	Domain     string `json:"domain"`      // Domain the message must name
+	ChainID    int64  `json:"chain_id"`    // Chain ID the message must name
+	IntentSalt string `json:"intent_salt"` // EIP-712 domain salt for signing intents
+	ExpiresAt  int64  `json:"expires_at"`
+}
+
+// Session is issued for a verified sign-in; its token authenticates API requests as Address
+type Session struct {
+	Token     string `json:"token"` // Sent as "Authorization: Bearer <token>"
+	Address   string `json:"address"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// APIKey is a long-lived credential acting as Address with a limited set of scopes
+type APIKey struct {
+	ID         string   `json:"id"`
+	Key        string   `json:"key,omitempty"` // Sent as "Authorization: Bearer <key>"; only returned on creation
+	Prefix     string   `json:"prefix"`        // First characters of the key, to tell keys apart
+	Name       string   `json:"name,omitempty"`
+	Address    string   `json:"address"`
+	Scopes     []string `json:"scopes"`
+	CreatedBy  string   `json:"created_by"` // Admin who issued the key
+	CreatedAt  int64    `json:"created_at"`
+	ExpiresAt  int64    `json:"expires_at,omitempty"` // Zero never expires
+	LastUsedAt int64    `json:"last_used_at,omitempty"`
+	RevokedAt  int64    `json:"revoked_at,omitempty"`
+}
+
+// AuditEvent is an entry in the hash-chained audit log
+type AuditEvent struct {
+	Seq       int64  `json:"seq"`
+	IntentID  string `json:"intent_id"`
+	Payload   string `json:"payload"`   // JSON record of the transition, hashed exactly as stored
+	PrevHash  string `json:"prev_hash"` // Hash of the previous event; all zeros for the first
+	Hash      string `json:"hash"`      // SHA-256 of "<prev_hash>\n<seq>\n<payload>", hex
+	CreatedAt int64  `json:"created_at"`
+}
+
+// AuditReport is the outcome of re-walking the audit chain
+type AuditReport struct {
+	Valid     bool   `json:"valid"`
+	Checked   int64  `json:"checked"`              // Events verified before stopping
+	HeadSeq   int64  `json:"head_seq"`             // Last intact event
+	HeadHash  string `json:"head_hash"`            // Its hash, committing to the whole intact chain
+	BrokenSeq int64  `json:"broken_seq,omitempty"` // First event that does not link to its predecessor
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AuditAnchor is a Merkle root over a range of audit events, published on-chain
+type AuditAnchor struct {
+	ID          int64  `json:"id"`
+	FromSeq     int64  `json:"from_seq"` // Exclusive: the first leaf is event from_seq+1
+	ToSeq       int64  `json:"to_seq"`   // Inclusive
+	Root        string `json:"root"`     // 0x-prefixed Merkle root
+	TxHash      string `json:"tx_hash"`
+	Status      string `json:"status"` // submitted, confirmed or failed
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	Error       string `json:"error,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	ConfirmedAt int64  `json:"confirmed_at,omitempty"`
+}
+
+// ProofNode is a sibling on the path from a Merkle leaf to the root
+type ProofNode struct {
+	Hash     string `json:"hash"`
+	Position string `json:"position"` // left or right of the running hash
+}
+
+// AuditProof proves one audit event is included in an anchored Merkle root
+type AuditProof struct {
+	Event     AuditEvent   `json:"event"`
+	Anchor    *AuditAnchor `json:"anchor,omitempty"` // Not yet anchored if nil
+	LeafIndex int          `json:"leaf_index"`       // Position of the event among the anchor's leaves
+	Path      []ProofNode  `json:"path,omitempty"`
+}
+
+// IntentAuditProof holds inclusion proofs for every audit event of an intent
+type IntentAuditProof struct {
+	IntentID string       `json:"intent_id"`
+	Proofs   []AuditProof `json:"proofs"`
 }